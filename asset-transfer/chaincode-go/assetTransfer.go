@@ -6,18 +6,100 @@ package main
 
 import (
 	"log"
+	"os"
+	"strconv"
 
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
 	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
 	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
 )
 
+// serverConfig holds the Chaincode-as-a-Service settings, read from the environment
+type serverConfig struct {
+	CCID    string // CHAINCODE_ID: package ID of the chaincode installed on the peers
+	Address string // CHAINCODE_SERVER_ADDRESS: listen address, e.g. 0.0.0.0:9999
+}
+
 func main() {
-	assetChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	assetChaincode, err := contractapi.NewChaincode(chaincode.Contracts()...)
 	if err != nil {
 		log.Panicf("Error creating asset-transfer-basic chaincode: %v", err)
 	}
 
-	if err := assetChaincode.Start(); err != nil {
-		log.Panicf("Error starting asset-transfer-basic chaincode: %v", err)
+	// Without a server address the peer launches the chaincode, which connects back to it
+	config := serverConfig{
+		CCID:    os.Getenv("CHAINCODE_ID"),
+		Address: os.Getenv("CHAINCODE_SERVER_ADDRESS"),
+	}
+	if config.Address == "" {
+		if err := assetChaincode.Start(); err != nil {
+			log.Panicf("Error starting asset-transfer-basic chaincode: %v", err)
+		}
+		return
+	}
+	if config.CCID == "" {
+		log.Panicf("Error starting asset-transfer-basic chaincode server: CHAINCODE_ID must be set to the chaincode package ID when CHAINCODE_SERVER_ADDRESS is set")
+	}
+
+	tlsProps, err := getTLSProperties()
+	if err != nil {
+		log.Panicf("Error reading chaincode server TLS settings: %v", err)
+	}
+
+	server := &shim.ChaincodeServer{
+		CCID:     config.CCID,
+		Address:  config.Address,
+		CC:       assetChaincode,
+		TLSProps: tlsProps,
+	}
+
+	log.Printf("Serving chaincode %s as a service on %s (TLS enabled: %t)", config.CCID, config.Address, !tlsProps.Disabled)
+	if err := server.Start(); err != nil {
+		log.Panicf("Error starting asset-transfer-basic chaincode server: %v", err)
+	}
+}
+
+// getTLSProperties reads the TLS settings of the chaincode server. TLS is disabled unless
+// CHAINCODE_TLS_DISABLED is false, in which case CHAINCODE_TLS_KEY and CHAINCODE_TLS_CERT
+// must point to PEM files; CHAINCODE_CLIENT_CA_CERT optionally enables peer authentication.
+func getTLSProperties() (shim.TLSProperties, error) {
+	tlsDisabled, err := strconv.ParseBool(getEnvOrDefault("CHAINCODE_TLS_DISABLED", "true"))
+	if err != nil {
+		return shim.TLSProperties{}, err
+	}
+	if tlsDisabled {
+		return shim.TLSProperties{Disabled: true}, nil
+	}
+
+	key, err := os.ReadFile(os.Getenv("CHAINCODE_TLS_KEY"))
+	if err != nil {
+		return shim.TLSProperties{}, err
+	}
+	cert, err := os.ReadFile(os.Getenv("CHAINCODE_TLS_CERT"))
+	if err != nil {
+		return shim.TLSProperties{}, err
+	}
+
+	var clientCACerts []byte
+	if path := os.Getenv("CHAINCODE_CLIENT_CA_CERT"); path != "" {
+		clientCACerts, err = os.ReadFile(path)
+		if err != nil {
+			return shim.TLSProperties{}, err
+		}
+	}
+
+	return shim.TLSProperties{
+		Disabled:      false,
+		Key:           key,
+		Cert:          cert,
+		ClientCACerts: clientCACerts,
+	}, nil
+}
+
+// getEnvOrDefault returns the value of an environment variable, or a default when it is unset
+func getEnvOrDefault(name string, defaultValue string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
 	}
+	return defaultValue
 }
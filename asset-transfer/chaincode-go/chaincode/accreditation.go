@@ -0,0 +1,106 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// accreditationMetadataKey is the attribute of the metadata key holding the accreditation settings
+const accreditationMetadataKey = "accreditation"
+
+// accreditationFunction is the function of the accreditation chaincode called for each check.
+// It takes the name of an institution and returns true while the institution is accredited.
+const accreditationFunction = "IsAccredited"
+
+// AccreditationConfig locates the chaincode that owns accreditation data
+type AccreditationConfig struct {
+	ChaincodeName string `json:"ChaincodeName"` // Accreditation chaincode; checks are disabled when empty
+	ChannelID     string `json:"ChannelID"`     // Channel of the accreditation chaincode; empty for the current channel
+}
+
+// GetAccreditationConfig returns the accreditation chaincode settings
+func (c *AdminContract) GetAccreditationConfig(ctx contractapi.TransactionContextInterface) (*AccreditationConfig, error) {
+	return readAccreditationConfig(ctx)
+}
+
+// SetAccreditationConfig sets the chaincode, and optionally the channel, consulted to confirm that
+// institutions are accredited. An empty chaincodeName disables the checks.
+func (c *AdminContract) SetAccreditationConfig(ctx contractapi.TransactionContextInterface, chaincodeName string, channelID string) error {
+	if err := requireIssuer(ctx, "configure the accreditation of"); err != nil {
+		return err
+	}
+	if chaincodeName != "" {
+		if err := validateID("chaincodeName", chaincodeName); err != nil {
+			return err
+		}
+	}
+	if channelID != "" {
+		if err := validateID("channelID", channelID); err != nil {
+			return err
+		}
+	}
+
+	configJSON, err := json.Marshal(AccreditationConfig{ChaincodeName: chaincodeName, ChannelID: channelID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal accreditation settings: %v", err)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(metadataObjectType, []string{accreditationMetadataKey})
+	if err != nil {
+		return fmt.Errorf("failed to create metadata key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(key, configJSON)
+}
+
+// readAccreditationConfig returns the accreditation settings, checks disabled if none were set
+func readAccreditationConfig(ctx contractapi.TransactionContextInterface) (*AccreditationConfig, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(metadataObjectType, []string{accreditationMetadataKey})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata key: %v", err)
+	}
+
+	configJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	var config AccreditationConfig
+	if configJSON != nil {
+		if err := json.Unmarshal(configJSON, &config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal accreditation settings: %v", err)
+		}
+	}
+
+	return &config, nil
+}
+
+// checkAccreditation asks the accreditation chaincode whether an institution is currently
+// accredited. Calls to a chaincode on another channel are read-only, which is all this needs.
+func checkAccreditation(ctx contractapi.TransactionContextInterface, institution string) error {
+	config, err := readAccreditationConfig(ctx)
+	if err != nil || config.ChaincodeName == "" {
+		return err
+	}
+
+	args := [][]byte{[]byte(accreditationFunction), []byte(institution)}
+	response := ctx.GetStub().InvokeChaincode(config.ChaincodeName, args, config.ChannelID)
+	if response.Status != shim.OK {
+		return newError(ErrAccreditationUnavailable, "accreditation chaincode %s failed: %s", config.ChaincodeName, response.Message)
+	}
+
+	accredited, err := strconv.ParseBool(strings.TrimSpace(string(response.Payload)))
+	if err != nil {
+		return newError(ErrAccreditationUnavailable, "accreditation chaincode %s returned %q instead of a boolean", config.ChaincodeName, response.Payload)
+	}
+	if !accredited {
+		return newError(ErrNotAccredited, "the institution %q is not currently accredited", institution)
+	}
+
+	return nil
+}
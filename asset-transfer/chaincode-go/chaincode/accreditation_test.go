@@ -0,0 +1,150 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgertest"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	accreditationChaincode = "accreditation"
+	accreditationChannel   = "regulators"
+)
+
+// AccreditationContract stubs the accreditation chaincode, which is deployed and governed separately
+type AccreditationContract struct {
+	contractapi.Contract
+	accredited map[string]bool
+	calls      []string
+}
+
+// IsAccredited reports whether an institution is currently accredited
+func (c *AccreditationContract) IsAccredited(ctx contractapi.TransactionContextInterface, institution string) (bool, error) {
+	c.calls = append(c.calls, institution)
+	return c.accredited[institution], nil
+}
+
+// registerAccreditation deploys the stubbed accreditation chaincode on the regulators channel
+func registerAccreditation(t *testing.T, ledger *ledgertest.Stub, accredited ...string) *AccreditationContract {
+	contract := &AccreditationContract{accredited: map[string]bool{}}
+	for _, institution := range accredited {
+		contract.accredited[institution] = true
+	}
+	accreditationCC, err := contractapi.NewChaincode(contract)
+	require.NoError(t, err)
+
+	ledger.RegisterChaincode(accreditationChaincode, accreditationChannel, accreditationCC)
+	return contract
+}
+
+// failingChaincode returns a fixed response to every call
+type failingChaincode struct {
+	response *peer.Response
+}
+
+func (cc failingChaincode) Init(stub shim.ChaincodeStubInterface) *peer.Response {
+	return cc.response
+}
+
+func (cc failingChaincode) Invoke(stub shim.ChaincodeStubInterface) *peer.Response {
+	return cc.response
+}
+
+// configureAccreditation points the chaincode to the accreditation chaincode
+func configureAccreditation(t *testing.T, ledger *ledgertest.Stub) {
+	t.Helper()
+
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.AdminContract{}).SetAccreditationConfig(ctx, accreditationChaincode, accreditationChannel)
+	})
+	require.NoError(t, err)
+}
+
+// tryCreateAcademic creates an academic credential as the registrar and returns the error
+func tryCreateAcademic(ledger *ledgertest.Stub, credentialID string, institution string) error {
+	return ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := (&chaincode.CredentialContract{}).CreateAcademicCredential(ctx, credentialID, "talent1", "Jane", "Doe", "Go", "BSc Computer Science", institution)
+		return err
+	})
+}
+
+func TestCreateAcademicCredentialChecksAccreditation(t *testing.T) {
+	ledger := newLedger()
+	accreditation := registerAccreditation(t, ledger, "Concordia University")
+	configureAccreditation(t, ledger)
+
+	require.NoError(t, tryCreateAcademic(ledger, "cred1", "Concordia University"))
+	require.Equal(t, []string{"Concordia University"}, accreditation.calls)
+
+	requireErrorCode(t, tryCreateAcademic(ledger, "cred2", "Diploma Mill"), chaincode.ErrNotAccredited)
+}
+
+func TestCreateProfessionalCredentialSkipsAccreditation(t *testing.T) {
+	ledger := newLedger()
+	accreditation := registerAccreditation(t, ledger)
+	configureAccreditation(t, ledger)
+
+	createProfessional(t, ledger, "cred1", "talent1")
+	require.Empty(t, accreditation.calls)
+}
+
+func TestAccreditationNotConfigured(t *testing.T) {
+	ledger := newLedger()
+	accreditation := registerAccreditation(t, ledger)
+
+	require.NoError(t, tryCreateAcademic(ledger, "cred1", "Diploma Mill"))
+	require.Empty(t, accreditation.calls)
+
+	ctx := ledger.BeginTx(recruiter)
+	config, err := (&chaincode.AdminContract{}).GetAccreditationConfig(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.AccreditationConfig{}, config)
+}
+
+func TestAccreditationChaincodeFailure(t *testing.T) {
+	ledger := newLedger()
+	configureAccreditation(t, ledger)
+
+	// The accreditation chaincode is configured but not deployed
+	require.EqualError(t, tryCreateAcademic(ledger, "cred1", "Concordia University"), "ACCREDITATION_UNAVAILABLE: accreditation chaincode accreditation failed: chaincode accreditation not found on channel regulators")
+
+	ledger.RegisterChaincode(accreditationChaincode, accreditationChannel, failingChaincode{&peer.Response{Status: shim.ERROR, Message: "accreditation registry unavailable"}})
+	require.EqualError(t, tryCreateAcademic(ledger, "cred1", "Concordia University"), "ACCREDITATION_UNAVAILABLE: accreditation chaincode accreditation failed: accreditation registry unavailable")
+
+	ledger.RegisterChaincode(accreditationChaincode, accreditationChannel, failingChaincode{&peer.Response{Status: shim.OK, Payload: []byte("maybe")}})
+	requireErrorCode(t, tryCreateAcademic(ledger, "cred1", "Concordia University"), chaincode.ErrAccreditationUnavailable)
+}
+
+func TestUpdateVerificationStatusChecksAccreditation(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+
+	// The institution loses its accreditation after the credential was created
+	accreditation := registerAccreditation(t, ledger)
+	configureAccreditation(t, ledger)
+
+	issuers := chaincode.IssuerContract{}
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return issuers.UpdateVerificationStatus(ctx, "cred1", "Verified", "Registrar")
+	})
+	requireErrorCode(t, err, chaincode.ErrNotAccredited)
+
+	// Revoking does not vouch for the institution
+	verify(t, ledger, "cred1", "Revoked")
+	require.Len(t, accreditation.calls, 1)
+	require.Equal(t, "Revoked", getCredential(t, ledger, "cred1").Data.VerificationStatus)
+}
+
+func TestSetAccreditationConfigRequiresIssuer(t *testing.T) {
+	ledger := newLedger()
+
+	err := ledger.Submit(recruiter, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.AdminContract{}).SetAccreditationConfig(ctx, accreditationChaincode, accreditationChannel)
+	})
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+}
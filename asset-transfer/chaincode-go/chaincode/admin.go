@@ -0,0 +1,309 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// AdminContract provides the functions that set up and maintain the world state
+type AdminContract struct {
+	contractapi.Contract
+}
+
+// GetEvaluateTransactions lists the read-only functions, which are tagged "evaluate" in the
+// contract metadata so that clients query them instead of submitting transactions
+func (c *AdminContract) GetEvaluateTransactions() []string {
+	return []string{
+		"GetKeySchemaVersion",
+		"GetIdempotencyRecord",
+		"GetStatistics",
+		"GetQuotas",
+		"GetAccreditationConfig",
+	}
+}
+
+// InitLedger initializes the ledger with some sample talent credentials. Running it again
+// adds only the samples missing from the ledger.
+func (c *AdminContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
+	credentials := []interface{}{
+		AcademicCredential{
+			BaseCredential: BaseCredential{
+				CredentialID:       "credential1",
+				TalentID:           "alicesmith01",
+				FirstName:          "Alice",
+				LastName:           "Smith",
+				Skills:             "Python, Data Analysis",
+				VerificationStatus: "Verified",
+				VerifiedBy:         "Concordia University",
+				CredentialType:     "academic",
+			},
+			Education:   "B.Sc. in Computer Science",
+			Institution: "Concordia University",
+		},
+		ProfessionalCredential{
+			BaseCredential: BaseCredential{
+				CredentialID:       "credential2",
+				TalentID:           "bobjohnson01",
+				FirstName:          "Bob",
+				LastName:           "Johnson",
+				Skills:             "Project Management, Leadership",
+				VerificationStatus: "Verified",
+				VerifiedBy:         "Company ABCDEF",
+				CredentialType:     "professional",
+			},
+			WorkExperience: "5 years as Project Manager",
+			Company:        "Company ABCDEF",
+		},
+		AcademicCredential{
+			BaseCredential: BaseCredential{
+				CredentialID:       "credential3",
+				TalentID:           "charliebrown02",
+				FirstName:          "Charlie",
+				LastName:           "Brown",
+				Skills:             "Java, Software Engineering",
+				VerificationStatus: "Pending",
+				VerifiedBy:         "",
+				CredentialType:     "academic",
+			},
+			Education:   "M.Sc. in Software Engineering",
+			Institution: "Polytechnique Montréal",
+		},
+		ProfessionalCredential{
+			BaseCredential: BaseCredential{
+				CredentialID:       "credential4",
+				TalentID:           "charliebrown02",
+				FirstName:          "Charlie",
+				LastName:           "Brown",
+				Skills:             "C, C++, Python, Shell",
+				VerificationStatus: "Pending",
+				VerifiedBy:         "",
+				CredentialType:     "professional",
+			},
+			WorkExperience: "4-Month Internship as a Software Developer",
+			Company:        "Company XYZ",
+		},
+	}
+
+	// World state writes are not visible to reads within the same transaction,
+	// so the talent profiles are built in memory and written once at the end
+	profiles := make(map[string]*TalentProfile)
+	talentIDs := []string{}
+	change := statisticsChange{}
+
+	for _, credential := range credentials {
+		credentialJSON, err := json.Marshal(credential)
+		if err != nil {
+			return fmt.Errorf("failed to marshal credential: %v", err)
+		}
+
+		// Store each credential in the ledger, in the credential namespace keyed by credentialID
+		credentialID := ""
+		var baseCredential BaseCredential
+		err = json.Unmarshal(credentialJSON, &baseCredential)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal credential: %v", err)
+		}
+		credentialID = baseCredential.CredentialID
+
+		// Samples already on the ledger are kept as they are, so InitLedger can run again
+		exists, err := credentialExists(ctx, credentialID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		err = putCredentialState(ctx, credentialID, credentialJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put talent credential to world state. %v", err)
+		}
+
+		var data CredentialData
+		if err := json.Unmarshal(credentialJSON, &data); err != nil {
+			return fmt.Errorf("failed to unmarshal credential: %v", err)
+		}
+		if err := change.add(ctx, nil, &data); err != nil {
+			return err
+		}
+
+		// Attach the credential to the profile of its talent
+		profile, ok := profiles[baseCredential.TalentID]
+		if !ok {
+			profile, err = readTalentProfile(ctx, baseCredential.TalentID)
+			if err != nil {
+				return err
+			}
+			if profile == nil {
+				profile = &TalentProfile{
+					TalentID:      baseCredential.TalentID,
+					FirstName:     baseCredential.FirstName,
+					LastName:      baseCredential.LastName,
+					CredentialIDs: []string{},
+				}
+			}
+			profiles[baseCredential.TalentID] = profile
+			talentIDs = append(talentIDs, baseCredential.TalentID)
+		}
+		if !containsString(profile.CredentialIDs, credentialID) {
+			profile.CredentialIDs = append(profile.CredentialIDs, credentialID)
+			profile.countPending(pendingChange(nil, &data))
+		}
+	}
+	if err := change.write(ctx); err != nil {
+		return err
+	}
+
+	for _, talentID := range talentIDs {
+		if err := putTalentProfile(ctx, profiles[talentID]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetKeySchemaVersion returns the layout version of the world state keys
+func (c *AdminContract) GetKeySchemaVersion(ctx contractapi.TransactionContextInterface) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(metadataObjectType, []string{"keySchemaVersion"})
+	if err != nil {
+		return "", fmt.Errorf("failed to create metadata key: %v", err)
+	}
+
+	version, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if version == nil {
+		return "1", nil
+	}
+
+	return string(version), nil
+}
+
+// MigrateKeys rekeys credentials stored under their bare ID (key schema version 1)
+// into the credential namespace, attaches them to their talent profile, and records
+// the new key schema version. It returns the number of migrated credentials.
+func (c *AdminContract) MigrateKeys(ctx contractapi.TransactionContextInterface) (int, error) {
+	// Get the identity of the invoker (the user calling the smart contract)
+	callerMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return 0, fmt.Errorf("could not get MSPID: %s", err)
+	}
+
+	// Only allow org1 (institutions) to run the migration
+	if callerMSPID != "Org1MSP" {
+		return 0, newError(ErrForbidden, "only members of Org1 (institutions) can migrate the world state")
+	}
+
+	// A range query over simple keys never returns composite keys, so it only sees legacy records
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return 0, err
+	}
+	defer resultsIterator.Close()
+
+	legacyCredentials := make(map[string][]byte)
+	legacyKeys := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+		legacyKeys = append(legacyKeys, queryResponse.Key)
+		legacyCredentials[queryResponse.Key] = queryResponse.Value
+	}
+
+	// World state writes are not visible to reads within the same transaction,
+	// so the talent profiles are built in memory and written once at the end
+	profiles := make(map[string]*TalentProfile)
+	talentIDs := []string{}
+	change := statisticsChange{}
+
+	for _, legacyKey := range legacyKeys {
+		var credential map[string]interface{}
+		err = json.Unmarshal(legacyCredentials[legacyKey], &credential)
+		if err != nil {
+			return 0, fmt.Errorf("failed to unmarshal legacy credential %s: %v", legacyKey, err)
+		}
+		var baseCredential BaseCredential
+		err = json.Unmarshal(legacyCredentials[legacyKey], &baseCredential)
+		if err != nil {
+			return 0, fmt.Errorf("failed to unmarshal legacy credential %s: %v", legacyKey, err)
+		}
+		if baseCredential.CredentialID == "" {
+			baseCredential.CredentialID = legacyKey
+			credential["CredentialID"] = legacyKey
+		}
+
+		// Attach the credential to its talent profile. Names may have drifted between
+		// legacy credentials: the first one seen becomes canonical and is copied to the others.
+		profile, ok := profiles[baseCredential.TalentID]
+		if !ok {
+			profile, err = readTalentProfile(ctx, baseCredential.TalentID)
+			if err != nil {
+				return 0, err
+			}
+			if profile == nil {
+				profile = &TalentProfile{
+					TalentID:      baseCredential.TalentID,
+					FirstName:     baseCredential.FirstName,
+					LastName:      baseCredential.LastName,
+					CredentialIDs: []string{},
+				}
+			}
+			profiles[baseCredential.TalentID] = profile
+			talentIDs = append(talentIDs, baseCredential.TalentID)
+		}
+		if !containsString(profile.CredentialIDs, baseCredential.CredentialID) {
+			profile.CredentialIDs = append(profile.CredentialIDs, baseCredential.CredentialID)
+		}
+		credential["FirstName"] = profile.FirstName
+		credential["LastName"] = profile.LastName
+
+		credentialJSON, err := json.Marshal(credential)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal credential: %v", err)
+		}
+		err = putCredentialState(ctx, baseCredential.CredentialID, credentialJSON)
+		if err != nil {
+			return 0, fmt.Errorf("failed to put talent credential to world state. %v", err)
+		}
+		err = ctx.GetStub().DelState(legacyKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete legacy key %s: %v", legacyKey, err)
+		}
+
+		// Legacy credentials were never counted
+		var data CredentialData
+		if err := json.Unmarshal(credentialJSON, &data); err != nil {
+			return 0, fmt.Errorf("failed to unmarshal credential: %v", err)
+		}
+		if err := change.add(ctx, nil, &data); err != nil {
+			return 0, err
+		}
+		profile.countPending(pendingChange(nil, &data))
+	}
+	if err := change.write(ctx); err != nil {
+		return 0, err
+	}
+
+	for _, talentID := range talentIDs {
+		if err := putTalentProfile(ctx, profiles[talentID]); err != nil {
+			return 0, err
+		}
+	}
+
+	versionKey, err := ctx.GetStub().CreateCompositeKey(metadataObjectType, []string{"keySchemaVersion"})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create metadata key: %v", err)
+	}
+	err = ctx.GetStub().PutState(versionKey, []byte(keySchemaVersion))
+	if err != nil {
+		return 0, fmt.Errorf("failed to put key schema version to world state. %v", err)
+	}
+
+	return len(legacyKeys), nil
+}
@@ -0,0 +1,108 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitLedger(t *testing.T) {
+	ledger := newLedger()
+
+	admin := chaincode.AdminContract{}
+	err := ledger.Submit(registrar, admin.InitLedger)
+	require.NoError(t, err)
+
+	ctx := ledger.BeginTx(recruiter)
+	all, err := (&chaincode.CredentialContract{}).GetAllCredentials(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+
+	profile, err := (&chaincode.TalentContract{}).GetTalentProfile(ctx, "charliebrown02")
+	require.NoError(t, err)
+	require.Equal(t, []string{"credential3", "credential4"}, profile.CredentialIDs)
+
+	statistics := getStatistics(t, ledger)
+	require.Equal(t, map[string]int64{"academic": 2, "professional": 2}, statistics.ByType)
+	require.Equal(t, map[string]int64{"Verified": 2, "Pending": 2}, statistics.ByStatus)
+
+	// Running it again changes nothing
+	err = ledger.Submit(registrar, admin.InitLedger)
+	require.NoError(t, err)
+	ctx = ledger.BeginTx(recruiter)
+	profile, err = (&chaincode.TalentContract{}).GetTalentProfile(ctx, "charliebrown02")
+	require.NoError(t, err)
+	require.Equal(t, []string{"credential3", "credential4"}, profile.CredentialIDs)
+	require.Equal(t, statistics, getStatistics(t, ledger))
+}
+
+func TestMigrateKeys(t *testing.T) {
+	ledger := newLedger()
+
+	// Version 1 stored credentials under their bare ID, with a copy of the talent's name
+	ledger.BeginTx(registrar)
+	require.NoError(t, ledger.PutState("cred1", []byte(`{"CredentialID":"cred1","CredentialType":"academic","TalentID":"talent1","FirstName":"Jane","LastName":"Doe","Skills":"Go","VerificationStatus":"Verified","VerifiedBy":"Registrar","Education":"BSc","Institution":"Concordia University"}`)))
+	require.NoError(t, ledger.PutState("cred2", []byte(`{"CredentialType":"professional","TalentID":"talent1","FirstName":"Janet","LastName":"Doe","Skills":"Go","VerificationStatus":"Pending","VerifiedBy":"","WorkExperience":"5 years","Company":"Acme"}`)))
+	ledger.Commit()
+
+	admin := chaincode.AdminContract{}
+	ctx := ledger.BeginTx(recruiter)
+	version, err := admin.GetKeySchemaVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "1", version)
+
+	err = ledger.Submit(recruiter, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := admin.MigrateKeys(ctx)
+		return err
+	})
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+
+	var migrated int
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		migrated, err = admin.MigrateKeys(ctx)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, migrated)
+
+	ctx = ledger.BeginTx(recruiter)
+	version, err = admin.GetKeySchemaVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "2", version)
+
+	legacy, err := ledger.GetState("cred1")
+	require.NoError(t, err)
+	require.Nil(t, legacy)
+
+	// The first credential seen gives the talent its canonical name
+	credential := getCredential(t, ledger, "cred2")
+	require.Equal(t, "Jane", credential.Data.FirstName)
+	require.Equal(t, "Acme", credential.Data.Company)
+
+	ctx = ledger.BeginTx(recruiter)
+	profile, err := (&chaincode.TalentContract{}).GetTalentProfile(ctx, "talent1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"cred1", "cred2"}, profile.CredentialIDs)
+
+	// Running the migration again finds nothing left to migrate
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		migrated, err = admin.MigrateKeys(ctx)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, migrated)
+}
+
+func TestUnknownFunction(t *testing.T) {
+	ledger := newLedger()
+	cc, err := contractapi.NewChaincode(chaincode.Contracts()...)
+	require.NoError(t, err)
+
+	ledger.BeginTx(registrar)
+	response := ledger.Invoke(cc, "admin:DropLedger")
+	require.Equal(t, int32(shim.ERROR), response.Status)
+	require.Equal(t, "NOT_FOUND: function DropLedger is not part of contract admin", response.Message)
+}
@@ -0,0 +1,83 @@
+package chaincode
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/v2/metadata"
+)
+
+// Contract names. Clients call a function as "name:Function"; bare function
+// names are routed to the credentials contract, which is registered first.
+const (
+	CredentialContractName  = "credentials"
+	TalentContractName      = "talents"
+	IssuerContractName      = "issuers"
+	AdminContractName       = "admin"
+	EndorsementContractName = "endorsements"
+)
+
+// contractVersion is the version published in the metadata of every contract
+const contractVersion = "1.0.0"
+
+// Contracts returns the contracts of the chaincode, default contract first
+func Contracts() []contractapi.ContractInterface {
+	credentials := &CredentialContract{}
+	credentials.Contract = newContract(CredentialContractName, "Issue, read and update talent credentials", credentials.GetEvaluateTransactions())
+	talents := &TalentContract{}
+	talents.Contract = newContract(TalentContractName, "Manage the talent profiles that own credentials", talents.GetEvaluateTransactions())
+	issuers := &IssuerContract{Contract: newContract(IssuerContractName, "Approve or revoke credentials as an issuing institution", nil)}
+	endorsements := &EndorsementContract{}
+	endorsements.Contract = newContract(EndorsementContractName, "Endorse the skills of professional credentials", endorsements.GetEvaluateTransactions())
+	admin := &AdminContract{}
+	admin.Contract = newContract(AdminContractName, "Initialize and migrate the world state", admin.GetEvaluateTransactions())
+
+	return []contractapi.ContractInterface{credentials, talents, issuers, endorsements, admin}
+}
+
+// newContract creates the base contract shared by every contract, with its name and hooks. The
+// idempotency keys of the evaluate functions are ignored, as evaluations write nothing.
+func newContract(name string, description string, evaluate []string) contractapi.Contract {
+	readOnly := func(ctx contractapi.TransactionContextInterface) bool {
+		function, _ := ctx.GetStub().GetFunctionAndParameters()
+		return containsString(evaluate, function[strings.LastIndex(function, ":")+1:])
+	}
+
+	return contractapi.Contract{
+		Name: name,
+		Info: metadata.InfoMetadata{
+			Title:       name,
+			Description: description,
+			Version:     contractVersion,
+		},
+		BeforeTransaction: func(ctx contractapi.TransactionContextInterface) error {
+			if readOnly(ctx) {
+				return nil
+			}
+			return checkIdempotencyKey(ctx, name)
+		},
+		AfterTransaction: func(ctx contractapi.TransactionContextInterface, result interface{}) error {
+			if readOnly(ctx) {
+				return nil
+			}
+			return recordIdempotencyKey(ctx, name, result)
+		},
+		UnknownTransaction: func(ctx contractapi.TransactionContextInterface) error {
+			function, _ := ctx.GetStub().GetFunctionAndParameters()
+			return newError(ErrNotFound, "function %s is not part of contract %s", strings.TrimPrefix(function, name+":"), name)
+		},
+	}
+}
+
+// txTimestamp returns the timestamp of the current transaction in RFC 3339 format. It is set by
+// the client in the proposal, so every endorser sees the same value.
+func txTimestamp(ctx contractapi.TransactionContextInterface) (string, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to read transaction timestamp: %v", err)
+	}
+
+	return timestamp.AsTime().UTC().Format(time.RFC3339), nil
+}
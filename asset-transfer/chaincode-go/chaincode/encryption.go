@@ -0,0 +1,163 @@
+package chaincode
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Sensitive credential fields can be stored encrypted with AES-GCM, for members that keep
+// credentials on the channel ledger rather than in private data collections. The key is passed
+// in the transient data of each proposal, so it never reaches the ledger, and the names of the
+// encrypted fields are listed in the EncryptedFields of the credential. Writes encrypt whenever
+// the key is supplied; reads decrypt whenever it is supplied and matches.
+//
+// The key is visible to the endorsing peers, which must be trusted with it.
+
+// fieldEncryptionTransientKey is the transient data entry holding the AES key (16, 24 or 32 bytes)
+const fieldEncryptionTransientKey = "fieldEncryptionKey"
+
+// encryptableFields are the credential fields encrypted when a key is supplied. Names, the
+// institution and the company stay in clear text: profiles, accreditation checks and statistics
+// rely on them.
+var encryptableFields = []string{"Skills", "Education", "WorkExperience"}
+
+// encryptableField returns a pointer to an encryptable field of a credential
+func encryptableField(data *CredentialData, field string) *string {
+	switch field {
+	case "Skills":
+		return &data.Skills
+	case "Education":
+		return &data.Education
+	case "WorkExperience":
+		return &data.WorkExperience
+	}
+
+	return nil
+}
+
+// fieldEncryptionKey returns the AES key from the transient data, or nil when none was supplied
+func fieldEncryptionKey(ctx contractapi.TransactionContextInterface) ([]byte, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transient data: %v", err)
+	}
+
+	key := transient[fieldEncryptionTransientKey]
+	if key == nil {
+		return nil, nil
+	}
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, newError(ErrInvalidArgument, "%s must be 16, 24 or 32 bytes long, got %d", fieldEncryptionTransientKey, len(key))
+	}
+
+	return key, nil
+}
+
+// encryptCredentialFields encrypts the non-empty encryptable fields of a credential that are
+// not encrypted yet, when a key is supplied
+func encryptCredentialFields(ctx contractapi.TransactionContextInterface, data *CredentialData) error {
+	key, err := fieldEncryptionKey(ctx)
+	if err != nil || key == nil {
+		return err
+	}
+	aead, err := newFieldCipher(key)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range encryptableFields {
+		value := encryptableField(data, field)
+		if *value == "" || containsString(data.EncryptedFields, field) {
+			continue
+		}
+
+		// Every endorsing peer must produce the same ciphertext, so the nonce cannot be random.
+		// It is derived from the transaction, credential and field instead, which a key never
+		// encrypts twice with different contents.
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(ctx.GetStub().GetTxID() + "\x00" + data.CredentialID + "\x00" + field))
+		nonce := mac.Sum(nil)[:aead.NonceSize()]
+
+		sealed := aead.Seal(nonce, nonce, []byte(*value), fieldAdditionalData(data, field))
+		*value = base64.StdEncoding.EncodeToString(sealed)
+		data.EncryptedFields = append(data.EncryptedFields, field)
+	}
+
+	return nil
+}
+
+// decryptCredentialFields decrypts the encrypted fields of a credential when a key is supplied.
+// Fields the key does not open, encrypted by another member, stay encrypted and listed.
+func decryptCredentialFields(ctx contractapi.TransactionContextInterface, data *CredentialData) error {
+	if len(data.EncryptedFields) == 0 {
+		return nil
+	}
+	key, err := fieldEncryptionKey(ctx)
+	if err != nil || key == nil {
+		return err
+	}
+	aead, err := newFieldCipher(key)
+	if err != nil {
+		return err
+	}
+
+	stillEncrypted := []string{}
+	for _, field := range data.EncryptedFields {
+		value := encryptableField(data, field)
+		if value == nil {
+			return fmt.Errorf("unknown encrypted field %s in credential %s", field, data.CredentialID)
+		}
+
+		sealed, err := base64.StdEncoding.DecodeString(*value)
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return fmt.Errorf("malformed encrypted field %s in credential %s", field, data.CredentialID)
+		}
+		plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], fieldAdditionalData(data, field))
+		if err != nil {
+			stillEncrypted = append(stillEncrypted, field)
+			continue
+		}
+		*value = string(plaintext)
+	}
+
+	if len(stillEncrypted) == 0 {
+		stillEncrypted = nil
+	}
+	data.EncryptedFields = stillEncrypted
+
+	return nil
+}
+
+// requireDecrypted rejects changes to the given fields of a credential, or to any field if
+// none is given, while they are encrypted
+func requireDecrypted(data *CredentialData, fields ...string) error {
+	for _, field := range data.EncryptedFields {
+		if len(fields) == 0 || containsString(fields, field) {
+			return newError(ErrForbidden, "the field %s of credential %s is encrypted, supply its key in the %s transient entry", field, data.CredentialID, fieldEncryptionTransientKey)
+		}
+	}
+
+	return nil
+}
+
+// newFieldCipher creates the AES-GCM cipher of a field encryption key
+func newFieldCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// fieldAdditionalData binds a ciphertext to its credential and field, so that it cannot be
+// moved to another one
+func fieldAdditionalData(data *CredentialData, field string) []byte {
+	return []byte(data.CredentialID + "\x00" + field)
+}
@@ -0,0 +1,101 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgertest"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	org1Key = []byte("0123456789abcdef0123456789abcdef")
+	org2Key = []byte("fedcba9876543210fedcba9876543210")
+)
+
+// beginTxWithKey starts a transaction of the registrar passing a field encryption key, if any
+func beginTxWithKey(ledger *ledgertest.Stub, key []byte) contractapi.TransactionContextInterface {
+	ctx := ledger.BeginTx(registrar)
+	if key != nil {
+		ledger.SetTransient(map[string][]byte{"fieldEncryptionKey": key})
+	}
+	return ctx
+}
+
+func TestCreateCredentialWithEncryptionKey(t *testing.T) {
+	ledger := newLedger()
+	credentials := chaincode.CredentialContract{}
+
+	ctx := beginTxWithKey(ledger, org1Key)
+	_, err := credentials.CreateProfessionalCredential(ctx, "cred1", "talent1", "Jane", "Doe", "Go, Kubernetes", "5 years at Acme", "Acme")
+	require.NoError(t, err)
+	ledger.Commit()
+
+	// The world state only holds ciphertext for the encrypted fields
+	key, err := ledger.CreateCompositeKey("credential", []string{"cred1"})
+	require.NoError(t, err)
+	value, err := ledger.GetState(key)
+	require.NoError(t, err)
+	var stored chaincode.CredentialData
+	require.NoError(t, json.Unmarshal(value, &stored))
+	require.Equal(t, []string{"Skills", "WorkExperience"}, stored.EncryptedFields)
+	require.NotContains(t, stored.Skills, "Kubernetes")
+	require.NotContains(t, stored.WorkExperience, "Acme")
+	require.Equal(t, "Acme", stored.Company)
+
+	ctx = beginTxWithKey(ledger, org1Key)
+	credential, err := credentials.GetTalentCredential(ctx, "cred1")
+	require.NoError(t, err)
+	require.Equal(t, "Go, Kubernetes", credential.Data.Skills)
+	require.Equal(t, "5 years at Acme", credential.Data.WorkExperience)
+	require.Empty(t, credential.Data.EncryptedFields)
+
+	// Without the key, or with another member's key, the fields stay encrypted and marked
+	credential = getCredential(t, ledger, "cred1")
+	require.Equal(t, stored.Skills, credential.Data.Skills)
+	require.Equal(t, []string{"Skills", "WorkExperience"}, credential.Data.EncryptedFields)
+
+	ctx = beginTxWithKey(ledger, org2Key)
+	credential, err = credentials.GetTalentCredential(ctx, "cred1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Skills", "WorkExperience"}, credential.Data.EncryptedFields)
+}
+
+func TestUpdateEncryptedSkillsRequiresKey(t *testing.T) {
+	ledger := newLedger()
+	credentials := chaincode.CredentialContract{}
+
+	ctx := beginTxWithKey(ledger, org1Key)
+	_, err := credentials.CreateAcademicCredential(ctx, "cred1", "talent1", "Jane", "Doe", "Go", "BSc Computer Science", "Concordia University")
+	require.NoError(t, err)
+	ledger.Commit()
+
+	ctx = beginTxWithKey(ledger, nil)
+	err = credentials.UpdateSkills(ctx, "cred1", "Go, Rust")
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+
+	// Verification does not touch encrypted fields and needs no key
+	verify(t, ledger, "cred1", "Verified")
+
+	ctx = beginTxWithKey(ledger, org1Key)
+	err = credentials.UpdateSkills(ctx, "cred1", "Go, Rust")
+	require.NoError(t, err)
+	ledger.Commit()
+
+	ctx = beginTxWithKey(ledger, org1Key)
+	credential, err := credentials.GetTalentCredential(ctx, "cred1")
+	require.NoError(t, err)
+	require.Equal(t, "Go, Rust", credential.Data.Skills)
+	require.Equal(t, "BSc Computer Science", credential.Data.Education)
+	require.Equal(t, "Verified", credential.Data.VerificationStatus)
+}
+
+func TestInvalidEncryptionKey(t *testing.T) {
+	ledger := newLedger()
+
+	ctx := beginTxWithKey(ledger, []byte("short"))
+	_, err := (&chaincode.CredentialContract{}).CreateAcademicCredential(ctx, "cred1", "talent1", "Jane", "Doe", "Go", "BSc Computer Science", "Concordia University")
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
+}
@@ -0,0 +1,252 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// EndorsementContract provides functions for endorsing the skills listed on professional credentials
+type EndorsementContract struct {
+	contractapi.Contract
+}
+
+// GetEvaluateTransactions lists the read-only functions, which are tagged "evaluate" in the
+// contract metadata so that clients query them instead of submitting transactions
+func (c *EndorsementContract) GetEvaluateTransactions() []string {
+	return []string{
+		"GetEndorsements",
+	}
+}
+
+// Endorsement is a skill endorsement signed by the identity that submitted it
+type Endorsement struct {
+	CredentialID string `json:"CredentialID"`
+	Skill        string `json:"Skill"`
+	EndorserMSP  string `json:"EndorserMSP"`
+	EndorserID   string `json:"EndorserID"` // Subject and issuer of the endorser's certificate
+	Comment      string `json:"Comment"`
+	Timestamp    string `json:"Timestamp"` // Transaction timestamp, RFC 3339
+}
+
+// EndorseSkill endorses one of the skills listed on a professional credential. Only talent
+// identities endorse skills, each one once, and neither the talent nor the identity that created
+// the credential may endorse it.
+func (c *EndorsementContract) EndorseSkill(ctx contractapi.TransactionContextInterface, credentialID string, skillName string, comment string) error {
+	if err := validateText("skillName", skillName, true); err != nil {
+		return err
+	}
+	if err := validateText("comment", comment, false); err != nil {
+		return err
+	}
+
+	credential, err := readCredential(ctx, credentialID)
+	if err != nil {
+		return err
+	}
+	if credential.Type != credentialTypeProfessional {
+		return newError(ErrInvalidArgument, "only skills of professional credentials can be endorsed, %s is %s", credentialID, credential.Type)
+	}
+	if err := checkNotSuperseded(credential); err != nil {
+		return err
+	}
+	if err := requireDecrypted(&credential.Data, "Skills"); err != nil {
+		return err
+	}
+
+	skill, ok := findSkill(credential.Data.Skills, skillName)
+	if !ok {
+		return newError(ErrInvalidArgument, "the skill %q is not listed on credential %s", skillName, credentialID)
+	}
+
+	endorserMSP, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("could not get MSPID: %v", err)
+	}
+	endorserID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("could not get client ID: %v", err)
+	}
+
+	// Endorsers are talents, whose identities carry their talent ID as a certificate attribute.
+	// Identities without it are refused, as they could be the credential's talent.
+	talentID, found, err := ctx.GetClientIdentity().GetAttributeValue("talentID")
+	if err != nil {
+		return fmt.Errorf("could not read talentID attribute: %v", err)
+	}
+	if !found || talentID == "" {
+		return newError(ErrForbidden, "only identities with a talentID certificate attribute can endorse skills")
+	}
+	if talentID == credential.Data.TalentID {
+		return newError(ErrForbidden, "talents cannot endorse their own credentials")
+	}
+	endorser, err := callerHash(ctx)
+	if err != nil {
+		return err
+	}
+	if endorser == credential.Data.CreatedBy {
+		return newError(ErrForbidden, "the creator of credential %s cannot endorse it", credentialID)
+	}
+
+	key, err := endorsementKey(ctx, credentialID, skill, endorserMSP, endorserID)
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return newError(ErrAlreadyExists, "the skill %q of credential %s was already endorsed by this identity", skill, credentialID)
+	}
+
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	endorsementJSON, err := json.Marshal(Endorsement{
+		CredentialID: credentialID,
+		Skill:        skill,
+		EndorserMSP:  endorserMSP,
+		EndorserID:   endorserID,
+		Comment:      comment,
+		Timestamp:    timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal endorsement: %v", err)
+	}
+
+	return ctx.GetStub().PutState(key, endorsementJSON)
+}
+
+// GetEndorsements returns the endorsements of the skills currently listed on a credential
+func (c *EndorsementContract) GetEndorsements(ctx contractapi.TransactionContextInterface, credentialID string) ([]*Endorsement, error) {
+	credential, err := readCredential(ctx, credentialID)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(endorsementObjectType, []string{credentialID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	endorsements := []*Endorsement{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var endorsement Endorsement
+		if err := json.Unmarshal(queryResponse.Value, &endorsement); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal endorsement: %v", err)
+		}
+		if !skillListed(&credential.Data, endorsement.Skill) {
+			continue
+		}
+		endorsements = append(endorsements, &endorsement)
+	}
+
+	return endorsements, nil
+}
+
+// endorsementKey returns the world state key of an endorsement. Keys are grouped by credential
+// and skill, and the endorser identity is hashed to keep certificate names out of the key.
+func endorsementKey(ctx contractapi.TransactionContextInterface, credentialID string, skill string, endorserMSP string, endorserID string) (string, error) {
+	endorser := sha256.Sum256([]byte(endorserMSP + "/" + endorserID))
+	key, err := ctx.GetStub().CreateCompositeKey(endorsementObjectType, []string{credentialID, skill, hex.EncodeToString(endorser[:])})
+	if err != nil {
+		return "", fmt.Errorf("failed to create endorsement key: %v", err)
+	}
+
+	return key, nil
+}
+
+// findSkill returns the skill of a comma-separated skill list matching a name, ignoring case
+func findSkill(skills string, name string) (string, bool) {
+	for _, skill := range strings.Split(skills, ",") {
+		skill = strings.TrimSpace(skill)
+		if skill != "" && strings.EqualFold(skill, strings.TrimSpace(name)) {
+			return skill, true
+		}
+	}
+
+	return "", false
+}
+
+// skillListed reports whether an endorsed skill is still listed on a credential. Endorsements
+// of skills removed by UpdateSkills or a reissue stay on the ledger but no longer count. While
+// the skills are encrypted they cannot be compared, and every endorsement is kept.
+func skillListed(data *CredentialData, skill string) bool {
+	if requireDecrypted(data, "Skills") != nil {
+		return true
+	}
+	_, ok := findSkill(data.Skills, skill)
+
+	return ok
+}
+
+// countEndorsements returns the number of endorsements per skill of each credential, in one scan.
+// An empty credentialID counts the endorsements of every credential.
+func countEndorsements(ctx contractapi.TransactionContextInterface, credentialID string) (map[string]map[string]int, error) {
+	attributes := []string{}
+	if credentialID != "" {
+		attributes = append(attributes, credentialID)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(endorsementObjectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	counts := map[string]map[string]int{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(keyParts) != 3 {
+			return nil, fmt.Errorf("invalid endorsement key %q: %v", queryResponse.Key, err)
+		}
+		if counts[keyParts[0]] == nil {
+			counts[keyParts[0]] = map[string]int{}
+		}
+		counts[keyParts[0]][keyParts[1]]++
+	}
+
+	return counts, nil
+}
+
+// addEndorsementCounts sets the endorsement counts of the skills currently listed on the
+// credentials of a list of envelopes
+func addEndorsementCounts(ctx contractapi.TransactionContextInterface, credentialID string, envelopes ...*CredentialEnvelope) error {
+	counts, err := countEndorsements(ctx, credentialID)
+	if err != nil {
+		return err
+	}
+
+	for _, envelope := range envelopes {
+		envelope.SkillEndorsements = nil
+		for skill, count := range counts[envelope.Data.CredentialID] {
+			if !skillListed(&envelope.Data, skill) {
+				continue
+			}
+			if envelope.SkillEndorsements == nil {
+				envelope.SkillEndorsements = map[string]int{}
+			}
+			envelope.SkillEndorsements[skill] = count
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// colleague and supervisor are talents of both organizations, who did not issue the test credentials
+var (
+	colleague  = mustNewIdentity("Org1MSP", "colleague@org1.example.com", map[string]string{"role": "talent", "talentID": "talent2"})
+	supervisor = mustNewIdentity("Org2MSP", "supervisor@org2.example.com", map[string]string{"role": "talent", "talentID": "talent3"})
+)
+
+func TestEndorseSkill(t *testing.T) {
+	ledger := newLedger()
+	createProfessional(t, ledger, "cred1", "talent1")
+
+	endorsements := chaincode.EndorsementContract{}
+	err := ledger.Submit(supervisor, func(ctx contractapi.TransactionContextInterface) error {
+		// Skills are matched regardless of case and spacing
+		return endorsements.EndorseSkill(ctx, "cred1", " kubernetes", "Ran our clusters")
+	})
+	require.NoError(t, err)
+	err = ledger.Submit(colleague, func(ctx contractapi.TransactionContextInterface) error {
+		return endorsements.EndorseSkill(ctx, "cred1", "Kubernetes", "")
+	})
+	require.NoError(t, err)
+
+	ctx := ledger.BeginTx(recruiter)
+	endorsed, err := endorsements.GetEndorsements(ctx, "cred1")
+	require.NoError(t, err)
+	require.Len(t, endorsed, 2)
+	require.Equal(t, "Kubernetes", endorsed[0].Skill)
+	require.ElementsMatch(t, []string{"Org1MSP", "Org2MSP"}, []string{endorsed[0].EndorserMSP, endorsed[1].EndorserMSP})
+
+	credential := getCredential(t, ledger, "cred1")
+	require.Equal(t, map[string]int{"Kubernetes": 2}, credential.SkillEndorsements)
+
+	// Each identity endorses a skill once
+	err = ledger.Submit(supervisor, func(ctx contractapi.TransactionContextInterface) error {
+		return endorsements.EndorseSkill(ctx, "cred1", "Kubernetes", "Again")
+	})
+	requireErrorCode(t, err, chaincode.ErrAlreadyExists)
+}
+
+func TestEndorseSkillRejections(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+	createProfessional(t, ledger, "cred2", "talent1")
+
+	endorsements := chaincode.EndorsementContract{}
+	err := ledger.Submit(supervisor, func(ctx contractapi.TransactionContextInterface) error {
+		return endorsements.EndorseSkill(ctx, "cred1", "Go", "")
+	})
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
+
+	err = ledger.Submit(supervisor, func(ctx contractapi.TransactionContextInterface) error {
+		return endorsements.EndorseSkill(ctx, "cred2", "Rust", "")
+	})
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
+
+	err = ledger.Submit(talent1, func(ctx contractapi.TransactionContextInterface) error {
+		return endorsements.EndorseSkill(ctx, "cred2", "Go", "")
+	})
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+
+	// Identities without a talent ID could be the talent's own
+	err = ledger.Submit(recruiter, func(ctx contractapi.TransactionContextInterface) error {
+		return endorsements.EndorseSkill(ctx, "cred2", "Go", "")
+	})
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+
+	// The identity that created the credential cannot endorse it either
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return endorsements.EndorseSkill(ctx, "cred2", "Go", "")
+	})
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+
+	err = ledger.Submit(supervisor, func(ctx contractapi.TransactionContextInterface) error {
+		return endorsements.EndorseSkill(ctx, "cred3", "Go", "")
+	})
+	requireErrorCode(t, err, chaincode.ErrNotFound)
+}
+
+func TestEndorsementsOfRemovedSkills(t *testing.T) {
+	ledger := newLedger()
+	createProfessional(t, ledger, "cred1", "talent1")
+
+	endorsements := chaincode.EndorsementContract{}
+	for _, skill := range []string{"Go", "Kubernetes"} {
+		err := ledger.Submit(supervisor, func(ctx contractapi.TransactionContextInterface) error {
+			return endorsements.EndorseSkill(ctx, "cred1", skill, "")
+		})
+		require.NoError(t, err)
+	}
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.CredentialContract{}).UpdateSkills(ctx, "cred1", "Go, Terraform")
+	})
+	require.NoError(t, err)
+
+	// The endorsement of Kubernetes stays on the ledger but no longer counts
+	credential := getCredential(t, ledger, "cred1")
+	require.Equal(t, map[string]int{"Go": 1}, credential.SkillEndorsements)
+
+	endorsed, err := endorsements.GetEndorsements(ledger.BeginTx(recruiter), "cred1")
+	require.NoError(t, err)
+	require.Len(t, endorsed, 1)
+	require.Equal(t, "Go", endorsed[0].Skill)
+
+	_, err = endorsements.GetEndorsements(ledger.BeginTx(recruiter), "cred2")
+	requireErrorCode(t, err, chaincode.ErrNotFound)
+}
@@ -0,0 +1,140 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// credentialSchemaVersion is the version of the CredentialData layout returned in envelopes.
+// Bump it whenever a field is added, removed or changes meaning.
+// Version 2 added the Supersedes and SupersededBy references, version 3 CreatedAt, version 4
+// EncryptedFields, version 5 NameChanges, version 6 CreatedBy.
+const credentialSchemaVersion = 6
+
+// maxSupersedeDepth bounds the number of reissues followed when resolving a credential
+const maxSupersedeDepth = 16
+
+// Credential types
+const (
+	credentialTypeAcademic     = "academic"
+	credentialTypeProfessional = "professional"
+)
+
+// CredentialData holds the fields of any credential type. Type-specific fields
+// are only set for the credential type they belong to.
+type CredentialData struct {
+	BaseCredential
+	Education      string `json:"Education,omitempty" metadata:",optional"`      // Academic only
+	Institution    string `json:"Institution,omitempty" metadata:",optional"`    // Academic only
+	Company        string `json:"Company,omitempty" metadata:",optional"`        // Professional only
+	WorkExperience string `json:"WorkExperience,omitempty" metadata:",optional"` // Professional only
+}
+
+// CredentialEnvelope is the single, discriminated shape in which every credential is returned
+type CredentialEnvelope struct {
+	Type          string         `json:"type"`          // Discriminator: academic, professional
+	SchemaVersion int            `json:"schemaVersion"` // Version of the data layout
+	Data          CredentialData `json:"data"`
+	ResolvedFrom  string         `json:"resolvedFrom,omitempty" metadata:",optional"` // Superseded ID that was requested, if any
+	// Number of endorsements of each endorsed skill, professional credentials only
+	SkillEndorsements map[string]int `json:"skillEndorsements,omitempty" metadata:",optional"`
+}
+
+// newCredentialEnvelope wraps the stored JSON of a credential in an envelope
+func newCredentialEnvelope(credentialJSON []byte) (*CredentialEnvelope, error) {
+	var data CredentialData
+	err := json.Unmarshal(credentialJSON, &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal talent credential: %v", err)
+	}
+
+	switch data.CredentialType {
+	case credentialTypeAcademic:
+		data.Company = ""
+		data.WorkExperience = ""
+	case credentialTypeProfessional:
+		data.Education = ""
+		data.Institution = ""
+	default:
+		return nil, newError(ErrInvalidArgument, "the talent credential type %v does not exist", data.CredentialType)
+	}
+
+	return &CredentialEnvelope{
+		Type:          data.CredentialType,
+		SchemaVersion: credentialSchemaVersion,
+		Data:          data,
+	}, nil
+}
+
+// readCredential returns the envelope of the credential with given ID, its encrypted fields
+// decrypted when the key is supplied
+func readCredential(ctx contractapi.TransactionContextInterface, credentialID string) (*CredentialEnvelope, error) {
+	if err := validateID("credentialID", credentialID); err != nil {
+		return nil, err
+	}
+
+	credentialJSON, err := getCredentialState(ctx, credentialID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if credentialJSON == nil {
+		return nil, newError(ErrNotFound, "the talent credential %s does not exist", credentialID)
+	}
+
+	envelope, err := newCredentialEnvelope(credentialJSON)
+	if err != nil {
+		return nil, err
+	}
+	if err := decryptCredentialFields(ctx, &envelope.Data); err != nil {
+		return nil, err
+	}
+
+	return envelope, nil
+}
+
+// readCurrentCredential returns the envelope of the current version of a credential, following
+// the SupersededBy references of reissued credentials
+func readCurrentCredential(ctx contractapi.TransactionContextInterface, credentialID string) (*CredentialEnvelope, error) {
+	envelope, err := readCredential(ctx, credentialID)
+	if err != nil {
+		return nil, err
+	}
+
+	for depth := 0; envelope.Data.SupersededBy != ""; depth++ {
+		if depth == maxSupersedeDepth {
+			return nil, newError(ErrConflict, "the credential %s was reissued more than %d times", credentialID, maxSupersedeDepth)
+		}
+		envelope, err = readCredential(ctx, envelope.Data.SupersededBy)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if envelope.Data.CredentialID != credentialID {
+		envelope.ResolvedFrom = credentialID
+	}
+
+	if err := addEndorsementCounts(ctx, envelope.Data.CredentialID, envelope); err != nil {
+		return nil, err
+	}
+
+	return envelope, nil
+}
+
+// putCredential writes the data of a credential envelope to world state, encrypting its
+// sensitive fields when the key is supplied
+func putCredential(ctx contractapi.TransactionContextInterface, envelope *CredentialEnvelope) error {
+	data := envelope.Data
+	data.EncryptedFields = append([]string{}, envelope.Data.EncryptedFields...)
+	if err := encryptCredentialFields(ctx, &data); err != nil {
+		return err
+	}
+
+	credentialJSON, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal updated %s credential: %v", envelope.Type, err)
+	}
+
+	return putCredentialState(ctx, envelope.Data.CredentialID, credentialJSON)
+}
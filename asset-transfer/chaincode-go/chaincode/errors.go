@@ -0,0 +1,54 @@
+package chaincode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode is a machine-readable error category returned by the chaincode.
+// The code is the first token of the error message, so clients can recover it
+// from the endorsement error returned by the Gateway.
+type ErrorCode string
+
+// Error codes returned by the chaincode
+const (
+	ErrNotFound                 ErrorCode = "NOT_FOUND"
+	ErrAlreadyExists            ErrorCode = "ALREADY_EXISTS"
+	ErrForbidden                ErrorCode = "FORBIDDEN"
+	ErrInvalidArgument          ErrorCode = "INVALID_ARGUMENT"
+	ErrConflict                 ErrorCode = "CONFLICT"
+	ErrAlreadyApplied           ErrorCode = "ALREADY_APPLIED" // The idempotency key was already committed
+	ErrQuotaExceeded            ErrorCode = "QUOTA_EXCEEDED"
+	ErrIdempotencyKeyReused     ErrorCode = "IDEMPOTENCY_KEY_REUSED"    // The idempotency key was already committed for another request
+	ErrNotAccredited            ErrorCode = "NOT_ACCREDITED"            // The institution of an academic credential is not accredited
+	ErrAccreditationUnavailable ErrorCode = "ACCREDITATION_UNAVAILABLE" // The accreditation chaincode failed or returned an invalid answer
+)
+
+// ChaincodeError is an error carrying an ErrorCode
+type ChaincodeError struct {
+	Code    ErrorCode
+	Message string
+}
+
+// Error formats the error as "CODE: message"
+func (e *ChaincodeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// newError creates a ChaincodeError with a formatted message
+func newError(code ErrorCode, format string, args ...interface{}) error {
+	return &ChaincodeError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// ErrorCodeOf returns the code of a ChaincodeError, or an empty code for any other error
+func ErrorCodeOf(err error) ErrorCode {
+	var chaincodeError *ChaincodeError
+	if errors.As(err, &chaincodeError) {
+		return chaincodeError.Code
+	}
+
+	return ""
+}
@@ -0,0 +1,83 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgertest"
+	"github.com/stretchr/testify/require"
+)
+
+// Identities of the test network: Org1 members are institutions issuing credentials, Org2
+// members are companies and talents. Talent certificates carry their talent ID as an attribute.
+var (
+	registrar = mustNewIdentity("Org1MSP", "registrar@org1.example.com", nil)
+	recruiter = mustNewIdentity("Org2MSP", "recruiter@org2.example.com", nil)
+	talent1   = mustNewIdentity("Org2MSP", "talent1@org2.example.com", map[string]string{"role": "talent", "talentID": "talent1"})
+)
+
+func mustNewIdentity(mspID string, commonName string, attributes map[string]string) *ledgertest.Identity {
+	identity, err := ledgertest.NewIdentity(mspID, commonName, attributes)
+	if err != nil {
+		panic(err)
+	}
+	return identity
+}
+
+// newLedger returns an empty ledger of the test channel
+func newLedger() *ledgertest.Stub {
+	return ledgertest.NewStub("mychannel")
+}
+
+// createAcademic issues an academic credential from Concordia University to a talent named Jane Doe
+func createAcademic(t *testing.T, ledger *ledgertest.Stub, credentialID string, talentID string) {
+	t.Helper()
+
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := (&chaincode.CredentialContract{}).CreateAcademicCredential(ctx, credentialID, talentID, "Jane", "Doe", "Go, SQL", "BSc Computer Science", "Concordia University")
+		return err
+	})
+	require.NoError(t, err)
+}
+
+// createProfessional issues a professional credential from Acme to a talent named Jane Doe
+func createProfessional(t *testing.T, ledger *ledgertest.Stub, credentialID string, talentID string) {
+	t.Helper()
+
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := (&chaincode.CredentialContract{}).CreateProfessionalCredential(ctx, credentialID, talentID, "Jane", "Doe", "Go, Kubernetes", "5 years at Acme", "Acme")
+		return err
+	})
+	require.NoError(t, err)
+}
+
+// verify sets the verification status of a credential as the registrar
+func verify(t *testing.T, ledger *ledgertest.Stub, credentialID string, status string) {
+	t.Helper()
+
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.IssuerContract{}).UpdateVerificationStatus(ctx, credentialID, status, "Registrar")
+	})
+	require.NoError(t, err)
+}
+
+// getCredential reads a credential with GetTalentCredential
+func getCredential(t *testing.T, ledger *ledgertest.Stub, credentialID string) *chaincode.CredentialEnvelope {
+	t.Helper()
+
+	var credential *chaincode.CredentialEnvelope
+	err := ledger.Evaluate(recruiter, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		credential, err = (&chaincode.CredentialContract{}).GetTalentCredential(ctx, credentialID)
+		return err
+	})
+	require.NoError(t, err)
+	return credential
+}
+
+func requireErrorCode(t *testing.T, err error, code chaincode.ErrorCode) {
+	t.Helper()
+	require.Error(t, err)
+	require.Equal(t, code, chaincode.ErrorCodeOf(err), err.Error())
+}
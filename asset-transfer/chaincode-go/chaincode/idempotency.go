@@ -0,0 +1,199 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// idempotencyTransientKey is the transient map entry holding the optional idempotency key
+// of a transaction. Transient data keeps the key out of the function signatures.
+const idempotencyTransientKey = "idempotencyKey"
+
+// IdempotencyRecord is stored for every transaction submitted with an idempotency key, so that
+// a retried request can be recognized and answered with the result of the original one. The
+// result itself stays in the block of the transaction: the record only holds its hash.
+type IdempotencyRecord struct {
+	IdempotencyKey string `json:"IdempotencyKey"`
+	Function       string `json:"Function"`    // contract:function that was called
+	RequestHash    string `json:"RequestHash"` // SHA-256 of the function and its arguments
+	TxID           string `json:"TxID"`        // Transaction that committed the request
+	Timestamp      string `json:"Timestamp"`   // Transaction timestamp, RFC 3339
+	ResultHash     string `json:"ResultHash"`  // SHA-256 of the payload returned by the function
+}
+
+// GetIdempotencyRecord returns the record of the transaction the calling identity committed with
+// given idempotency key. Keys are scoped to the identity: the records of others are not found.
+func (c *AdminContract) GetIdempotencyRecord(ctx contractapi.TransactionContextInterface, idempotencyKey string) (*IdempotencyRecord, error) {
+	record, err := readIdempotencyRecord(ctx, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, newError(ErrNotFound, "no transaction was committed with idempotency key %s", idempotencyKey)
+	}
+
+	return record, nil
+}
+
+// transactionIdempotencyKey returns the idempotency key passed in the transient map, if any
+func transactionIdempotencyKey(ctx contractapi.TransactionContextInterface) (string, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("failed to read transient data: %v", err)
+	}
+
+	key := string(transient[idempotencyTransientKey])
+	if key == "" {
+		return "", nil
+	}
+	if err := validateID("idempotencyKey", key); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// requestHash identifies a call by its qualified function name and arguments
+func requestHash(ctx contractapi.TransactionContextInterface, contractName string) (string, string, error) {
+	function, args := ctx.GetStub().GetFunctionAndParameters()
+	function = contractName + ":" + function[strings.LastIndex(function, ":")+1:]
+
+	request, err := json.Marshal(append([]string{function}, args...))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal request: %v", err)
+	}
+	hash := sha256.Sum256(request)
+
+	return function, hex.EncodeToString(hash[:]), nil
+}
+
+// checkIdempotencyKey rejects a transaction whose idempotency key was already committed by the
+// calling identity. A retry of the same request fails with ALREADY_APPLIED, a different request
+// reusing the key with IDEMPOTENCY_KEY_REUSED.
+func checkIdempotencyKey(ctx contractapi.TransactionContextInterface, contractName string) error {
+	key, err := transactionIdempotencyKey(ctx)
+	if err != nil || key == "" {
+		return err
+	}
+
+	record, err := readIdempotencyRecord(ctx, key)
+	if err != nil || record == nil {
+		return err
+	}
+
+	_, hash, err := requestHash(ctx, contractName)
+	if err != nil {
+		return err
+	}
+	if hash != record.RequestHash {
+		return newError(ErrIdempotencyKeyReused, "idempotency key %s was already used for a different %s request", key, record.Function)
+	}
+
+	return newError(ErrAlreadyApplied, "the request with idempotency key %s was already committed in transaction %s", key, record.TxID)
+}
+
+// recordIdempotencyKey stores the hash of the result of a transaction submitted with an
+// idempotency key
+func recordIdempotencyKey(ctx contractapi.TransactionContextInterface, contractName string, result interface{}) error {
+	key, err := transactionIdempotencyKey(ctx)
+	if err != nil || key == "" {
+		return err
+	}
+
+	function, hash, err := requestHash(ctx, contractName)
+	if err != nil {
+		return err
+	}
+
+	// Serialize the result the way contractapi returns it to the client. Functions returning
+	// only an error pass a nil pointer of an internal contractapi type.
+	var payload string
+	switch value := result.(type) {
+	case nil:
+	case string:
+		payload = value
+	default:
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr && v.IsNil() {
+			break
+		}
+		resultJSON, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal transaction result: %v", err)
+		}
+		payload = string(resultJSON)
+	}
+	resultHash := sha256.Sum256([]byte(payload))
+
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	record := IdempotencyRecord{
+		IdempotencyKey: key,
+		Function:       function,
+		RequestHash:    hash,
+		TxID:           ctx.GetStub().GetTxID(),
+		Timestamp:      timestamp,
+		ResultHash:     hex.EncodeToString(resultHash[:]),
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %v", err)
+	}
+
+	recordKey, err := idempotencyRecordKey(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(recordKey, recordJSON)
+}
+
+// idempotencyRecordKey returns the world state key of the record of an idempotency key of the
+// calling identity
+func idempotencyRecordKey(ctx contractapi.TransactionContextInterface, idempotencyKey string) (string, error) {
+	caller, err := callerHash(ctx)
+	if err != nil {
+		return "", err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(idempotencyObjectType, []string{caller, idempotencyKey})
+	if err != nil {
+		return "", fmt.Errorf("failed to create idempotency key: %v", err)
+	}
+
+	return key, nil
+}
+
+// readIdempotencyRecord returns the record stored for an idempotency key of the calling identity,
+// or nil if there is none
+func readIdempotencyRecord(ctx contractapi.TransactionContextInterface, idempotencyKey string) (*IdempotencyRecord, error) {
+	if err := validateID("idempotencyKey", idempotencyKey); err != nil {
+		return nil, err
+	}
+
+	recordKey, err := idempotencyRecordKey(ctx, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	recordJSON, err := ctx.GetStub().GetState(recordKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if recordJSON == nil {
+		return nil, nil
+	}
+
+	var record IdempotencyRecord
+	if err := json.Unmarshal(recordJSON, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %v", err)
+	}
+
+	return &record, nil
+}
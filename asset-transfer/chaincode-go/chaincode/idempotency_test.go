@@ -0,0 +1,77 @@
+package chaincode_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	ledger := newLedger()
+	cc, err := contractapi.NewChaincode(chaincode.Contracts()...)
+	require.NoError(t, err)
+	transient := map[string][]byte{"idempotencyKey": []byte("request-1")}
+
+	// The idempotency key is checked and recorded by the transaction hooks
+	ledger.BeginTx(registrar)
+	ledger.SetTransient(transient)
+	response := ledger.Invoke(cc, "credentials:CreateProfessionalCredential", "", "talent1", "Jane", "Doe", "Go", "5 years", "Acme")
+	require.Equal(t, int32(shim.OK), response.Status, response.Message)
+	credentialID := string(response.Payload)
+
+	ctx := ledger.BeginTx(registrar)
+	record, err := (&chaincode.AdminContract{}).GetIdempotencyRecord(ctx, "request-1")
+	require.NoError(t, err)
+	require.Equal(t, "credentials:CreateProfessionalCredential", record.Function)
+	require.Equal(t, "tx1", record.TxID)
+	resultHash := sha256.Sum256([]byte(credentialID))
+	require.Equal(t, hex.EncodeToString(resultHash[:]), record.ResultHash)
+
+	// A retry, which would otherwise create a second credential, is rejected
+	ledger.BeginTx(registrar)
+	ledger.SetTransient(transient)
+	response = ledger.Invoke(cc, "CreateProfessionalCredential", "", "talent1", "Jane", "Doe", "Go", "5 years", "Acme")
+	require.Equal(t, int32(shim.ERROR), response.Status)
+	require.Equal(t, "ALREADY_APPLIED: the request with idempotency key request-1 was already committed in transaction tx1", response.Message)
+
+	ledger.BeginTx(registrar)
+	ledger.SetTransient(transient)
+	response = ledger.Invoke(cc, "credentials:UpdateSkills", credentialID, "Go, Rust")
+	require.Equal(t, int32(shim.ERROR), response.Status)
+	require.Contains(t, response.Message, "IDEMPOTENCY_KEY_REUSED: ")
+
+	// Keys are scoped to the identity using them
+	ctx = ledger.BeginTx(talent1)
+	_, err = (&chaincode.AdminContract{}).GetIdempotencyRecord(ctx, "request-1")
+	requireErrorCode(t, err, chaincode.ErrNotFound)
+
+	ledger.BeginTx(talent1)
+	ledger.SetTransient(transient)
+	response = ledger.Invoke(cc, "talents:CreateTalentProfile", "talent9", "Jane", "Doe", "")
+	require.Equal(t, int32(shim.OK), response.Status, response.Message)
+
+	// Evaluations record nothing
+	ledger.BeginTx(registrar)
+	ledger.SetTransient(map[string][]byte{"idempotencyKey": []byte("request-3")})
+	response = ledger.Invoke(cc, "credentials:CredentialExists", credentialID)
+	require.Equal(t, int32(shim.OK), response.Status, response.Message)
+
+	ctx = ledger.BeginTx(registrar)
+	_, err = (&chaincode.AdminContract{}).GetIdempotencyRecord(ctx, "request-3")
+	requireErrorCode(t, err, chaincode.ErrNotFound)
+
+	// Without a key, the same request is applied again
+	ledger.BeginTx(registrar)
+	response = ledger.Invoke(cc, "credentials:CreateProfessionalCredential", "", "talent1", "Jane", "Doe", "Go", "5 years", "Acme")
+	require.Equal(t, int32(shim.OK), response.Status, response.Message)
+	require.NotEqual(t, credentialID, string(response.Payload))
+
+	ctx = ledger.BeginTx(registrar)
+	_, err = (&chaincode.AdminContract{}).GetIdempotencyRecord(ctx, "request-2")
+	requireErrorCode(t, err, chaincode.ErrNotFound)
+}
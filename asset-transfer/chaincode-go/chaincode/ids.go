@@ -0,0 +1,44 @@
+package chaincode
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// credentialIDNamespace is the UUID namespace of ledger-assigned credential IDs
+var credentialIDNamespace = [16]byte{
+	0x3b, 0x6f, 0x1e, 0x52, 0x8c, 0x0d, 0x4a, 0x7e,
+	0x9f, 0x21, 0x5d, 0x64, 0xc0, 0x8a, 0x37, 0xe9,
+}
+
+// newCredentialID derives a credential ID from the transaction ID, the MSP of the issuer and a
+// nonce distinguishing the IDs derived in the same transaction. Every endorser computes the
+// same ID, which is a name-based UUID (version 5) in URN form: urn:uuid:xxxxxxxx-xxxx-5xxx-...
+func newCredentialID(ctx contractapi.TransactionContextInterface, nonce int) (string, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not get MSPID: %v", err)
+	}
+
+	name := ctx.GetStub().GetTxID() + "/" + mspID + "/" + strconv.Itoa(nonce)
+	return "urn:uuid:" + uuidV5(credentialIDNamespace, name), nil
+}
+
+// uuidV5 returns the RFC 4122 name-based UUID of a name in a namespace, using SHA-1
+func uuidV5(namespace [16]byte, name string) string {
+	hash := sha1.New()
+	hash.Write(namespace[:])
+	hash.Write([]byte(name))
+	sum := hash.Sum(nil)
+
+	uuid := sum[:16]
+	uuid[6] = (uuid[6] & 0x0f) | 0x50 // Version 5
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	encoded := hex.EncodeToString(uuid)
+	return encoded[0:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:32]
+}
@@ -0,0 +1,213 @@
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// IssuerContract provides the functions reserved to credential issuers (Org1 institutions)
+type IssuerContract struct {
+	contractapi.Contract
+}
+
+// statusSuperseded marks a credential replaced by a reissued one. Unlike the other
+// verification statuses, it is only set by ReissueCredential.
+const statusSuperseded = "Superseded"
+
+// reissueCorrections are the fields a reissue may correct, by credential type
+var reissueCorrections = map[string][]string{
+	credentialTypeAcademic:     {"Skills", "Education", "Institution"},
+	credentialTypeProfessional: {"Skills", "WorkExperience", "Company"},
+}
+
+// requireIssuer checks that the caller belongs to Org1 (institutions)
+func requireIssuer(ctx contractapi.TransactionContextInterface, action string) error {
+	// Get the identity of the invoker (the user calling the smart contract)
+	callerMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("could not get MSPID: %s", err)
+	}
+
+	if callerMSPID != "Org1MSP" {
+		return newError(ErrForbidden, "only members of Org1 (institutions) can %s credentials", action)
+	}
+
+	return nil
+}
+
+// checkNotSuperseded rejects changes to a credential that was replaced by a reissued one
+func checkNotSuperseded(envelope *CredentialEnvelope) error {
+	if envelope.Data.SupersededBy != "" {
+		return newError(ErrConflict, "the credential %s was superseded by %s", envelope.Data.CredentialID, envelope.Data.SupersededBy)
+	}
+
+	return nil
+}
+
+// Updates the verification status of a talent credential
+func (c *IssuerContract) UpdateVerificationStatus(ctx contractapi.TransactionContextInterface, credentialID string, status string, verifiedBy string) error {
+	// Only allow org1 (institutions) to approve or revoke
+	if err := requireIssuer(ctx, "approve or revoke"); err != nil {
+		return err
+	}
+
+	if err := validateVerificationStatus(status); err != nil {
+		return err
+	}
+	if err := validateText("verifiedBy", verifiedBy, false); err != nil {
+		return err
+	}
+
+	talentCredential, err := readCredential(ctx, credentialID)
+	if err != nil {
+		return err
+	}
+	if err := checkNotSuperseded(talentCredential); err != nil {
+		return err
+	}
+
+	// Verifying an academic credential vouches for its institution, which must still be accredited
+	if status == "Verified" && talentCredential.Type == credentialTypeAcademic {
+		if err := checkAccreditation(ctx, talentCredential.Data.Institution); err != nil {
+			return err
+		}
+	}
+
+	before := talentCredential.Data
+	talentCredential.Data.VerificationStatus = status
+	talentCredential.Data.VerifiedBy = verifiedBy
+
+	if err := putCredential(ctx, talentCredential); err != nil {
+		return err
+	}
+	if err := updatePendingCount(ctx, &before, &talentCredential.Data); err != nil {
+		return err
+	}
+
+	return recordCredentialChange(ctx, &before, &talentCredential.Data)
+}
+
+// ReissueCredential replaces a credential with a corrected copy and returns the ID of the copy, which
+// is assigned by the ledger when reissuedID is empty. corrections maps the fields to change
+// (Skills, and Education/Institution or WorkExperience/Company depending on the type) to their new
+// values. The old credential is kept, marked Superseded and linked to the new one; getters called
+// with its ID return the new version.
+func (c *IssuerContract) ReissueCredential(ctx contractapi.TransactionContextInterface, credentialID string, reissuedID string, corrections map[string]string) (string, error) {
+	if err := requireIssuer(ctx, "reissue"); err != nil {
+		return "", err
+	}
+
+	oldCredential, err := readCredential(ctx, credentialID)
+	if err != nil {
+		return "", err
+	}
+	if err := checkNotSuperseded(oldCredential); err != nil {
+		return "", err
+	}
+	if oldCredential.Data.VerificationStatus == "Revoked" {
+		return "", newError(ErrConflict, "the credential %s was revoked and cannot be reissued", credentialID)
+	}
+	// Encrypted fields are bound to the ID of their credential and cannot be copied as they are
+	if err := requireDecrypted(&oldCredential.Data); err != nil {
+		return "", err
+	}
+
+	if reissuedID == "" {
+		reissuedID, err = newCredentialID(ctx, 0)
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := validateID("reissuedID", reissuedID); err != nil {
+		return "", err
+	}
+	exists, err := credentialExists(ctx, reissuedID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", newError(ErrAlreadyExists, "the credential %s already exists", reissuedID)
+	}
+
+	newData := oldCredential.Data
+	newData.CredentialID = reissuedID
+	newData.Supersedes = credentialID
+	newData.SupersededBy = ""
+	newData.CreatedAt, err = txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	newData.CreatedBy, err = callerHash(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := applyCorrections(&newData, corrections); err != nil {
+		return "", err
+	}
+	if newData.CredentialType == credentialTypeAcademic {
+		if err := checkAccreditation(ctx, newData.Institution); err != nil {
+			return "", err
+		}
+	}
+
+	// The new credential joins the talent profile, which keeps listing the superseded one
+	profile, err := readTalentProfile(ctx, newData.TalentID)
+	if err != nil {
+		return "", err
+	}
+	if profile != nil {
+		profile.CredentialIDs = append(profile.CredentialIDs, reissuedID)
+		profile.countPending(pendingChange(&oldCredential.Data, nil) + pendingChange(nil, &newData))
+		if err := putTalentProfile(ctx, profile); err != nil {
+			return "", err
+		}
+	}
+
+	before := oldCredential.Data
+	oldCredential.Data.VerificationStatus = statusSuperseded
+	oldCredential.Data.SupersededBy = reissuedID
+	if err := putCredential(ctx, oldCredential); err != nil {
+		return "", err
+	}
+	if err := putCredential(ctx, &CredentialEnvelope{Type: oldCredential.Type, Data: newData}); err != nil {
+		return "", err
+	}
+
+	change := statisticsChange{}
+	if err := change.add(ctx, &before, &oldCredential.Data); err != nil {
+		return "", err
+	}
+	if err := change.add(ctx, nil, &newData); err != nil {
+		return "", err
+	}
+	if err := change.write(ctx); err != nil {
+		return "", err
+	}
+
+	return reissuedID, nil
+}
+
+// applyCorrections sets the corrected fields of a reissued credential
+func applyCorrections(data *CredentialData, corrections map[string]string) error {
+	fields := map[string]*string{
+		"Skills":         &data.Skills,
+		"Education":      &data.Education,
+		"Institution":    &data.Institution,
+		"WorkExperience": &data.WorkExperience,
+		"Company":        &data.Company,
+	}
+
+	for field, value := range corrections {
+		if !containsString(reissueCorrections[data.CredentialType], field) {
+			return newError(ErrInvalidArgument, "field %s cannot be corrected on a %s credential", field, data.CredentialType)
+		}
+		// Skills may be cleared, the type-specific fields are required
+		if err := validateText(field, value, field != "Skills"); err != nil {
+			return err
+		}
+		*fields[field] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateVerificationStatus(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+
+	verify(t, ledger, "cred1", "Verified")
+	credential := getCredential(t, ledger, "cred1")
+	require.Equal(t, "Verified", credential.Data.VerificationStatus)
+	require.Equal(t, "Registrar", credential.Data.VerifiedBy)
+
+	issuers := chaincode.IssuerContract{}
+	err := ledger.Submit(recruiter, func(ctx contractapi.TransactionContextInterface) error {
+		return issuers.UpdateVerificationStatus(ctx, "cred1", "Revoked", "Acme")
+	})
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return issuers.UpdateVerificationStatus(ctx, "cred1", "Superseded", "Registrar")
+	})
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
+
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return issuers.UpdateVerificationStatus(ctx, "cred2", "Verified", "Registrar")
+	})
+	requireErrorCode(t, err, chaincode.ErrNotFound)
+}
+
+func TestReissueCredential(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+	verify(t, ledger, "cred1", "Verified")
+
+	issuers := chaincode.IssuerContract{}
+	var reissuedID string
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		reissuedID, err = issuers.ReissueCredential(ctx, "cred1", "cred1-v2", map[string]string{"Education": "BEng Software Engineering"})
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, "cred1-v2", reissuedID)
+
+	// Getters follow the superseded credential to its replacement
+	credential := getCredential(t, ledger, "cred1")
+	require.Equal(t, "cred1-v2", credential.Data.CredentialID)
+	require.Equal(t, "cred1", credential.ResolvedFrom)
+	require.Equal(t, "cred1", credential.Data.Supersedes)
+	require.Equal(t, "BEng Software Engineering", credential.Data.Education)
+	require.Equal(t, "Verified", credential.Data.VerificationStatus)
+	require.Equal(t, "2024-01-01T00:00:03Z", credential.Data.CreatedAt)
+
+	ctx := ledger.BeginTx(recruiter)
+	profile, err := (&chaincode.TalentContract{}).GetTalentProfile(ctx, "talent1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"cred1", "cred1-v2"}, profile.CredentialIDs)
+	require.Equal(t, "Superseded", profile.Credentials[0].Data.VerificationStatus)
+	require.Equal(t, "cred1-v2", profile.Credentials[0].Data.SupersededBy)
+
+	// A superseded credential cannot be changed or reissued again
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := issuers.ReissueCredential(ctx, "cred1", "", nil)
+		return err
+	})
+	requireErrorCode(t, err, chaincode.ErrConflict)
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.CredentialContract{}).UpdateSkills(ctx, "cred1", "Go")
+	})
+	requireErrorCode(t, err, chaincode.ErrConflict)
+}
+
+func TestReissueCredentialRejections(t *testing.T) {
+	ledger := newLedger()
+	createProfessional(t, ledger, "cred1", "talent1")
+	createProfessional(t, ledger, "cred2", "talent1")
+
+	issuers := chaincode.IssuerContract{}
+	for name, test := range map[string]struct {
+		reissuedID  string
+		corrections map[string]string
+		code        chaincode.ErrorCode
+	}{
+		"existing ID":         {reissuedID: "cred2", code: chaincode.ErrAlreadyExists},
+		"invalid ID":          {reissuedID: "cred 3", code: chaincode.ErrInvalidArgument},
+		"field of other type": {corrections: map[string]string{"Education": "BSc"}, code: chaincode.ErrInvalidArgument},
+		"cleared company":     {corrections: map[string]string{"Company": ""}, code: chaincode.ErrInvalidArgument},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+				_, err := issuers.ReissueCredential(ctx, "cred1", test.reissuedID, test.corrections)
+				return err
+			})
+			requireErrorCode(t, err, test.code)
+		})
+	}
+
+	err := ledger.Submit(recruiter, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := issuers.ReissueCredential(ctx, "cred1", "", nil)
+		return err
+	})
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+
+	verify(t, ledger, "cred1", "Revoked")
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := issuers.ReissueCredential(ctx, "cred1", "", nil)
+		return err
+	})
+	requireErrorCode(t, err, chaincode.ErrConflict)
+}
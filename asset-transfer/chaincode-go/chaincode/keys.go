@@ -0,0 +1,94 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Object types used as composite key namespaces in world state.
+// Every entity is stored under its own namespace so that range queries
+// over one type never see records of another.
+const (
+	credentialObjectType      = "credential"
+	talentProfileObjectType   = "talentprofile"
+	metadataObjectType        = "meta"
+	idempotencyObjectType     = "idempotency"
+	endorsementObjectType     = "endorsement"
+	statisticsObjectType      = "stats"
+	statisticsDeltaObjectType = "statdelta"
+	quotaObjectType           = "quota"
+)
+
+// keySchemaVersion is the current layout of world state keys. Version 1 stored credentials
+// under their bare ID, version 2 stores every entity under a typed composite key.
+const keySchemaVersion = "2"
+
+// callerHash identifies the calling identity in world state keys by the SHA-256 of its MSP ID
+// and client ID, which keeps the certificate subjects out of the keys
+func callerHash(ctx contractapi.TransactionContextInterface) (string, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not get MSPID: %v", err)
+	}
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("could not get client ID: %v", err)
+	}
+	hash := sha256.Sum256([]byte(mspID + "/" + clientID))
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// credentialKey returns the world state key of a credential
+func credentialKey(ctx contractapi.TransactionContextInterface, credentialID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(credentialObjectType, []string{credentialID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create credential key: %v", err)
+	}
+
+	return key, nil
+}
+
+// getCredentialState reads the raw JSON of a credential from world state
+func getCredentialState(ctx contractapi.TransactionContextInterface, credentialID string) ([]byte, error) {
+	key, err := credentialKey(ctx, credentialID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx.GetStub().GetState(key)
+}
+
+// putCredentialState writes the raw JSON of a credential to world state
+func putCredentialState(ctx contractapi.TransactionContextInterface, credentialID string, credentialJSON []byte) error {
+	key, err := credentialKey(ctx, credentialID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(key, credentialJSON)
+}
+
+// delCredentialState removes a credential from world state
+func delCredentialState(ctx contractapi.TransactionContextInterface, credentialID string) error {
+	key, err := credentialKey(ctx, credentialID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().DelState(key)
+}
+
+// containsString reports whether a slice contains the given string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
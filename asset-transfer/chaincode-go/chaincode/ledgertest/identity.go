@@ -0,0 +1,141 @@
+package ledgertest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/attrmgr"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"google.golang.org/protobuf/proto"
+)
+
+// Identity is the X.509 identity of a transaction submitter
+type Identity struct {
+	MSPID          string
+	Certificate    *x509.Certificate
+	CertificatePEM []byte
+}
+
+// testCA signs the certificates of one MSP
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+var (
+	testCAsMutex sync.Mutex
+	testCAs      = map[string]*testCA{}
+	serialNumber int64
+)
+
+// NewIdentity creates an identity of an MSP with a test certificate, issued by a test CA of the
+// MSP, carrying the given attributes the way the Fabric CA does
+func NewIdentity(mspID string, commonName string, attributes map[string]string) (*Identity, error) {
+	ca, err := mspCA(mspID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: nextSerialNumber(),
+		Subject:      pkix.Name{CommonName: commonName, OrganizationalUnit: []string{"client"}},
+		NotBefore:    time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if len(attributes) > 0 {
+		attributesJSON, err := json.Marshal(attrmgr.Attributes{Attrs: attributes})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal attributes: %w", err)
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{Id: attrmgr.AttrOID, Value: attributesJSON})
+	}
+
+	certificateDER, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	return IdentityFromPEM(mspID, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificateDER}))
+}
+
+// IdentityFromPEM creates an identity of an MSP from a PEM-encoded certificate
+func IdentityFromPEM(mspID string, certificatePEM []byte) (*Identity, error) {
+	block, _ := pem.Decode(certificatePEM)
+	if block == nil {
+		return nil, errors.New("no PEM certificate found")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	return &Identity{MSPID: mspID, Certificate: certificate, CertificatePEM: certificatePEM}, nil
+}
+
+// Serialize returns the serialized identity found in transaction proposals
+func (id *Identity) Serialize() ([]byte, error) {
+	return proto.Marshal(&msp.SerializedIdentity{Mspid: id.MSPID, IdBytes: id.CertificatePEM})
+}
+
+// mspCA returns the test CA of an MSP, creating it on first use
+func mspCA(mspID string) (*testCA, error) {
+	testCAsMutex.Lock()
+	defer testCAsMutex.Unlock()
+
+	if ca, ok := testCAs[mspID]; ok {
+		return ca, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca." + strings.ToLower(strings.TrimSuffix(mspID, "MSP")) + ".example.com"},
+		NotBefore:             time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certificateDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	certificate, err := x509.ParseCertificate(certificateDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+
+	ca := &testCA{certificate: certificate, key: key}
+	testCAs[mspID] = ca
+
+	return ca, nil
+}
+
+// nextSerialNumber returns a unique certificate serial number
+func nextSerialNumber() *big.Int {
+	testCAsMutex.Lock()
+	defer testCAsMutex.Unlock()
+
+	serialNumber++
+	return big.NewInt(serialNumber + 1)
+}
@@ -0,0 +1,75 @@
+package ledgertest
+
+import (
+	"errors"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+)
+
+// errIteratorExhausted is returned by Next when an iterator has no more results
+var errIteratorExhausted = errors.New("no more results")
+
+// errIteratorClosed is returned by Next after Close
+var errIteratorClosed = errors.New("iterator is closed")
+
+// stateIterator iterates over the results of a range or composite-key query
+type stateIterator struct {
+	results []*queryresult.KV
+	index   int
+	closed  bool
+}
+
+// HasNext returns true while the iterator has results left
+func (it *stateIterator) HasNext() bool {
+	return !it.closed && it.index < len(it.results)
+}
+
+// Next returns the next result
+func (it *stateIterator) Next() (*queryresult.KV, error) {
+	if it.closed {
+		return nil, errIteratorClosed
+	}
+	if it.index >= len(it.results) {
+		return nil, errIteratorExhausted
+	}
+
+	it.index++
+	return it.results[it.index-1], nil
+}
+
+// Close releases the iterator
+func (it *stateIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+// historyIterator iterates over the modifications of a key
+type historyIterator struct {
+	results []*queryresult.KeyModification
+	index   int
+	closed  bool
+}
+
+// HasNext returns true while the iterator has results left
+func (it *historyIterator) HasNext() bool {
+	return !it.closed && it.index < len(it.results)
+}
+
+// Next returns the next result
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if it.closed {
+		return nil, errIteratorClosed
+	}
+	if it.index >= len(it.results) {
+		return nil, errIteratorExhausted
+	}
+
+	it.index++
+	return it.results[it.index-1], nil
+}
+
+// Close releases the iterator
+func (it *historyIterator) Close() error {
+	it.closed = true
+	return nil
+}
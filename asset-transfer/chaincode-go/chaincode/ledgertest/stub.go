@@ -0,0 +1,625 @@
+// Package ledgertest provides an in-memory implementation of shim.ChaincodeStubInterface, so that
+// contracts can be tested end-to-end against a world state that behaves like the peer's.
+//
+// Like on a peer, writes are only visible once their transaction commits: reads within a
+// transaction return the committed state, and range and composite-key queries skip pending
+// writes. A transaction starts with BeginTx, which gives it a new ID, a timestamp one second
+// after the previous one and the identity of its submitter, and ends with Commit, or with the
+// next BeginTx, which discards its writes. Submit and Evaluate wrap both.
+package ledgertest
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// compositeKeyNamespace is the first character of every composite key
+const compositeKeyNamespace = "\x00"
+
+// emptyKeySubstitute replaces an empty start key in range queries, which the peer does to keep
+// composite keys out of them
+const emptyKeySubstitute = "\x01"
+
+// errRichQuery is returned by the rich query functions, which need CouchDB
+var errRichQuery = errors.New("rich queries require CouchDB and are not supported by ledgertest")
+
+// Event is a chaincode event emitted by a committed transaction
+type Event struct {
+	TxID    string
+	Name    string
+	Payload []byte
+}
+
+// stateKey locates a value in the public world state (empty collection) or a private data collection
+type stateKey struct {
+	collection string
+	key        string
+}
+
+// stateWrite is a pending write of a transaction
+type stateWrite struct {
+	value   []byte
+	deleted bool
+}
+
+// invokedChaincode is a chaincode reachable through InvokeChaincode, with its own world state
+type invokedChaincode struct {
+	chaincode shim.Chaincode
+	stub      *Stub
+}
+
+var _ shim.ChaincodeStubInterface = (*Stub)(nil)
+
+// Stub is an in-memory ChaincodeStubInterface. It is not safe for concurrent use.
+type Stub struct {
+	channelID  string
+	state      map[stateKey][]byte
+	validation map[stateKey][]byte
+	history    map[string][]*queryresult.KeyModification // Oldest first
+	events     []Event
+	chaincodes map[string]*invokedChaincode
+	clock      time.Time
+	txCount    int
+
+	// The current transaction
+	txID      string
+	txTime    time.Time
+	creator   []byte
+	transient map[string][]byte
+	args      [][]byte
+	writes    map[stateKey]stateWrite
+	event     *Event
+}
+
+// NewStub creates an empty ledger for a channel. Its first transaction is timestamped
+// 2024-01-01T00:00:01Z.
+func NewStub(channelID string) *Stub {
+	return &Stub{
+		channelID:  channelID,
+		state:      make(map[stateKey][]byte),
+		validation: make(map[stateKey][]byte),
+		history:    make(map[string][]*queryresult.KeyModification),
+		chaincodes: make(map[string]*invokedChaincode),
+		clock:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		writes:     make(map[stateKey]stateWrite),
+	}
+}
+
+// BeginTx starts a transaction submitted by identity and returns its context. The writes of a
+// transaction that was not committed are discarded. It panics if the identity is invalid.
+func (s *Stub) BeginTx(identity *Identity) *contractapi.TransactionContext {
+	s.txCount++
+	s.clock = s.clock.Add(time.Second)
+	s.txID = fmt.Sprintf("tx%d", s.txCount)
+	s.txTime = s.clock
+	s.transient = make(map[string][]byte)
+	s.args = nil
+	s.writes = make(map[stateKey]stateWrite)
+	s.event = nil
+
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(s)
+	if identity == nil {
+		s.creator = nil
+		return ctx
+	}
+
+	creator, err := identity.Serialize()
+	if err != nil {
+		panic(fmt.Sprintf("ledgertest: %v", err))
+	}
+	s.creator = creator
+	clientIdentity, err := cid.New(s)
+	if err != nil {
+		panic(fmt.Sprintf("ledgertest: %v", err))
+	}
+	ctx.SetClientIdentity(clientIdentity)
+
+	return ctx
+}
+
+// Commit applies the writes and the event of the current transaction
+func (s *Stub) Commit() {
+	keys := make([]stateKey, 0, len(s.writes))
+	for key := range s.writes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].collection < keys[j].collection || keys[i].collection == keys[j].collection && keys[i].key < keys[j].key
+	})
+
+	for _, key := range keys {
+		write := s.writes[key]
+		if write.deleted {
+			delete(s.state, key)
+		} else {
+			s.state[key] = write.value
+		}
+		if key.collection == "" {
+			s.history[key.key] = append(s.history[key.key], &queryresult.KeyModification{
+				TxId:      s.txID,
+				Value:     write.value,
+				Timestamp: timestamppb.New(s.txTime),
+				IsDelete:  write.deleted,
+			})
+		}
+	}
+	if s.event != nil {
+		s.events = append(s.events, *s.event)
+	}
+
+	s.writes = make(map[stateKey]stateWrite)
+	s.event = nil
+}
+
+// Submit runs fn in a new transaction of identity and commits it when fn succeeds
+func (s *Stub) Submit(identity *Identity, fn func(ctx contractapi.TransactionContextInterface) error) error {
+	ctx := s.BeginTx(identity)
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	s.Commit()
+
+	return nil
+}
+
+// Evaluate runs fn in a new transaction of identity and discards its writes, like a query
+func (s *Stub) Evaluate(identity *Identity, fn func(ctx contractapi.TransactionContextInterface) error) error {
+	err := fn(s.BeginTx(identity))
+	s.writes = make(map[stateKey]stateWrite)
+	s.event = nil
+
+	return err
+}
+
+// Invoke calls a chaincode in the current transaction, as the peer does with a proposal, and
+// commits the transaction when the chaincode succeeds
+func (s *Stub) Invoke(chaincode shim.Chaincode, function string, args ...string) *peer.Response {
+	s.args = [][]byte{[]byte(function)}
+	for _, arg := range args {
+		s.args = append(s.args, []byte(arg))
+	}
+
+	response := chaincode.Invoke(s)
+	if response.Status < shim.ERRORTHRESHOLD {
+		s.Commit()
+	}
+
+	return response
+}
+
+// SetTransient sets the transient data of the current transaction
+func (s *Stub) SetTransient(transient map[string][]byte) {
+	s.transient = transient
+}
+
+// Advance moves the clock forward, delaying the timestamp of the next transaction
+func (s *Stub) Advance(d time.Duration) {
+	s.clock = s.clock.Add(d)
+}
+
+// Events returns the events of the committed transactions, oldest first
+func (s *Stub) Events() []Event {
+	return s.events
+}
+
+// RegisterChaincode makes a chaincode reachable through InvokeChaincode on a channel. It runs
+// against a world state of its own; its writes are committed when it succeeds on the channel of
+// the stub, and discarded when called on another channel, where it is read-only.
+func (s *Stub) RegisterChaincode(name string, channelID string, chaincode shim.Chaincode) *Stub {
+	stub := NewStub(channelID)
+	s.chaincodes[name+"/"+channelID] = &invokedChaincode{chaincode: chaincode, stub: stub}
+
+	return stub
+}
+
+// GetArgs returns the arguments set by Invoke
+func (s *Stub) GetArgs() [][]byte {
+	return s.args
+}
+
+// GetStringArgs returns the arguments set by Invoke as strings
+func (s *Stub) GetStringArgs() []string {
+	args := make([]string, 0, len(s.args))
+	for _, arg := range s.args {
+		args = append(args, string(arg))
+	}
+
+	return args
+}
+
+// GetFunctionAndParameters returns the function and parameters set by Invoke
+func (s *Stub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+
+	return args[0], args[1:]
+}
+
+// GetArgsSlice returns the concatenated arguments set by Invoke
+func (s *Stub) GetArgsSlice() ([]byte, error) {
+	slice := []byte{}
+	for _, arg := range s.args {
+		slice = append(slice, arg...)
+	}
+
+	return slice, nil
+}
+
+// GetTxID returns the ID of the current transaction
+func (s *Stub) GetTxID() string {
+	return s.txID
+}
+
+// GetChannelID returns the channel of the stub
+func (s *Stub) GetChannelID() string {
+	return s.channelID
+}
+
+// InvokeChaincode calls a chaincode registered with RegisterChaincode. An empty channel is the
+// channel of the stub.
+func (s *Stub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) *peer.Response {
+	if channel == "" {
+		channel = s.channelID
+	}
+	invoked, ok := s.chaincodes[chaincodeName+"/"+channel]
+	if !ok {
+		return &peer.Response{Status: shim.ERROR, Message: fmt.Sprintf("chaincode %s not found on channel %s", chaincodeName, channel)}
+	}
+
+	stub := invoked.stub
+	stub.txID = s.txID
+	stub.txTime = s.txTime
+	stub.creator = s.creator
+	stub.transient = s.transient
+	stub.args = args
+	stub.writes = make(map[stateKey]stateWrite)
+	stub.event = nil
+
+	response := invoked.chaincode.Invoke(stub)
+	if response.Status < shim.ERRORTHRESHOLD && channel == s.channelID {
+		stub.Commit()
+	}
+
+	return response
+}
+
+// GetState returns the committed value of a key, or nil if it does not exist
+func (s *Stub) GetState(key string) ([]byte, error) {
+	return s.getState("", key)
+}
+
+// PutState writes a key when the transaction commits
+func (s *Stub) PutState(key string, value []byte) error {
+	return s.putState("", key, value)
+}
+
+// DelState deletes a key when the transaction commits
+func (s *Stub) DelState(key string) error {
+	return s.delState("", key)
+}
+
+// SetStateValidationParameter sets the key-level endorsement policy of a key
+func (s *Stub) SetStateValidationParameter(key string, ep []byte) error {
+	s.validation[stateKey{key: key}] = ep
+	return nil
+}
+
+// GetStateValidationParameter returns the key-level endorsement policy of a key
+func (s *Stub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.validation[stateKey{key: key}], nil
+}
+
+// GetStateByRange returns the committed simple keys in [startKey, endKey). Empty keys leave the
+// range open; composite keys are never included.
+func (s *Stub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+
+	results, _ := s.rangeQuery("", rangeStart(startKey), endKey, 0, "")
+	return &stateIterator{results: results}, nil
+}
+
+// GetStateByRangeWithPagination returns a page of GetStateByRange. The bookmark of the response
+// is the key starting the next page, empty after the last page.
+func (s *Stub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, nil, err
+	}
+
+	results, metadata := s.rangeQuery("", rangeStart(startKey), endKey, pageSize, bookmark)
+	return &stateIterator{results: results}, metadata, nil
+}
+
+// GetStateByPartialCompositeKey returns the committed composite keys starting with the object
+// type and attributes
+func (s *Stub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	startKey, endKey, err := partialCompositeKeyRange(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	results, _ := s.rangeQuery("", startKey, endKey, 0, "")
+	return &stateIterator{results: results}, nil
+}
+
+// GetStateByPartialCompositeKeyWithPagination returns a page of GetStateByPartialCompositeKey
+func (s *Stub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	startKey, endKey, err := partialCompositeKeyRange(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, metadata := s.rangeQuery("", startKey, endKey, pageSize, bookmark)
+	return &stateIterator{results: results}, metadata, nil
+}
+
+// CreateCompositeKey combines an object type and attributes into a composite key
+func (s *Stub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+// SplitCompositeKey splits a composite key into its object type and attributes
+func (s *Stub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, compositeKeyNamespace) {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+
+	components := strings.Split(strings.TrimSuffix(compositeKey[1:], "\x00"), "\x00")
+	return components[0], components[1:], nil
+}
+
+// GetQueryResult is not supported: rich queries need CouchDB
+func (s *Stub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errRichQuery
+}
+
+// GetQueryResultWithPagination is not supported: rich queries need CouchDB
+func (s *Stub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	return nil, nil, errRichQuery
+}
+
+// GetHistoryForKey returns the committed modifications of a key, most recent first like the peer
+func (s *Stub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	modifications := s.history[key]
+	results := make([]*queryresult.KeyModification, 0, len(modifications))
+	for i := len(modifications) - 1; i >= 0; i-- {
+		results = append(results, modifications[i])
+	}
+
+	return &historyIterator{results: results}, nil
+}
+
+// GetPrivateData returns the committed value of a key in a private data collection
+func (s *Stub) GetPrivateData(collection, key string) ([]byte, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	return s.getState(collection, key)
+}
+
+// GetPrivateDataHash returns the SHA-256 of the committed value of a private key
+func (s *Stub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	value, err := s.GetPrivateData(collection, key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+// PutPrivateData writes a private key when the transaction commits
+func (s *Stub) PutPrivateData(collection string, key string, value []byte) error {
+	if collection == "" {
+		return errors.New("collection must not be an empty string")
+	}
+	return s.putState(collection, key, value)
+}
+
+// DelPrivateData deletes a private key when the transaction commits
+func (s *Stub) DelPrivateData(collection, key string) error {
+	if collection == "" {
+		return errors.New("collection must not be an empty string")
+	}
+	return s.delState(collection, key)
+}
+
+// PurgePrivateData deletes a private key when the transaction commits. The fake keeps no
+// private data history, so purging is the same as deleting.
+func (s *Stub) PurgePrivateData(collection, key string) error {
+	return s.DelPrivateData(collection, key)
+}
+
+// SetPrivateDataValidationParameter sets the key-level endorsement policy of a private key
+func (s *Stub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	s.validation[stateKey{collection: collection, key: key}] = ep
+	return nil
+}
+
+// GetPrivateDataValidationParameter returns the key-level endorsement policy of a private key
+func (s *Stub) GetPrivateDataValidationParameter(collection, key string) ([]byte, error) {
+	return s.validation[stateKey{collection: collection, key: key}], nil
+}
+
+// GetPrivateDataByRange returns the committed simple keys of a collection in [startKey, endKey)
+func (s *Stub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+
+	results, _ := s.rangeQuery(collection, rangeStart(startKey), endKey, 0, "")
+	return &stateIterator{results: results}, nil
+}
+
+// GetPrivateDataByPartialCompositeKey returns the committed composite keys of a collection
+// starting with the object type and attributes
+func (s *Stub) GetPrivateDataByPartialCompositeKey(collection, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	startKey, endKey, err := partialCompositeKeyRange(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	results, _ := s.rangeQuery(collection, startKey, endKey, 0, "")
+	return &stateIterator{results: results}, nil
+}
+
+// GetPrivateDataQueryResult is not supported: rich queries need CouchDB
+func (s *Stub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errRichQuery
+}
+
+// GetCreator returns the serialized identity passed to BeginTx
+func (s *Stub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+// GetTransient returns the transient data set with SetTransient
+func (s *Stub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+// GetBinding is not supported: the fake has no signed proposal
+func (s *Stub) GetBinding() ([]byte, error) {
+	return nil, errors.New("proposal bindings are not supported by ledgertest")
+}
+
+// GetDecorations returns no decorations
+func (s *Stub) GetDecorations() map[string][]byte {
+	return map[string][]byte{}
+}
+
+// GetSignedProposal is not supported: the fake has no signed proposal
+func (s *Stub) GetSignedProposal() (*peer.SignedProposal, error) {
+	return nil, errors.New("signed proposals are not supported by ledgertest")
+}
+
+// GetTxTimestamp returns the timestamp of the current transaction
+func (s *Stub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.txTime), nil
+}
+
+// SetEvent sets the event of the current transaction, replacing any earlier one as on a peer
+func (s *Stub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	s.event = &Event{TxID: s.txID, Name: name, Payload: payload}
+
+	return nil
+}
+
+// getState returns the committed value of a key
+func (s *Stub) getState(collection, key string) ([]byte, error) {
+	if key == "" {
+		return nil, errors.New("key must not be an empty string")
+	}
+	return s.state[stateKey{collection: collection, key: key}], nil
+}
+
+// putState records the write of a key
+func (s *Stub) putState(collection, key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if !utf8.ValidString(key) {
+		return fmt.Errorf("key %q is not valid UTF-8", key)
+	}
+	s.writes[stateKey{collection: collection, key: key}] = stateWrite{value: append([]byte{}, value...)}
+
+	return nil
+}
+
+// delState records the deletion of a key
+func (s *Stub) delState(collection, key string) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	s.writes[stateKey{collection: collection, key: key}] = stateWrite{deleted: true}
+
+	return nil
+}
+
+// rangeQuery returns the committed keys of a collection in [startKey, endKey), an empty endKey
+// leaving the range open. A positive pageSize returns one page, starting at the bookmark.
+func (s *Stub) rangeQuery(collection, startKey, endKey string, pageSize int32, bookmark string) ([]*queryresult.KV, *peer.QueryResponseMetadata) {
+	if bookmark > startKey {
+		startKey = bookmark
+	}
+
+	keys := []string{}
+	for key := range s.state {
+		if key.collection == collection && key.key >= startKey && (endKey == "" || key.key < endKey) {
+			keys = append(keys, key.key)
+		}
+	}
+	sort.Strings(keys)
+
+	metadata := &peer.QueryResponseMetadata{}
+	if pageSize > 0 && len(keys) > int(pageSize) {
+		metadata.Bookmark = keys[pageSize]
+		keys = keys[:pageSize]
+	}
+	metadata.FetchedRecordsCount = int32(len(keys))
+
+	results := make([]*queryresult.KV, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, &queryresult.KV{
+			Namespace: collection,
+			Key:       key,
+			Value:     s.state[stateKey{collection: collection, key: key}],
+		})
+	}
+
+	return results, metadata
+}
+
+// rangeStart substitutes an empty start key, keeping composite keys out of range queries
+func rangeStart(startKey string) string {
+	if startKey == "" {
+		return emptyKeySubstitute
+	}
+	return startKey
+}
+
+// partialCompositeKeyRange returns the range of keys starting with a partial composite key
+func partialCompositeKeyRange(objectType string, attributes []string) (string, string, error) {
+	partialKey, err := shim.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return "", "", err
+	}
+
+	return partialKey, partialKey + string(utf8.MaxRune), nil
+}
+
+// validateSimpleKeys rejects composite keys as bounds of a range query, like the shim
+func validateSimpleKeys(keys ...string) error {
+	for _, key := range keys {
+		if strings.HasPrefix(key, compositeKeyNamespace) {
+			return fmt.Errorf("first character of the key [%s] contains a null character which is not allowed", key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,278 @@
+package ledgertest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgertest"
+	"github.com/stretchr/testify/require"
+)
+
+func newIdentity(t *testing.T, mspID string, attributes map[string]string) *ledgertest.Identity {
+	identity, err := ledgertest.NewIdentity(mspID, "User1@example.com", attributes)
+	require.NoError(t, err)
+	return identity
+}
+
+func keys(t *testing.T, iterator shim.StateQueryIteratorInterface) []string {
+	defer iterator.Close()
+
+	keys := []string{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		require.NoError(t, err)
+		keys = append(keys, kv.Key)
+	}
+	return keys
+}
+
+func TestWritesAreVisibleAfterCommit(t *testing.T) {
+	stub := ledgertest.NewStub("mychannel")
+
+	stub.BeginTx(nil)
+	require.NoError(t, stub.PutState("a", []byte("1")))
+	value, err := stub.GetState("a")
+	require.NoError(t, err)
+	require.Nil(t, value, "writes must not be visible in their own transaction")
+	stub.Commit()
+
+	value, err = stub.GetState("a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), value)
+
+	// A transaction that is not committed is discarded by the next one
+	stub.BeginTx(nil)
+	require.NoError(t, stub.DelState("a"))
+	stub.BeginTx(nil)
+	value, err = stub.GetState("a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), value)
+
+	require.Error(t, stub.PutState("", []byte("1")))
+}
+
+func TestSubmitCommitsOnlyOnSuccess(t *testing.T) {
+	stub := ledgertest.NewStub("mychannel")
+
+	err := stub.Submit(nil, func(ctx contractapi.TransactionContextInterface) error {
+		return ctx.GetStub().PutState("a", []byte("1"))
+	})
+	require.NoError(t, err)
+
+	err = stub.Submit(nil, func(ctx contractapi.TransactionContextInterface) error {
+		require.NoError(t, ctx.GetStub().PutState("b", []byte("2")))
+		return fmt.Errorf("endorsement failed")
+	})
+	require.EqualError(t, err, "endorsement failed")
+
+	err = stub.Evaluate(nil, func(ctx contractapi.TransactionContextInterface) error {
+		return ctx.GetStub().PutState("c", []byte("3"))
+	})
+	require.NoError(t, err)
+
+	stub.BeginTx(nil)
+	iterator, err := stub.GetStateByRange("", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, keys(t, iterator))
+}
+
+func TestRangeQueries(t *testing.T) {
+	stub := ledgertest.NewStub("mychannel")
+
+	stub.BeginTx(nil)
+	for _, key := range []string{"k1", "k2", "k3", "k4", "k5"} {
+		require.NoError(t, stub.PutState(key, []byte(key)))
+	}
+	compositeKey, err := stub.CreateCompositeKey("credential", []string{"c1"})
+	require.NoError(t, err)
+	require.NoError(t, stub.PutState(compositeKey, []byte("c1")))
+	stub.Commit()
+
+	iterator, err := stub.GetStateByRange("", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"k1", "k2", "k3", "k4", "k5"}, keys(t, iterator), "composite keys are not part of range queries")
+
+	iterator, err = stub.GetStateByRange("k2", "k4")
+	require.NoError(t, err)
+	require.Equal(t, []string{"k2", "k3"}, keys(t, iterator))
+
+	_, err = stub.GetStateByRange(compositeKey, "")
+	require.Error(t, err)
+
+	iterator, metadata, err := stub.GetStateByRangeWithPagination("", "", 2, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"k1", "k2"}, keys(t, iterator))
+	require.Equal(t, int32(2), metadata.FetchedRecordsCount)
+	require.Equal(t, "k3", metadata.Bookmark)
+
+	iterator, metadata, err = stub.GetStateByRangeWithPagination("", "", 2, "k5")
+	require.NoError(t, err)
+	require.Equal(t, []string{"k5"}, keys(t, iterator))
+	require.Equal(t, "", metadata.Bookmark)
+}
+
+func TestCompositeKeyQueries(t *testing.T) {
+	stub := ledgertest.NewStub("mychannel")
+
+	stub.BeginTx(nil)
+	for _, attributes := range [][]string{{"c1", "go"}, {"c1", "rust"}, {"c2", "go"}, {"c10", "go"}} {
+		key, err := stub.CreateCompositeKey("endorsement", attributes)
+		require.NoError(t, err)
+		require.NoError(t, stub.PutState(key, []byte("{}")))
+	}
+	other, err := stub.CreateCompositeKey("credential", []string{"c1"})
+	require.NoError(t, err)
+	require.NoError(t, stub.PutState(other, []byte("{}")))
+	stub.Commit()
+
+	iterator, err := stub.GetStateByPartialCompositeKey("endorsement", []string{"c1"})
+	require.NoError(t, err)
+	found := keys(t, iterator)
+	require.Len(t, found, 2, "c10 must not match the partial key c1")
+
+	objectType, attributes, err := stub.SplitCompositeKey(found[1])
+	require.NoError(t, err)
+	require.Equal(t, "endorsement", objectType)
+	require.Equal(t, []string{"c1", "rust"}, attributes)
+
+	iterator, metadata, err := stub.GetStateByPartialCompositeKeyWithPagination("endorsement", []string{}, 3, "")
+	require.NoError(t, err)
+	require.Len(t, keys(t, iterator), 3)
+	require.NotEmpty(t, metadata.Bookmark)
+
+	iterator, metadata, err = stub.GetStateByPartialCompositeKeyWithPagination("endorsement", []string{}, 3, metadata.Bookmark)
+	require.NoError(t, err)
+	require.Len(t, keys(t, iterator), 1)
+	require.Empty(t, metadata.Bookmark)
+}
+
+func TestHistoryForKey(t *testing.T) {
+	stub := ledgertest.NewStub("mychannel")
+
+	stub.BeginTx(nil)
+	require.NoError(t, stub.PutState("a", []byte("1")))
+	stub.Commit()
+	stub.Advance(time.Hour)
+	stub.BeginTx(nil)
+	require.NoError(t, stub.PutState("a", []byte("2")))
+	stub.Commit()
+	stub.BeginTx(nil)
+	require.NoError(t, stub.DelState("a"))
+	stub.Commit()
+
+	iterator, err := stub.GetHistoryForKey("a")
+	require.NoError(t, err)
+	defer iterator.Close()
+
+	modifications := []string{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		require.NoError(t, err)
+		modifications = append(modifications, fmt.Sprintf("%s %s %s %t", modification.TxId, modification.Timestamp.AsTime().Format(time.RFC3339), modification.Value, modification.IsDelete))
+	}
+	require.Equal(t, []string{
+		"tx3 2024-01-01T01:00:03Z  true",
+		"tx2 2024-01-01T01:00:02Z 2 false",
+		"tx1 2024-01-01T00:00:01Z 1 false",
+	}, modifications)
+}
+
+func TestTransientDataAndEvents(t *testing.T) {
+	stub := ledgertest.NewStub("mychannel")
+
+	stub.BeginTx(nil)
+	stub.SetTransient(map[string][]byte{"key": []byte("secret")})
+	transient, err := stub.GetTransient()
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret"), transient["key"])
+	require.NoError(t, stub.SetEvent("First", []byte("1")))
+	require.NoError(t, stub.SetEvent("Second", []byte("2")))
+	require.Empty(t, stub.Events(), "events are emitted on commit")
+	stub.Commit()
+
+	require.Equal(t, []ledgertest.Event{{TxID: "tx1", Name: "Second", Payload: []byte("2")}}, stub.Events())
+
+	stub.BeginTx(nil)
+	transient, err = stub.GetTransient()
+	require.NoError(t, err)
+	require.Empty(t, transient, "transient data belongs to a single transaction")
+}
+
+func TestClientIdentity(t *testing.T) {
+	stub := ledgertest.NewStub("mychannel")
+	identity := newIdentity(t, "Org2MSP", map[string]string{"role": "talent", "talentID": "talent1"})
+
+	ctx := stub.BeginTx(identity)
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	require.NoError(t, err)
+	require.Equal(t, "Org2MSP", mspID)
+
+	id, err := ctx.GetClientIdentity().GetID()
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	talentID, found, err := ctx.GetClientIdentity().GetAttributeValue("talentID")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "talent1", talentID)
+	require.NoError(t, ctx.GetClientIdentity().AssertAttributeValue("role", "talent"))
+
+	timestamp, err := stub.GetTxTimestamp()
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-01T00:00:01Z", timestamp.AsTime().Format(time.RFC3339))
+}
+
+// echoChaincode returns its arguments and counts its calls in its own world state
+type echoChaincode struct{}
+
+func (echoChaincode) Init(stub shim.ChaincodeStubInterface) *peer.Response { return shim.Success(nil) }
+
+func (echoChaincode) Invoke(stub shim.ChaincodeStubInterface) *peer.Response {
+	calls, _ := stub.GetState("calls")
+	if err := stub.PutState("calls", append(calls, '+')); err != nil {
+		return shim.Error(err.Error())
+	}
+	function, params := stub.GetFunctionAndParameters()
+	return shim.Success([]byte(fmt.Sprintf("%s%v", function, params)))
+}
+
+func TestInvokeChaincode(t *testing.T) {
+	stub := ledgertest.NewStub("mychannel")
+	local := stub.RegisterChaincode("echo", "mychannel", echoChaincode{})
+	remote := stub.RegisterChaincode("echo", "otherchannel", echoChaincode{})
+
+	stub.BeginTx(nil)
+	response := stub.InvokeChaincode("echo", [][]byte{[]byte("Ping"), []byte("a")}, "")
+	require.Equal(t, int32(shim.OK), response.Status)
+	require.Equal(t, "Ping[a]", string(response.Payload))
+
+	response = stub.InvokeChaincode("echo", [][]byte{[]byte("Ping")}, "otherchannel")
+	require.Equal(t, int32(shim.OK), response.Status)
+
+	response = stub.InvokeChaincode("missing", [][]byte{[]byte("Ping")}, "")
+	require.Equal(t, int32(shim.ERROR), response.Status)
+
+	calls, err := local.GetState("calls")
+	require.NoError(t, err)
+	require.Equal(t, "+", string(calls))
+	calls, err = remote.GetState("calls")
+	require.NoError(t, err)
+	require.Nil(t, calls, "chaincodes called on another channel are read-only")
+}
+
+func TestInvoke(t *testing.T) {
+	stub := ledgertest.NewStub("mychannel")
+
+	stub.BeginTx(nil)
+	response := stub.Invoke(echoChaincode{}, "Ping", "a", "b")
+	require.Equal(t, int32(shim.OK), response.Status)
+	require.Equal(t, "Ping[a b]", string(response.Payload))
+
+	calls, err := stub.GetState("calls")
+	require.NoError(t, err)
+	require.Equal(t, "+", string(calls))
+}
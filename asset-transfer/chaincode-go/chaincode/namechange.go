@@ -0,0 +1,150 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// statusPendingReview marks a verified credential whose name changed and that awaits the
+// re-approval of its issuer. Like Superseded, it is only set by the chaincode itself.
+const statusPendingReview = "PendingReview"
+
+// nameChangeReviewEvent is the chaincode event asking issuers to re-approve renamed credentials
+const nameChangeReviewEvent = "NameChangeReviewRequested"
+
+// NameChange records a change of the name on a credential
+type NameChange struct {
+	PreviousFirstName string `json:"PreviousFirstName"`
+	PreviousLastName  string `json:"PreviousLastName"`
+	EvidenceHash      string `json:"EvidenceHash"` // SHA-256 of the supporting document, e.g. a legal name-change certificate
+	TxID              string `json:"TxID"`
+	Timestamp         string `json:"Timestamp"` // Transaction timestamp, RFC 3339
+}
+
+// NameChangeReview is the payload of the NameChangeReviewRequested event
+type NameChangeReview struct {
+	TalentID          string                `json:"TalentID"`
+	PreviousFirstName string                `json:"PreviousFirstName"`
+	PreviousLastName  string                `json:"PreviousLastName"`
+	FirstName         string                `json:"FirstName"`
+	LastName          string                `json:"LastName"`
+	EvidenceHash      string                `json:"EvidenceHash"`
+	Credentials       []CredentialReviewRef `json:"Credentials"` // Verified credentials moved back to PendingReview
+}
+
+// CredentialReviewRef identifies a credential awaiting re-approval and its issuer
+type CredentialReviewRef struct {
+	CredentialID   string `json:"CredentialID"`
+	CredentialType string `json:"CredentialType"`
+	Issuer         string `json:"Issuer"` // Institution or company
+}
+
+// requireTalentOrIssuer rejects callers that are neither the talent, identified by the talentID
+// attribute of their certificate, nor members of Org1
+func requireTalentOrIssuer(ctx contractapi.TransactionContextInterface, talentID string) error {
+	callerTalentID, found, err := ctx.GetClientIdentity().GetAttributeValue("talentID")
+	if err != nil {
+		return fmt.Errorf("could not read talentID attribute: %v", err)
+	}
+	if found && callerTalentID == talentID {
+		return nil
+	}
+
+	callerMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("could not get MSPID: %s", err)
+	}
+	if callerMSPID != "Org1MSP" {
+		return newError(ErrForbidden, "only the talent %s or members of Org1 (institutions) can change the name of the talent", talentID)
+	}
+
+	return nil
+}
+
+// renameTalent writes the new name to a talent profile and copies it onto all of its credentials.
+// Only the talent or an issuer may rename them. Verified credentials go back to PendingReview, and
+// their issuers are notified by an event.
+func renameTalent(ctx contractapi.TransactionContextInterface, profile *TalentProfile, newFirstName string, newLastName string, evidenceHash string) error {
+	if err := requireTalentOrIssuer(ctx, profile.TalentID); err != nil {
+		return err
+	}
+	if err := validateEvidenceHash(evidenceHash); err != nil {
+		return err
+	}
+
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	review := NameChangeReview{
+		TalentID:          profile.TalentID,
+		PreviousFirstName: profile.FirstName,
+		PreviousLastName:  profile.LastName,
+		FirstName:         newFirstName,
+		LastName:          newLastName,
+		EvidenceHash:      evidenceHash,
+		Credentials:       []CredentialReviewRef{},
+	}
+
+	profile.FirstName = newFirstName
+	profile.LastName = newLastName
+	if err := putTalentProfile(ctx, profile); err != nil {
+		return err
+	}
+
+	change := statisticsChange{}
+	for _, credentialID := range profile.CredentialIDs {
+		credential, err := readCredential(ctx, credentialID)
+		if err != nil {
+			return err
+		}
+		if credential.Data.FirstName == newFirstName && credential.Data.LastName == newLastName {
+			continue
+		}
+
+		before := credential.Data
+		credential.Data.NameChanges = append(append([]NameChange{}, before.NameChanges...), NameChange{
+			PreviousFirstName: before.FirstName,
+			PreviousLastName:  before.LastName,
+			EvidenceHash:      evidenceHash,
+			TxID:              ctx.GetStub().GetTxID(),
+			Timestamp:         timestamp,
+		})
+		credential.Data.FirstName = newFirstName
+		credential.Data.LastName = newLastName
+
+		// The issuer vouched for the previous name only
+		if before.VerificationStatus == "Verified" {
+			credential.Data.VerificationStatus = statusPendingReview
+			review.Credentials = append(review.Credentials, CredentialReviewRef{
+				CredentialID:   credentialID,
+				CredentialType: credential.Type,
+				Issuer:         credentialIssuer(&credential.Data),
+			})
+		}
+
+		if err := putCredential(ctx, credential); err != nil {
+			return err
+		}
+		if err := change.add(ctx, &before, &credential.Data); err != nil {
+			return err
+		}
+	}
+	if err := change.write(ctx); err != nil {
+		return err
+	}
+
+	if len(review.Credentials) == 0 {
+		return nil
+	}
+
+	reviewJSON, err := json.Marshal(review)
+	if err != nil {
+		return fmt.Errorf("failed to marshal name change review: %v", err)
+	}
+
+	return ctx.GetStub().SetEvent(nameChangeReviewEvent, reviewJSON)
+}
@@ -0,0 +1,109 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+const evidenceHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func TestUpdateNameMovesVerifiedCredentialsToReview(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+	createProfessional(t, ledger, "cred2", "talent1")
+	verify(t, ledger, "cred1", "Verified")
+
+	credentials := chaincode.CredentialContract{}
+	err := ledger.Submit(talent1, func(ctx contractapi.TransactionContextInterface) error {
+		return credentials.UpdateName(ctx, "cred1", "Jane", "Smith", "")
+	})
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
+
+	err = ledger.Submit(talent1, func(ctx contractapi.TransactionContextInterface) error {
+		return credentials.UpdateName(ctx, "cred1", "Jane", "Smith", evidenceHash)
+	})
+	require.NoError(t, err)
+
+	verified := getCredential(t, ledger, "cred1")
+	require.Equal(t, "PendingReview", verified.Data.VerificationStatus)
+	require.Equal(t, "Smith", verified.Data.LastName)
+	require.Equal(t, []chaincode.NameChange{{
+		PreviousFirstName: "Jane",
+		PreviousLastName:  "Doe",
+		EvidenceHash:      evidenceHash,
+		TxID:              "tx5",
+		Timestamp:         "2024-01-01T00:00:05Z",
+	}}, verified.Data.NameChanges)
+
+	// Pending credentials were not vouched for and keep their status
+	pending := getCredential(t, ledger, "cred2")
+	require.Equal(t, "Pending", pending.Data.VerificationStatus)
+	require.Equal(t, "Smith", pending.Data.LastName)
+	require.Len(t, pending.Data.NameChanges, 1)
+
+	events := ledger.Events()
+	require.Len(t, events, 1)
+	require.Equal(t, "NameChangeReviewRequested", events[0].Name)
+	var review chaincode.NameChangeReview
+	require.NoError(t, json.Unmarshal(events[0].Payload, &review))
+	require.Equal(t, "Doe", review.PreviousLastName)
+	require.Equal(t, "Smith", review.LastName)
+	require.Equal(t, []chaincode.CredentialReviewRef{{
+		CredentialID:   "cred1",
+		CredentialType: "academic",
+		Issuer:         "Concordia University",
+	}}, review.Credentials)
+
+	// The issuer re-approves the credential under the new name
+	verify(t, ledger, "cred1", "Verified")
+	require.Equal(t, "Verified", getCredential(t, ledger, "cred1").Data.VerificationStatus)
+}
+
+func TestUpdateTalentNameWithoutVerifiedCredentials(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+
+	talents := chaincode.TalentContract{}
+	err := ledger.Submit(talent1, func(ctx contractapi.TransactionContextInterface) error {
+		return talents.UpdateTalentName(ctx, "talent1", "Janet", "Doe", evidenceHash)
+	})
+	require.NoError(t, err)
+	require.Empty(t, ledger.Events())
+
+	ctx := ledger.BeginTx(recruiter)
+	profile, err := talents.GetTalentProfile(ctx, "talent1")
+	require.NoError(t, err)
+	require.Equal(t, "Janet", profile.FirstName)
+	require.Equal(t, "Janet", profile.Credentials[0].Data.FirstName)
+	require.Equal(t, "Pending", profile.Credentials[0].Data.VerificationStatus)
+}
+
+func TestUpdateNameRequiresTalentOrIssuer(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+	createAcademic(t, ledger, "cred2", "talent2")
+
+	// Neither other members nor other talents rename a talent
+	credentials := chaincode.CredentialContract{}
+	talents := chaincode.TalentContract{}
+	err := ledger.Submit(recruiter, func(ctx contractapi.TransactionContextInterface) error {
+		return credentials.UpdateName(ctx, "cred1", "Jane", "Smith", evidenceHash)
+	})
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+	err = ledger.Submit(talent1, func(ctx contractapi.TransactionContextInterface) error {
+		return talents.UpdateTalentName(ctx, "talent2", "Jane", "Smith", evidenceHash)
+	})
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+	require.Equal(t, "Doe", getCredential(t, ledger, "cred2").Data.LastName)
+
+	// Issuers do
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return talents.UpdateTalentName(ctx, "talent2", "Jane", "Smith", evidenceHash)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Smith", getCredential(t, ledger, "cred2").Data.LastName)
+}
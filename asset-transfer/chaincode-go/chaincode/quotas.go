@@ -0,0 +1,231 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// quotasMetadataKey is the attribute of the metadata key holding the quota configuration
+const quotasMetadataKey = "quotas"
+
+// QuotaConfig limits credential creation. A zero limit disables the corresponding quota.
+type QuotaConfig struct {
+	MaxPendingPerTalent   int `json:"MaxPendingPerTalent"`   // Pending credentials a talent may hold
+	MaxCreationsPerIssuer int `json:"MaxCreationsPerIssuer"` // Credentials an identity may create per window
+	IssuerWindowSeconds   int `json:"IssuerWindowSeconds"`   // Length of the creation window, in seconds
+}
+
+// GetQuotas returns the quota configuration. Quotas are disabled until SetQuotas is called.
+func (c *AdminContract) GetQuotas(ctx contractapi.TransactionContextInterface) (*QuotaConfig, error) {
+	return readQuotas(ctx)
+}
+
+// SetQuotas changes the quota configuration
+func (c *AdminContract) SetQuotas(ctx contractapi.TransactionContextInterface, maxPendingPerTalent int, maxCreationsPerIssuer int, issuerWindowSeconds int) error {
+	if err := requireIssuer(ctx, "set quotas on"); err != nil {
+		return err
+	}
+	if maxPendingPerTalent < 0 || maxCreationsPerIssuer < 0 || issuerWindowSeconds < 0 {
+		return newError(ErrInvalidArgument, "quotas must not be negative")
+	}
+	if maxCreationsPerIssuer > 0 && issuerWindowSeconds == 0 {
+		return newError(ErrInvalidArgument, "issuerWindowSeconds is required when maxCreationsPerIssuer is set")
+	}
+
+	quotasJSON, err := json.Marshal(QuotaConfig{
+		MaxPendingPerTalent:   maxPendingPerTalent,
+		MaxCreationsPerIssuer: maxCreationsPerIssuer,
+		IssuerWindowSeconds:   issuerWindowSeconds,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal quotas: %v", err)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(metadataObjectType, []string{quotasMetadataKey})
+	if err != nil {
+		return fmt.Errorf("failed to create metadata key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(key, quotasJSON)
+}
+
+// readQuotas returns the quota configuration, all quotas disabled if none was set
+func readQuotas(ctx contractapi.TransactionContextInterface) (*QuotaConfig, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(metadataObjectType, []string{quotasMetadataKey})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata key: %v", err)
+	}
+
+	quotasJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	var quotas QuotaConfig
+	if quotasJSON != nil {
+		if err := json.Unmarshal(quotasJSON, &quotas); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal quotas: %v", err)
+		}
+	}
+
+	return &quotas, nil
+}
+
+// enforceCreationQuotas rejects the creation of a credential for a talent when it would exceed
+// the quotas, and counts the creation in the window of the calling identity
+func enforceCreationQuotas(ctx contractapi.TransactionContextInterface, talentID string) error {
+	quotas, err := readQuotas(ctx)
+	if err != nil {
+		return err
+	}
+
+	if quotas.MaxPendingPerTalent > 0 {
+		if err := checkPendingQuota(ctx, talentID, quotas.MaxPendingPerTalent); err != nil {
+			return err
+		}
+	}
+	if quotas.MaxCreationsPerIssuer > 0 {
+		if err := countIssuerCreation(ctx, quotas.MaxCreationsPerIssuer, quotas.IssuerWindowSeconds); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkPendingQuota rejects a new credential when the talent already holds the maximum number of
+// pending credentials, as counted on their profile
+func checkPendingQuota(ctx contractapi.TransactionContextInterface, talentID string, maxPending int) error {
+	profile, err := readTalentProfile(ctx, talentID)
+	if err != nil || profile == nil {
+		return err
+	}
+
+	if profile.PendingCredentials >= maxPending {
+		return newError(ErrQuotaExceeded, "the talent %s already has %d pending credentials", talentID, profile.PendingCredentials)
+	}
+
+	return nil
+}
+
+// pendingChange returns the change of the number of pending credentials of a talent when one of
+// their credentials changes from before to after. before is nil for a new credential and after
+// is nil for a deleted one.
+func pendingChange(before *CredentialData, after *CredentialData) int {
+	change := 0
+	if before != nil && before.VerificationStatus == "Pending" {
+		change--
+	}
+	if after != nil && after.VerificationStatus == "Pending" {
+		change++
+	}
+
+	return change
+}
+
+// countPending changes the number of pending credentials of a profile. Profiles written before
+// the count existed start from zero, so it never goes below zero.
+func (profile *TalentProfile) countPending(change int) {
+	profile.PendingCredentials += change
+	if profile.PendingCredentials < 0 {
+		profile.PendingCredentials = 0
+	}
+}
+
+// updatePendingCount updates the profile of the talent of a credential changing from before to
+// after, when the change affects its number of pending credentials
+func updatePendingCount(ctx contractapi.TransactionContextInterface, before *CredentialData, after *CredentialData) error {
+	change := pendingChange(before, after)
+	if change == 0 {
+		return nil
+	}
+
+	profile, err := readTalentProfile(ctx, after.TalentID)
+	if err != nil || profile == nil {
+		return err
+	}
+	profile.countPending(change)
+
+	return putTalentProfile(ctx, profile)
+}
+
+// countIssuerCreation rejects a creation when the calling identity already created the maximum
+// number of credentials within the last windowSeconds, and otherwise records it. Each creation is
+// its own entry, keyed by the identity, the window-long bucket of its timestamp and its
+// transaction ID, so creations never rewrite a shared key; the creations of the window are
+// counted by scanning the current and the previous bucket.
+func countIssuerCreation(ctx contractapi.TransactionContextInterface, maxCreations int, windowSeconds int) error {
+	issuer, err := callerHash(ctx)
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid transaction timestamp %s: %v", timestamp, err)
+	}
+	window := time.Duration(windowSeconds) * time.Second
+	bucket := now.Unix() / int64(windowSeconds)
+
+	creations := 0
+	for _, scanned := range []int64{bucket - 1, bucket} {
+		count, err := countCreationsSince(ctx, issuer, scanned, now.Add(-window))
+		if err != nil {
+			return err
+		}
+		creations += count
+	}
+	if creations >= maxCreations {
+		return newError(ErrQuotaExceeded, "at most %d credentials may be created every %d seconds by the same identity", maxCreations, windowSeconds)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(quotaObjectType, []string{issuer, strconv.FormatInt(bucket, 10), ctx.GetStub().GetTxID()})
+	if err != nil {
+		return fmt.Errorf("failed to create quota key: %v", err)
+	}
+	creationJSON, err := json.Marshal(timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal quota entry: %v", err)
+	}
+
+	return ctx.GetStub().PutState(key, creationJSON)
+}
+
+// countCreationsSince counts the creations of an identity in a bucket that happened after since
+func countCreationsSince(ctx contractapi.TransactionContextInterface, issuer string, bucket int64, since time.Time) (int, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(quotaObjectType, []string{issuer, strconv.FormatInt(bucket, 10)})
+	if err != nil {
+		return 0, err
+	}
+	defer resultsIterator.Close()
+
+	count := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+
+		var timestamp string
+		if err := json.Unmarshal(queryResponse.Value, &timestamp); err != nil {
+			return 0, fmt.Errorf("failed to unmarshal quota entry: %v", err)
+		}
+		created, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			return 0, fmt.Errorf("invalid quota entry timestamp %s: %v", timestamp, err)
+		}
+		if created.After(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
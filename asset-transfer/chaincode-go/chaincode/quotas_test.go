@@ -0,0 +1,101 @@
+package chaincode_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgertest"
+	"github.com/stretchr/testify/require"
+)
+
+// setQuotas configures the quotas as the registrar
+func setQuotas(t *testing.T, ledger *ledgertest.Stub, maxPendingPerTalent int, maxCreationsPerIssuer int, issuerWindowSeconds int) {
+	t.Helper()
+
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.AdminContract{}).SetQuotas(ctx, maxPendingPerTalent, maxCreationsPerIssuer, issuerWindowSeconds)
+	})
+	require.NoError(t, err)
+}
+
+// tryCreate creates a professional credential as the registrar and returns the error
+func tryCreate(ledger *ledgertest.Stub, credentialID string, talentID string) error {
+	return ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := (&chaincode.CredentialContract{}).CreateProfessionalCredential(ctx, credentialID, talentID, "Jane", "Doe", "Go", "5 years", "Acme")
+		return err
+	})
+}
+
+func TestSetQuotas(t *testing.T) {
+	ledger := newLedger()
+	admin := chaincode.AdminContract{}
+
+	ctx := ledger.BeginTx(recruiter)
+	quotas, err := admin.GetQuotas(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.QuotaConfig{}, quotas)
+
+	setQuotas(t, ledger, 2, 10, 3600)
+	ctx = ledger.BeginTx(recruiter)
+	quotas, err = admin.GetQuotas(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.QuotaConfig{MaxPendingPerTalent: 2, MaxCreationsPerIssuer: 10, IssuerWindowSeconds: 3600}, quotas)
+
+	err = ledger.Submit(recruiter, func(ctx contractapi.TransactionContextInterface) error {
+		return admin.SetQuotas(ctx, 0, 0, 0)
+	})
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return admin.SetQuotas(ctx, -1, 0, 0)
+	})
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
+
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return admin.SetQuotas(ctx, 0, 10, 0)
+	})
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
+}
+
+func TestPendingQuota(t *testing.T) {
+	ledger := newLedger()
+	setQuotas(t, ledger, 2, 0, 0)
+
+	require.NoError(t, tryCreate(ledger, "cred1", "talent1"))
+	require.NoError(t, tryCreate(ledger, "cred2", "talent1"))
+	requireErrorCode(t, tryCreate(ledger, "cred3", "talent1"), chaincode.ErrQuotaExceeded)
+
+	// The quota applies per talent and frees up as credentials are reviewed
+	require.NoError(t, tryCreate(ledger, "cred3", "talent2"))
+	verify(t, ledger, "cred1", "Verified")
+	require.NoError(t, tryCreate(ledger, "cred4", "talent1"))
+	requireErrorCode(t, tryCreate(ledger, "cred5", "talent1"), chaincode.ErrQuotaExceeded)
+
+	// Deleting a pending credential frees up the quota as well
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.CredentialContract{}).DeleteTalentCredential(ctx, "cred2")
+	})
+	require.NoError(t, err)
+	require.NoError(t, tryCreate(ledger, "cred5", "talent1"))
+}
+
+func TestIssuerQuota(t *testing.T) {
+	ledger := newLedger()
+	setQuotas(t, ledger, 0, 2, 60)
+
+	require.NoError(t, tryCreate(ledger, "cred1", "talent1"))
+	require.NoError(t, tryCreate(ledger, "cred2", "talent2"))
+	requireErrorCode(t, tryCreate(ledger, "cred3", "talent3"), chaincode.ErrQuotaExceeded)
+
+	// The window slides with the transaction timestamps rather than restarting at fixed times
+	ledger.Advance(30 * time.Second)
+	requireErrorCode(t, tryCreate(ledger, "cred3", "talent3"), chaincode.ErrQuotaExceeded)
+
+	// Rejected creations are not counted, and creations older than the window are no longer counted
+	ledger.Advance(30 * time.Second)
+	require.NoError(t, tryCreate(ledger, "cred3", "talent3"))
+	require.NoError(t, tryCreate(ledger, "cred4", "talent4"))
+	requireErrorCode(t, tryCreate(ledger, "cred5", "talent5"), chaincode.ErrQuotaExceeded)
+}
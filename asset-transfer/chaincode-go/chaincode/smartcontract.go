@@ -1,17 +1,32 @@
 package chaincode
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
 )
 
-// SmartContract provides functions for managing an Asset
-type SmartContract struct {
+// CredentialContract provides functions for issuing and managing talent credentials
+type CredentialContract struct {
 	contractapi.Contract
 }
 
+// GetEvaluateTransactions lists the read-only functions, which are tagged "evaluate" in the
+// contract metadata so that clients query them instead of submitting transactions
+func (c *CredentialContract) GetEvaluateTransactions() []string {
+	return []string{
+		"CredentialExists",
+		"GetBaseCredential",
+		"GetAcademicCredential",
+		"GetProfessionalCredential",
+		"GetTalentCredential",
+		"GetAllCredentials",
+		"GetCredentialsByTalent",
+		"GetCredentialsByInstitution",
+		"GetCredentialsByCompany",
+	}
+}
+
 // BaseCredential contains the common fields for all credentials
 type BaseCredential struct {
 	CredentialID		string `json:"CredentialID"`		// Credential unique identifier
@@ -22,6 +37,12 @@ type BaseCredential struct {
 	TalentID          	string `json:"TalentID"`         	// Talent identifier
 	VerificationStatus 	string `json:"VerificationStatus"` 	// Status of the credential verification (e.g., "Pending", "Verified")
 	VerifiedBy        	string `json:"VerifiedBy"`        	// Institution or admin that verified the credentials
+	CreatedAt         	string `json:"CreatedAt,omitempty" metadata:",optional"`    // Timestamp of the creating transaction, RFC 3339
+	CreatedBy         	string `json:"CreatedBy,omitempty" metadata:",optional"`    // Hash of the identity that created the credential, see callerHash
+	Supersedes        	string `json:"Supersedes,omitempty" metadata:",optional"`   // ID of the credential this one was reissued from
+	SupersededBy      	string `json:"SupersededBy,omitempty" metadata:",optional"` // ID of the credential reissued from this one
+	EncryptedFields   	[]string `json:"EncryptedFields,omitempty" metadata:",optional"` // Fields stored encrypted, see encryption.go
+	NameChanges       	[]NameChange `json:"NameChanges,omitempty" metadata:",optional"` // Earlier names on the credential, oldest first
 }
 
 // AcademicCredential is for academic credentials (e.g., degree, diploma)
@@ -38,160 +59,167 @@ type ProfessionalCredential struct {
 	WorkExperience  string `json:"WorkExperience"`
 }
 
-// InitLedger initializes the ledger with some sample talent credentials
-func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
-	credentials := []interface{}{
-		AcademicCredential{
-			BaseCredential: BaseCredential{
-				CredentialID:		"credential1",
-				TalentID:           "alicesmith01",
-				FirstName:          "Alice",
-				LastName:           "Smith",
-				Skills:             "Python, Data Analysis",
-				VerificationStatus: "Verified",
-				VerifiedBy:         "Concordia University",
-				CredentialType:		"academic",
-			},
-			Education:  "B.Sc. in Computer Science",
-			Institution: "Concordia University",
-		},
-		ProfessionalCredential{
-			BaseCredential: BaseCredential{
-				CredentialID:		"credential2",
-				TalentID:           "bobjohnson01",
-				FirstName:          "Bob",
-				LastName:           "Johnson",
-				Skills:             "Project Management, Leadership",
-				VerificationStatus: "Verified",
-				VerifiedBy:         "Company ABCDEF",
-				CredentialType:		"professional",
-			},
-			WorkExperience: "5 years as Project Manager",
-			Company:        "Company ABCDEF",
-		},
-		AcademicCredential{
-			BaseCredential: BaseCredential{
-				CredentialID:		"credential3",
-				TalentID:           "charliebrown02",
-				FirstName:          "Charlie",
-				LastName:           "Brown",
-				Skills:             "Java, Software Engineering",
-				VerificationStatus: "Pending",
-				VerifiedBy:         "",
-				CredentialType:		"academic",
-			},
-			Education:   "M.Sc. in Software Engineering",
-			Institution: "Polytechnique Montréal",
-		},
-		ProfessionalCredential{
-			BaseCredential: BaseCredential{
-				CredentialID:		"credential4",
-				TalentID:           "charliebrown02",
-				FirstName:          "Charlie",
-				LastName:           "Brown",
-				Skills:             "C, C++, Python, Shell",
-				VerificationStatus: "Pending",
-				VerifiedBy:         "",
-				CredentialType:		"professional",
-			},
-			WorkExperience: "4-Month Internship as a Software Developer",
-			Company:        "Company XYZ",
-		},
-	}
-
-	for _, credential := range credentials {
-		credentialJSON, err := json.Marshal(credential)
+// Issues a new academic credential and returns its ID. When credentialID is empty, the ID is
+// assigned by the ledger (see newCredentialID).
+func (c *CredentialContract) CreateAcademicCredential(ctx contractapi.TransactionContextInterface, credentialID string, talentID string, firstName string, lastName string, skills string, education string, institution string) (string, error) {
+	if credentialID == "" {
+		var err error
+		credentialID, err = newCredentialID(ctx, 0)
 		if err != nil {
-			return fmt.Errorf("failed to marshal credential: %v", err)
+			return "", err
 		}
+	}
+	if err := validateCredentialFields(credentialID, talentID, firstName, lastName, skills); err != nil {
+		return "", err
+	}
+	if err := validateText("education", education, true); err != nil {
+		return "", err
+	}
+	if err := validateText("institution", institution, true); err != nil {
+		return "", err
+	}
 
-		// Store each credential in the ledger, using credentialID as the key
-		credentialID := ""
-		var baseCredential BaseCredential
-		err = json.Unmarshal(credentialJSON, &baseCredential)
-		if err != nil {
-			return fmt.Errorf("failed to unmarshal credential: %v", err)
-		}
-		credentialID = baseCredential.CredentialID		
-
-		err = ctx.GetStub().PutState(credentialID, credentialJSON)
-		if err != nil {
-			return fmt.Errorf("failed to put talent credential to world state. %v", err)
-		}
+	exists, err := credentialExists(ctx, credentialID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", newError(ErrAlreadyExists, "the credential %s already exists", credentialID)
+	}
+	if err := enforceCreationQuotas(ctx, talentID); err != nil {
+		return "", err
+	}
+	if err := checkAccreditation(ctx, institution); err != nil {
+		return "", err
 	}
 
-	return nil
-}
+	// The credential references the talent profile, which holds the canonical name
+	profile, err := linkCredentialToProfile(ctx, talentID, credentialID, firstName, lastName)
+	if err != nil {
+		return "", err
+	}
 
-// Issues a new academic credential
-func (s *SmartContract) CreateAcademicCredential(ctx contractapi.TransactionContextInterface, credentialID string, talentID string, firstName string, lastName string, skills string, education string, institution string) error {
-	exists, err := s.CredentialExists(ctx, credentialID)
+	createdAt, err := txTimestamp(ctx)
 	if err != nil {
-		return err
+		return "", err
 	}
-	if exists {
-		return fmt.Errorf("the credential %s already exists", credentialID)
+	createdBy, err := callerHash(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	academicCredential := AcademicCredential{
+	academicCredential := CredentialData{
 		BaseCredential: BaseCredential{
 			CredentialID:		credentialID,
 			TalentID:           talentID,
-			FirstName:          firstName,
-			LastName:           lastName,
+			FirstName:          profile.FirstName,
+			LastName:           profile.LastName,
 			Skills:             skills,
 			VerificationStatus: "Pending",
 			CredentialType:		"academic",
 			VerifiedBy:			"",
+			CreatedAt:          createdAt,
+			CreatedBy:          createdBy,
 		},
 		Education: education,
 		Institution: institution,
 	}
 
-	academicCredentialJSON, err := json.Marshal(academicCredential)
-	if err != nil {
-		return fmt.Errorf("failed to marshal academic credential: %v", err)
+	if err := putCredential(ctx, &CredentialEnvelope{Type: credentialTypeAcademic, Data: academicCredential}); err != nil {
+		return "", err
+	}
+	if err := recordCredentialChange(ctx, nil, &academicCredential); err != nil {
+		return "", err
 	}
 
-	return ctx.GetStub().PutState(credentialID, academicCredentialJSON)
+	return credentialID, nil
 }
 
-// Issues a new professional credential
-func (s *SmartContract) CreateProfessionalCredential(ctx contractapi.TransactionContextInterface, credentialID string, talentID string, firstName string, lastName string, skills string, workExperience string, company string) error {
-	exists, err := s.CredentialExists(ctx, credentialID)
+// Issues a new professional credential and returns its ID. When credentialID is empty, the ID is
+// assigned by the ledger (see newCredentialID).
+func (c *CredentialContract) CreateProfessionalCredential(ctx contractapi.TransactionContextInterface, credentialID string, talentID string, firstName string, lastName string, skills string, workExperience string, company string) (string, error) {
+	if credentialID == "" {
+		var err error
+		credentialID, err = newCredentialID(ctx, 0)
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := validateCredentialFields(credentialID, talentID, firstName, lastName, skills); err != nil {
+		return "", err
+	}
+	if err := validateText("workExperience", workExperience, true); err != nil {
+		return "", err
+	}
+	if err := validateText("company", company, true); err != nil {
+		return "", err
+	}
+
+	exists, err := credentialExists(ctx, credentialID)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if exists {
-		return fmt.Errorf("the credential %s already exists", credentialID)
+		return "", newError(ErrAlreadyExists, "the credential %s already exists", credentialID)
+	}
+	if err := enforceCreationQuotas(ctx, talentID); err != nil {
+		return "", err
+	}
+
+	// The credential references the talent profile, which holds the canonical name
+	profile, err := linkCredentialToProfile(ctx, talentID, credentialID, firstName, lastName)
+	if err != nil {
+		return "", err
 	}
 
-	professionalCredential := ProfessionalCredential{
+	createdAt, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	createdBy, err := callerHash(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	professionalCredential := CredentialData{
 		BaseCredential: BaseCredential{
 			CredentialID:		credentialID,
 			TalentID:           talentID,
-			FirstName:          firstName,
-			LastName:           lastName,
+			FirstName:          profile.FirstName,
+			LastName:           profile.LastName,
 			Skills:             skills,
 			VerificationStatus: "Pending",
 			CredentialType:		"professional",
 			VerifiedBy:			"",
+			CreatedAt:          createdAt,
+			CreatedBy:          createdBy,
 		},
 		WorkExperience: workExperience,
 		Company:        company,
 	}
 
-	professionalCredentialJSON, err := json.Marshal(professionalCredential)
-	if err != nil {
-		return fmt.Errorf("failed to marshal professional credential: %v", err)
+	if err := putCredential(ctx, &CredentialEnvelope{Type: credentialTypeProfessional, Data: professionalCredential}); err != nil {
+		return "", err
+	}
+	if err := recordCredentialChange(ctx, nil, &professionalCredential); err != nil {
+		return "", err
 	}
 
-	return ctx.GetStub().PutState(credentialID, professionalCredentialJSON)
+	return credentialID, nil
 }
 
 // CredentialExists returns true when credential with given ID exists in world state
-func (s *SmartContract) CredentialExists(ctx contractapi.TransactionContextInterface, credentialID string) (bool, error) {
-	credentialJSON, err := ctx.GetStub().GetState(credentialID)
+func (c *CredentialContract) CredentialExists(ctx contractapi.TransactionContextInterface, credentialID string) (bool, error) {
+	return credentialExists(ctx, credentialID)
+}
+
+// credentialExists returns true when credential with given ID exists in world state
+func credentialExists(ctx contractapi.TransactionContextInterface, credentialID string) (bool, error) {
+	if err := validateID("credentialID", credentialID); err != nil {
+		return false, err
+	}
+
+	credentialJSON, err := getCredentialState(ctx, credentialID)
 	if err != nil {
 		return false, fmt.Errorf("failed to read from world state: %v", err)
 	}
@@ -199,297 +227,212 @@ func (s *SmartContract) CredentialExists(ctx contractapi.TransactionContextInter
 	return credentialJSON != nil, nil
 }
 
-// GetBaseCredential retrieves the talent base credential by its ID
-func (s *SmartContract) GetBaseCredential(ctx contractapi.TransactionContextInterface, credentialID string) (*BaseCredential, error) {
-	talentCredentialJSON, err := ctx.GetStub().GetState(credentialID)
+// GetBaseCredential retrieves the common fields of a talent credential by its ID
+func (c *CredentialContract) GetBaseCredential(ctx contractapi.TransactionContextInterface, credentialID string) (*CredentialEnvelope, error) {
+	envelope, err := readCurrentCredential(ctx, credentialID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if talentCredentialJSON == nil {
-		return nil, fmt.Errorf("the talent credential %s does not exist", credentialID)
+		return nil, err
 	}
 
-	var baseCredential BaseCredential
-	err = json.Unmarshal(talentCredentialJSON, &baseCredential)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal talent credential: %v", err)
-	}
+	// Only keep the fields shared by all credential types
+	envelope.Data = CredentialData{BaseCredential: envelope.Data.BaseCredential}
 
-	return &baseCredential, nil
+	return envelope, nil
 }
 
 // GetAcademicCredential retrieves the academic credential by its ID
-func (s *SmartContract) GetAcademicCredential(ctx contractapi.TransactionContextInterface, credentialID string) (*AcademicCredential, error) {
-	talentCredentialJSON, err := ctx.GetStub().GetState(credentialID)
+func (c *CredentialContract) GetAcademicCredential(ctx contractapi.TransactionContextInterface, credentialID string) (*CredentialEnvelope, error) {
+	envelope, err := readCurrentCredential(ctx, credentialID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if talentCredentialJSON == nil {
-		return nil, fmt.Errorf("the academic credential %s does not exist", credentialID)
-	}
-
-	var academicCredential AcademicCredential
-	err = json.Unmarshal(talentCredentialJSON, &academicCredential)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal academic credential: %v", err)
+		return nil, err
 	}
-	if academicCredential.CredentialType != "academic" {
-		return nil, fmt.Errorf("the credential is not of type academic but %v", academicCredential.CredentialType)
+	if envelope.Type != credentialTypeAcademic {
+		return nil, newError(ErrInvalidArgument, "the credential is not of type academic but %v", envelope.Type)
 	}
 
-	return &academicCredential, nil
+	return envelope, nil
 }
 
 // GetProfessionalCredential retrieves the professional credential by its ID
-func (s *SmartContract) GetProfessionalCredential(ctx contractapi.TransactionContextInterface, credentialID string) (*ProfessionalCredential, error) {
-	talentCredentialJSON, err := ctx.GetStub().GetState(credentialID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if talentCredentialJSON == nil {
-		return nil, fmt.Errorf("the professional credential %s does not exist", credentialID)
-	}
-
-	var professionalCredential ProfessionalCredential
-	err = json.Unmarshal(talentCredentialJSON, &professionalCredential)
+func (c *CredentialContract) GetProfessionalCredential(ctx contractapi.TransactionContextInterface, credentialID string) (*CredentialEnvelope, error) {
+	envelope, err := readCurrentCredential(ctx, credentialID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal professional credential: %v", err)
+		return nil, err
 	}
-	if professionalCredential.CredentialType != "professional" {
-		return nil, fmt.Errorf("the credential is not of type professional but %v", professionalCredential.CredentialType)
+	if envelope.Type != credentialTypeProfessional {
+		return nil, newError(ErrInvalidArgument, "the credential is not of type professional but %v", envelope.Type)
 	}
 
-	return &professionalCredential, nil
+	return envelope, nil
 }
 
+// GetTalentCredential retrieves the entire talent credential by its ID (either academic or professional).
+// Like the other getters, it returns the current version of a superseded credential.
+func (c *CredentialContract) GetTalentCredential(ctx contractapi.TransactionContextInterface, credentialID string) (*CredentialEnvelope, error) {
+	return readCurrentCredential(ctx, credentialID)
+}
 
-// GetTalentCredential retrieves the entire talent credential by its ID (either academic or professional)
-// But we lose the structure TODO: problem
-func (s *SmartContract) GetTalentCredential(ctx contractapi.TransactionContextInterface, credentialID string) (interface{}, error) {
-	talentCredentialJSON, err := ctx.GetStub().GetState(credentialID)
+// Deletes a talent credential by its ID
+func (c *CredentialContract) DeleteTalentCredential(ctx contractapi.TransactionContextInterface, credentialID string) error {
+	talentCredential, err := readCredential(ctx, credentialID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if talentCredentialJSON == nil {
-		return nil, fmt.Errorf("the talent credential %s does not exist", credentialID)
+		return err
 	}
 
-	var baseCredential BaseCredential
-	err = json.Unmarshal(talentCredentialJSON, &baseCredential)
+	// Delete the credential from the ledger
+	err = delCredentialState(ctx, credentialID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal talent credential: %v", err)
+		return fmt.Errorf("failed to delete talent credential from world state: %v", err)
 	}
-
-	// Determine if the credential is academic or professional
-	if baseCredential.CredentialType == "academic" {
-		var academicCredential AcademicCredential
-		err = json.Unmarshal(talentCredentialJSON, &academicCredential)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal academic credential: %v", err)
-		}
-		return academicCredential, nil
-	} else if baseCredential.CredentialType == "professional" {
-		var professionalCredential ProfessionalCredential
-		err = json.Unmarshal(talentCredentialJSON, &professionalCredential)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal professional credential: %v", err)
-		}
-		return professionalCredential, nil
+	if err := recordCredentialChange(ctx, &talentCredential.Data, nil); err != nil {
+		return err
 	}
 
-	return nil, fmt.Errorf("the talent credential type %v does not exist", baseCredential.CredentialType)
+	return unlinkCredentialFromProfile(ctx, &talentCredential.Data)
 }
 
-// Updates the verification status of a talent credential
-func (s *SmartContract) UpdateVerificationStatus(ctx contractapi.TransactionContextInterface, credentialID string, status string, verifiedBy string) error {
-	// Get the identity of the invoker (the user calling the smart contract)
-	callerMSPID, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return fmt.Errorf("could not get MSPID: %s", err)
+// Updates the skills of a talent credential
+func (c *CredentialContract) UpdateSkills(ctx contractapi.TransactionContextInterface, credentialID string, newSkills string) error {
+	if err := validateText("newSkills", newSkills, false); err != nil {
+		return err
 	}
 
-	// Only allow org1 (institutions) to approve or revoke
-	if callerMSPID != "Org1MSP" {
-		return fmt.Errorf("only members of Org1 (institutions) can approve or revoke credentials")
-	}
-	
-	talentCredential, err := s.GetTalentCredential(ctx, credentialID)
+	talentCredential, err := readCredential(ctx, credentialID)
 	if err != nil {
 		return err
 	}
+	if err := checkNotSuperseded(talentCredential); err != nil {
+		return err
+	}
+	if err := requireDecrypted(&talentCredential.Data, "Skills"); err != nil {
+		return err
+	}
 
-	// Type assertion: Determine the type of talentCredential and update the necessary fields
-	switch v := talentCredential.(type) {
-	case AcademicCredential:
-		v.VerificationStatus = status
-		v.VerifiedBy = verifiedBy
-
-		updatedCredentialJSON, err := json.Marshal(v)
-		if err != nil {
-			return fmt.Errorf("failed to marshal updated academic credential: %v", err)
-		}
-
-		return ctx.GetStub().PutState(credentialID, updatedCredentialJSON)
-
-	case ProfessionalCredential:
-		v.VerificationStatus = status
-		v.VerifiedBy = verifiedBy
-
-		updatedCredentialJSON, err := json.Marshal(v)
-		if err != nil {
-			return fmt.Errorf("failed to marshal updated professional credential: %v", err)
-		}
-
-		return ctx.GetStub().PutState(credentialID, updatedCredentialJSON)
+	talentCredential.Data.Skills = newSkills
 
-	default:
-		return fmt.Errorf("unexpected credential type: %T", v)
-	}
+	return putCredential(ctx, talentCredential)
 }
 
-// Deletes a talent credential by its ID
-func (s *SmartContract) DeleteTalentCredential(ctx contractapi.TransactionContextInterface, credentialID string) error {
-	talentCredential, err := s.GetTalentCredential(ctx, credentialID)
-	if err != nil {
+// Updates the first and last name of the talent owning a credential (if the talent made an error).
+// The name lives on the talent profile, so the change applies to all of the talent's credentials.
+// evidenceHash is the SHA-256 of the document supporting the change; verified credentials need to be re-approved.
+func (c *CredentialContract) UpdateName(ctx contractapi.TransactionContextInterface, credentialID string, newFirstName string, newLastName string, evidenceHash string) error {
+	if err := validateName("newFirstName", newFirstName); err != nil {
 		return err
 	}
-
-	if talentCredential == nil {
-		return fmt.Errorf("the talent credential %s does not exist", credentialID)
+	if err := validateName("newLastName", newLastName); err != nil {
+		return err
 	}
 
-	// Delete the credential from the ledger
-	return ctx.GetStub().DelState(credentialID)
-}
-
-// Updates the skills of a talent credential
-func (s *SmartContract) UpdateSkills(ctx contractapi.TransactionContextInterface, credentialID string, newSkills string) error {
-	talentCredential, err := s.GetTalentCredential(ctx, credentialID)
+	talentCredential, err := readCredential(ctx, credentialID)
 	if err != nil {
 		return err
 	}
 
-	// Type assertion: Determine the type of talentCredential and update the skills
-	switch v := talentCredential.(type) {
-	case AcademicCredential:
-		v.Skills = newSkills
-
-		updatedCredentialJSON, err := json.Marshal(v)
-		if err != nil {
-			return fmt.Errorf("failed to marshal updated academic credential: %v", err)
+	profile, err := readTalentProfile(ctx, talentCredential.Data.TalentID)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		// Credentials issued before talent profiles existed get their profile on first rename
+		profile = &TalentProfile{
+			TalentID:      talentCredential.Data.TalentID,
+			CredentialIDs: []string{credentialID},
 		}
+	}
 
-		return ctx.GetStub().PutState(credentialID, updatedCredentialJSON)
-
-	case ProfessionalCredential:
-		v.Skills = newSkills
-
-		updatedCredentialJSON, err := json.Marshal(v)
-		if err != nil {
-			return fmt.Errorf("failed to marshal updated professional credential: %v", err)
-		}
+	return renameTalent(ctx, profile, newFirstName, newLastName, evidenceHash)
+}
 
-		return ctx.GetStub().PutState(credentialID, updatedCredentialJSON)
+// GetAllCredentials retrieves all credentials (both academic and professional) from the ledger
+func (c *CredentialContract) GetAllCredentials(ctx contractapi.TransactionContextInterface) ([]*CredentialEnvelope, error) {
+	return listCredentials(ctx, func(*CredentialData) bool { return true })
+}
 
-	default:
-		return fmt.Errorf("unexpected credential type: %T", v)
+// GetCredentialsByTalent retrieves the credentials issued to a talent, linked from their profile
+func (c *CredentialContract) GetCredentialsByTalent(ctx contractapi.TransactionContextInterface, talentID string) ([]*CredentialEnvelope, error) {
+	if err := validateID("talentID", talentID); err != nil {
+		return nil, err
 	}
-}
 
-// Updates the first and last name of a talent credential (if the talent made an error)
-func (s *SmartContract) UpdateName(ctx contractapi.TransactionContextInterface, credentialID string, newFirstName string, newLastName string) error {
-	talentCredential, err := s.GetTalentCredential(ctx, credentialID)
+	profile, err := readTalentProfile(ctx, talentID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Type assertion: Determine the type of talentCredential and update the name fields
-	switch v := talentCredential.(type) {
-	case AcademicCredential:
-		v.FirstName = newFirstName
-		v.LastName = newLastName
-
-		updatedCredentialJSON, err := json.Marshal(v)
+	credentials := []*CredentialEnvelope{}
+	if profile == nil {
+		return credentials, nil
+	}
+	for _, credentialID := range profile.CredentialIDs {
+		credential, err := readCredential(ctx, credentialID)
 		if err != nil {
-			return fmt.Errorf("failed to marshal updated academic credential: %v", err)
+			return nil, err
 		}
-
-		return ctx.GetStub().PutState(credentialID, updatedCredentialJSON)
-
-	case ProfessionalCredential:
-		v.FirstName = newFirstName
-		v.LastName = newLastName
-
-		updatedCredentialJSON, err := json.Marshal(v)
-		if err != nil {
-			return fmt.Errorf("failed to marshal updated professional credential: %v", err)
+		if err := decryptCredentialFields(ctx, &credential.Data); err != nil {
+			return nil, err
 		}
+		if err := addEndorsementCounts(ctx, credentialID, credential); err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
 
-		return ctx.GetStub().PutState(credentialID, updatedCredentialJSON)
+	return credentials, nil
+}
 
-	default:
-		return fmt.Errorf("unexpected credential type: %T", v)
+// GetCredentialsByInstitution retrieves the academic credentials granted by an institution
+func (c *CredentialContract) GetCredentialsByInstitution(ctx contractapi.TransactionContextInterface, institution string) ([]*CredentialEnvelope, error) {
+	if err := validateText("institution", institution, true); err != nil {
+		return nil, err
 	}
+
+	return listCredentials(ctx, func(data *CredentialData) bool { return data.Institution == institution })
 }
 
+// GetCredentialsByCompany retrieves the professional credentials of work at a company
+func (c *CredentialContract) GetCredentialsByCompany(ctx contractapi.TransactionContextInterface, company string) ([]*CredentialEnvelope, error) {
+	if err := validateText("company", company, true); err != nil {
+		return nil, err
+	}
+
+	return listCredentials(ctx, func(data *CredentialData) bool { return data.Company == company })
+}
 
-// GetAllCredentials retrieves all credentials (both academic and professional) from the ledger
-func (s *SmartContract) GetAllCredentials(ctx contractapi.TransactionContextInterface) ([]byte, error) { //TODO: before []interface{}, now []byte
-	// Perform a range query with an empty string for startKey and endKey for an open-ended query
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+// listCredentials returns the credentials in world state matching a filter
+func listCredentials(ctx contractapi.TransactionContextInterface, match func(*CredentialData) bool) ([]*CredentialEnvelope, error) {
+	// Scan only the credential namespace so that other entity types are never listed as credentials
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(credentialObjectType, []string{})
 	if err != nil {
 		return nil, err
 	}
 	defer resultsIterator.Close()
 
-	var credentials []interface{}
+	credentials := []*CredentialEnvelope{}
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
 			return nil, err
 		}
 
-		// Check the credential type by unmarshalling into a BaseCredential first
-		var baseCredential BaseCredential
-		err = json.Unmarshal(queryResponse.Value, &baseCredential)
+		envelope, err := newCredentialEnvelope(queryResponse.Value)
 		if err != nil {
 			return nil, err
 		}
-
-		// Determine whether the credential is academic or professional
-		var credential interface{}
-		if baseCredential.CredentialType == "academic" {
-			// AcademicCredential
-			var academicCredential AcademicCredential
-			err = json.Unmarshal(queryResponse.Value, &academicCredential)
-			if err != nil {
-				return nil, err
-			}
-			credential = academicCredential
-		} else if baseCredential.CredentialType == "professional" {
-			// ProfessionalCredential
-			var professionalCredential ProfessionalCredential
-			err = json.Unmarshal(queryResponse.Value, &professionalCredential)
-			if err != nil {
-				return nil, err
-			}
-			credential = professionalCredential
-		} else {
-			// If it doesn't match either, we handle it as a base credential
-			credential = baseCredential
+		if !match(&envelope.Data) {
+			continue
+		}
+		if err := decryptCredentialFields(ctx, &envelope.Data); err != nil {
+			return nil, err
 		}
 
-		// Append the found credential to the list
-		credentials = append(credentials, credential)
+		credentials = append(credentials, envelope)
 	}
 
-	// Marshal the final array to JSON bytes
-	jsonBytes, err := json.Marshal(credentials)
-	if err != nil {
+	// Count the endorsements of all credentials in a single scan
+	if err := addEndorsementCounts(ctx, "", credentials...); err != nil {
 		return nil, err
 	}
-	return jsonBytes, nil
 
-	// return credentials, nil
+	return credentials, nil
 }
-
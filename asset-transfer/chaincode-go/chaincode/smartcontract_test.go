@@ -1,184 +1,254 @@
 package chaincode_test
 
 import (
-	"encoding/json"
-	"fmt"
+	"strings"
 	"testing"
 
-	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
 	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
-	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
 	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
-	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
 	"github.com/stretchr/testify/require"
 )
 
-//go:generate counterfeiter -o mocks/transaction.go -fake-name TransactionContext . transactionContext
-type transactionContext interface {
-	contractapi.TransactionContextInterface
+func TestCreateAcademicCredential(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+
+	credential := getCredential(t, ledger, "cred1")
+	require.Equal(t, "academic", credential.Type)
+	require.Len(t, credential.Data.CreatedBy, 64) // Hash of the registrar identity
+	require.Equal(t, chaincode.CredentialData{
+		BaseCredential: chaincode.BaseCredential{
+			CredentialID:       "cred1",
+			CredentialType:     "academic",
+			FirstName:          "Jane",
+			LastName:           "Doe",
+			Skills:             "Go, SQL",
+			TalentID:           "talent1",
+			VerificationStatus: "Pending",
+			CreatedAt:          "2024-01-01T00:00:01Z",
+			CreatedBy:          credential.Data.CreatedBy,
+		},
+		Education:   "BSc Computer Science",
+		Institution: "Concordia University",
+	}, credential.Data)
+
+	credentials := chaincode.CredentialContract{}
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := credentials.CreateAcademicCredential(ctx, "cred1", "talent1", "Jane", "Doe", "Go", "MSc Computer Science", "Concordia University")
+		return err
+	})
+	requireErrorCode(t, err, chaincode.ErrAlreadyExists)
+
+	// The talent profile holds the canonical name, which later credentials must match
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := credentials.CreateAcademicCredential(ctx, "cred2", "talent1", "John", "Doe", "Go", "MSc Computer Science", "Concordia University")
+		return err
+	})
+	requireErrorCode(t, err, chaincode.ErrConflict)
 }
 
-//go:generate counterfeiter -o mocks/chaincodestub.go -fake-name ChaincodeStub . chaincodeStub
-type chaincodeStub interface {
-	shim.ChaincodeStubInterface
+func TestCreateCredentialValidatesArguments(t *testing.T) {
+	ledger := newLedger()
+	credentials := chaincode.CredentialContract{}
+
+	for name, create := range map[string]func(ctx contractapi.TransactionContextInterface) (string, error){
+		"invalid ID": func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return credentials.CreateAcademicCredential(ctx, "cred 1", "talent1", "Jane", "Doe", "Go", "BSc Computer Science", "Concordia University")
+		},
+		"invalid name": func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return credentials.CreateAcademicCredential(ctx, "cred1", "talent1", "Jane1", "Doe", "Go", "BSc Computer Science", "Concordia University")
+		},
+		"missing institution": func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return credentials.CreateAcademicCredential(ctx, "cred1", "talent1", "Jane", "Doe", "Go", "BSc Computer Science", "")
+		},
+		"missing company": func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return credentials.CreateProfessionalCredential(ctx, "cred1", "talent1", "Jane", "Doe", "Go", "5 years", "")
+		},
+		"control characters": func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return credentials.CreateProfessionalCredential(ctx, "cred1", "talent1", "Jane", "Doe", "Go\x00", "5 years", "Acme")
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+				_, err := create(ctx)
+				return err
+			})
+			requireErrorCode(t, err, chaincode.ErrInvalidArgument)
+		})
+	}
 }
 
-//go:generate counterfeiter -o mocks/statequeryiterator.go -fake-name StateQueryIterator . stateQueryIterator
-type stateQueryIterator interface {
-	shim.StateQueryIteratorInterface
-}
-
-func TestInitLedger(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+func TestCreateCredentialAssignsID(t *testing.T) {
+	ledger := newLedger()
+	credentials := chaincode.CredentialContract{}
 
-	assetTransfer := chaincode.SmartContract{}
-	err := assetTransfer.InitLedger(transactionContext)
+	var credentialID string
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		credentialID, err = credentials.CreateProfessionalCredential(ctx, "", "talent1", "Jane", "Doe", "Go", "5 years", "Acme")
+		return err
+	})
 	require.NoError(t, err)
-
-	chaincodeStub.PutStateReturns(fmt.Errorf("failed inserting key"))
-	err = assetTransfer.InitLedger(transactionContext)
-	require.EqualError(t, err, "failed to put to world state. failed inserting key")
-}
-
-func TestCreateAsset(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
-
-	assetTransfer := chaincode.SmartContract{}
-	err := assetTransfer.CreateAsset(transactionContext, "", "", 0, "", 0)
+	require.Regexp(t, `^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, credentialID)
+	require.Equal(t, credentialID, getCredential(t, ledger, credentialID).Data.CredentialID)
+
+	// Every transaction derives a new ID
+	var nextID string
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		nextID, err = credentials.CreateProfessionalCredential(ctx, "", "talent1", "", "", "Go", "2 years", "Globex")
+		return err
+	})
 	require.NoError(t, err)
-
-	chaincodeStub.GetStateReturns([]byte{}, nil)
-	err = assetTransfer.CreateAsset(transactionContext, "asset1", "", 0, "", 0)
-	require.EqualError(t, err, "the asset asset1 already exists")
-
-	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	err = assetTransfer.CreateAsset(transactionContext, "asset1", "", 0, "", 0)
-	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
+	require.NotEqual(t, credentialID, nextID)
 }
 
-func TestReadAsset(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+func TestGetCredentialByType(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+	createProfessional(t, ledger, "cred2", "talent1")
+
+	credentials := chaincode.CredentialContract{}
+	ctx := ledger.BeginTx(recruiter)
 
-	expectedAsset := &chaincode.Asset{ID: "asset1"}
-	bytes, err := json.Marshal(expectedAsset)
+	academic, err := credentials.GetAcademicCredential(ctx, "cred1")
 	require.NoError(t, err)
+	require.Equal(t, "Concordia University", academic.Data.Institution)
+	_, err = credentials.GetAcademicCredential(ctx, "cred2")
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
 
-	chaincodeStub.GetStateReturns(bytes, nil)
-	assetTransfer := chaincode.SmartContract{}
-	asset, err := assetTransfer.ReadAsset(transactionContext, "")
+	professional, err := credentials.GetProfessionalCredential(ctx, "cred2")
 	require.NoError(t, err)
-	require.Equal(t, expectedAsset, asset)
+	require.Equal(t, "Acme", professional.Data.Company)
+	_, err = credentials.GetProfessionalCredential(ctx, "cred1")
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
 
-	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	_, err = assetTransfer.ReadAsset(transactionContext, "")
-	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
+	base, err := credentials.GetBaseCredential(ctx, "cred2")
+	require.NoError(t, err)
+	require.Equal(t, "Jane", base.Data.FirstName)
+	require.Empty(t, base.Data.Company)
 
-	chaincodeStub.GetStateReturns(nil, nil)
-	asset, err = assetTransfer.ReadAsset(transactionContext, "asset1")
-	require.EqualError(t, err, "the asset asset1 does not exist")
-	require.Nil(t, asset)
+	_, err = credentials.GetTalentCredential(ctx, "cred3")
+	requireErrorCode(t, err, chaincode.ErrNotFound)
 }
 
-func TestUpdateAsset(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+func TestCredentialExists(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
 
-	expectedAsset := &chaincode.Asset{ID: "asset1"}
-	bytes, err := json.Marshal(expectedAsset)
-	require.NoError(t, err)
+	credentials := chaincode.CredentialContract{}
+	ctx := ledger.BeginTx(recruiter)
 
-	chaincodeStub.GetStateReturns(bytes, nil)
-	assetTransfer := chaincode.SmartContract{}
-	err = assetTransfer.UpdateAsset(transactionContext, "", "", 0, "", 0)
+	exists, err := credentials.CredentialExists(ctx, "cred1")
 	require.NoError(t, err)
+	require.True(t, exists)
 
-	chaincodeStub.GetStateReturns(nil, nil)
-	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 0, "", 0)
-	require.EqualError(t, err, "the asset asset1 does not exist")
-
-	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 0, "", 0)
-	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
+	exists, err = credentials.CredentialExists(ctx, "cred2")
+	require.NoError(t, err)
+	require.False(t, exists)
 }
 
-func TestDeleteAsset(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+func TestDeleteTalentCredential(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+	createProfessional(t, ledger, "cred2", "talent1")
 
-	asset := &chaincode.Asset{ID: "asset1"}
-	bytes, err := json.Marshal(asset)
+	credentials := chaincode.CredentialContract{}
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return credentials.DeleteTalentCredential(ctx, "cred1")
+	})
 	require.NoError(t, err)
 
-	chaincodeStub.GetStateReturns(bytes, nil)
-	chaincodeStub.DelStateReturns(nil)
-	assetTransfer := chaincode.SmartContract{}
-	err = assetTransfer.DeleteAsset(transactionContext, "")
+	ctx := ledger.BeginTx(recruiter)
+	exists, err := credentials.CredentialExists(ctx, "cred1")
 	require.NoError(t, err)
+	require.False(t, exists)
 
-	chaincodeStub.GetStateReturns(nil, nil)
-	err = assetTransfer.DeleteAsset(transactionContext, "asset1")
-	require.EqualError(t, err, "the asset asset1 does not exist")
+	profile, err := (&chaincode.TalentContract{}).GetTalentProfile(ctx, "talent1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"cred2"}, profile.CredentialIDs)
 
-	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	err = assetTransfer.DeleteAsset(transactionContext, "")
-	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return credentials.DeleteTalentCredential(ctx, "cred1")
+	})
+	requireErrorCode(t, err, chaincode.ErrNotFound)
 }
 
-func TestTransferAsset(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+func TestUpdateSkills(t *testing.T) {
+	ledger := newLedger()
+	createProfessional(t, ledger, "cred1", "talent1")
 
-	asset := &chaincode.Asset{ID: "asset1"}
-	bytes, err := json.Marshal(asset)
+	credentials := chaincode.CredentialContract{}
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return credentials.UpdateSkills(ctx, "cred1", "Go, Rust")
+	})
 	require.NoError(t, err)
+	require.Equal(t, "Go, Rust", getCredential(t, ledger, "cred1").Data.Skills)
 
-	chaincodeStub.GetStateReturns(bytes, nil)
-	assetTransfer := chaincode.SmartContract{}
-	_, err = assetTransfer.TransferAsset(transactionContext, "", "")
-	require.NoError(t, err)
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return credentials.UpdateSkills(ctx, "cred1", strings.Repeat("Go, ", 200))
+	})
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
 
-	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	_, err = assetTransfer.TransferAsset(transactionContext, "", "")
-	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return credentials.UpdateSkills(ctx, "cred2", "Go")
+	})
+	requireErrorCode(t, err, chaincode.ErrNotFound)
 }
 
-func TestGetAllAssets(t *testing.T) {
-	asset := &chaincode.Asset{ID: "asset1"}
-	bytes, err := json.Marshal(asset)
-	require.NoError(t, err)
-
-	iterator := &mocks.StateQueryIterator{}
-	iterator.HasNextReturnsOnCall(0, true)
-	iterator.HasNextReturnsOnCall(1, false)
-	iterator.NextReturns(&queryresult.KV{Value: bytes}, nil)
+func TestGetAllCredentials(t *testing.T) {
+	ledger := newLedger()
 
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+	credentials := chaincode.CredentialContract{}
+	ctx := ledger.BeginTx(recruiter)
+	all, err := credentials.GetAllCredentials(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, all)
+	require.Empty(t, all)
+
+	createAcademic(t, ledger, "cred1", "talent1")
+	createProfessional(t, ledger, "cred2", "talent2")
+	err = ledger.Submit(talent1, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.EndorsementContract{}).EndorseSkill(ctx, "cred2", "Go", "")
+	})
+	require.NoError(t, err)
 
-	chaincodeStub.GetStateByRangeReturns(iterator, nil)
-	assetTransfer := &chaincode.SmartContract{}
-	assets, err := assetTransfer.GetAllAssets(transactionContext)
+	// Talent profiles, endorsements and statistics live in other namespaces and are not listed
+	ctx = ledger.BeginTx(recruiter)
+	all, err = credentials.GetAllCredentials(ctx)
 	require.NoError(t, err)
-	require.Equal(t, []*chaincode.Asset{asset}, assets)
-
-	iterator.HasNextReturns(true)
-	iterator.NextReturns(nil, fmt.Errorf("failed retrieving next item"))
-	assets, err = assetTransfer.GetAllAssets(transactionContext)
-	require.EqualError(t, err, "failed retrieving next item")
-	require.Nil(t, assets)
-
-	chaincodeStub.GetStateByRangeReturns(nil, fmt.Errorf("failed retrieving all assets"))
-	assets, err = assetTransfer.GetAllAssets(transactionContext)
-	require.EqualError(t, err, "failed retrieving all assets")
-	require.Nil(t, assets)
+	require.Len(t, all, 2)
+	require.Equal(t, "cred1", all[0].Data.CredentialID)
+	require.Empty(t, all[0].SkillEndorsements)
+	require.Equal(t, "cred2", all[1].Data.CredentialID)
+	require.Equal(t, map[string]int{"Go": 1}, all[1].SkillEndorsements)
+}
+
+func TestGetCredentialsBy(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+	createProfessional(t, ledger, "cred2", "talent1")
+	createProfessional(t, ledger, "cred3", "talent2")
+
+	credentials := chaincode.CredentialContract{}
+	ids := func(envelopes []*chaincode.CredentialEnvelope, err error) []string {
+		require.NoError(t, err)
+		ids := []string{}
+		for _, envelope := range envelopes {
+			ids = append(ids, envelope.Data.CredentialID)
+		}
+		return ids
+	}
+
+	ctx := ledger.BeginTx(recruiter)
+	require.Equal(t, []string{"cred1", "cred2"}, ids(credentials.GetCredentialsByTalent(ctx, "talent1")))
+	require.Equal(t, []string{}, ids(credentials.GetCredentialsByTalent(ctx, "talent3")))
+	require.Equal(t, []string{"cred1"}, ids(credentials.GetCredentialsByInstitution(ctx, "Concordia University")))
+	require.Equal(t, []string{}, ids(credentials.GetCredentialsByInstitution(ctx, "McGill University")))
+	require.Equal(t, []string{"cred2", "cred3"}, ids(credentials.GetCredentialsByCompany(ctx, "Acme")))
+
+	_, err := credentials.GetCredentialsByTalent(ctx, "talent 1")
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
+	_, err = credentials.GetCredentialsByCompany(ctx, "")
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
 }
@@ -0,0 +1,287 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Statistics counters are named "dimension/value". Every transaction that changes a counted
+// credential writes its own delta of each counter it changes, under a key ending with its
+// transaction ID, without reading the counter, so concurrent transactions never conflict on a
+// hot key. GetStatistics adds the deltas to the compacted counters, and CompactStatistics
+// periodically folds them in so that reads stay cheap.
+const (
+	counterType          = "type/"          // type/<credential type>
+	counterStatus        = "status/"        // status/<verification status>
+	counterIssuer        = "issuer/"        // issuer/<institution or company>/<verification status>
+	counterRevoked       = "revoked/"       // revoked/<YYYY-MM>
+	counterVerifyCount   = "verify/count"   // Number of credentials verified since their creation
+	counterVerifySeconds = "verify/seconds" // Total time between creation and verification
+)
+
+// Statistics summarizes the credentials on the ledger
+type Statistics struct {
+	ByType         map[string]int64            `json:"ByType"`         // Credentials per type
+	ByStatus       map[string]int64            `json:"ByStatus"`       // Credentials per verification status
+	ByIssuer       map[string]map[string]int64 `json:"ByIssuer"`       // Credentials per institution or company, then status
+	RevokedByMonth map[string]int64            `json:"RevokedByMonth"` // Revocations per month (YYYY-MM)
+	TimeToVerify   TimeToVerify                `json:"TimeToVerify"`
+}
+
+// TimeToVerify measures the time between the creation and the verification of credentials,
+// based on transaction timestamps
+type TimeToVerify struct {
+	Count          int64   `json:"Count"`
+	TotalSeconds   int64   `json:"TotalSeconds"`
+	AverageSeconds float64 `json:"AverageSeconds"`
+}
+
+// GetStatistics returns the credential statistics maintained by the mutating functions
+func (c *AdminContract) GetStatistics(ctx contractapi.TransactionContextInterface) (*Statistics, error) {
+	counters, deltas, _, err := readCounters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for name, delta := range deltas {
+		counters[name] += delta
+	}
+
+	statistics := Statistics{
+		ByType:         map[string]int64{},
+		ByStatus:       map[string]int64{},
+		ByIssuer:       map[string]map[string]int64{},
+		RevokedByMonth: map[string]int64{},
+	}
+	for name, value := range counters {
+		if value == 0 {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(name, counterType):
+			statistics.ByType[strings.TrimPrefix(name, counterType)] = value
+		case strings.HasPrefix(name, counterStatus):
+			statistics.ByStatus[strings.TrimPrefix(name, counterStatus)] = value
+		case strings.HasPrefix(name, counterIssuer):
+			// Issuer names may contain '/', statuses may not
+			issuerStatus := strings.TrimPrefix(name, counterIssuer)
+			separator := strings.LastIndex(issuerStatus, "/")
+			issuer, status := issuerStatus[:separator], issuerStatus[separator+1:]
+			if statistics.ByIssuer[issuer] == nil {
+				statistics.ByIssuer[issuer] = map[string]int64{}
+			}
+			statistics.ByIssuer[issuer][status] = value
+		case strings.HasPrefix(name, counterRevoked):
+			statistics.RevokedByMonth[strings.TrimPrefix(name, counterRevoked)] = value
+		}
+	}
+
+	statistics.TimeToVerify.Count = counters[counterVerifyCount]
+	statistics.TimeToVerify.TotalSeconds = counters[counterVerifySeconds]
+	if statistics.TimeToVerify.Count > 0 {
+		statistics.TimeToVerify.AverageSeconds = float64(statistics.TimeToVerify.TotalSeconds) / float64(statistics.TimeToVerify.Count)
+	}
+
+	return &statistics, nil
+}
+
+// CompactStatistics folds the statistics deltas into the compacted counters and returns the
+// number of deltas folded. Running it regularly keeps GetStatistics from reading many deltas.
+func (c *AdminContract) CompactStatistics(ctx contractapi.TransactionContextInterface) (int, error) {
+	if err := requireIssuer(ctx, "compact the statistics of"); err != nil {
+		return 0, err
+	}
+
+	counters, deltas, deltaKeys, err := readCounters(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for name, delta := range deltas {
+		key, err := ctx.GetStub().CreateCompositeKey(statisticsObjectType, []string{name})
+		if err != nil {
+			return 0, fmt.Errorf("failed to create statistics key: %v", err)
+		}
+
+		// Counters dropping to zero are deleted
+		value := counters[name] + delta
+		if value == 0 {
+			if err := ctx.GetStub().DelState(key); err != nil {
+				return 0, fmt.Errorf("failed to delete statistics counter: %v", err)
+			}
+			continue
+		}
+		if err := putCounter(ctx, key, value); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, key := range deltaKeys {
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return 0, fmt.Errorf("failed to delete statistics delta: %v", err)
+		}
+	}
+
+	return len(deltaKeys), nil
+}
+
+// readCounters returns the compacted counters, the sum of the pending deltas of each counter and
+// the keys of the deltas
+func readCounters(ctx contractapi.TransactionContextInterface) (map[string]int64, map[string]int64, []string, error) {
+	counters, _, err := readCounterRecords(ctx, statisticsObjectType)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	deltas, deltaKeys, err := readCounterRecords(ctx, statisticsDeltaObjectType)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return counters, deltas, deltaKeys, nil
+}
+
+// readCounterRecords sums the values of the records of an object type by counter, the counter
+// being the first attribute of their key, and returns their keys
+func readCounterRecords(ctx contractapi.TransactionContextInterface, objectType string) (map[string]int64, []string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resultsIterator.Close()
+
+	counters := map[string]int64{}
+	keys := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(keyParts) == 0 {
+			return nil, nil, fmt.Errorf("invalid statistics key %q: %v", queryResponse.Key, err)
+		}
+		var value int64
+		if err := json.Unmarshal(queryResponse.Value, &value); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal statistics counter: %v", err)
+		}
+		counters[keyParts[0]] += value
+		keys = append(keys, queryResponse.Key)
+	}
+
+	return counters, keys, nil
+}
+
+// putCounter writes the value of a statistics counter or delta
+func putCounter(ctx contractapi.TransactionContextInterface, key string, value int64) error {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal statistics counter: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, valueJSON); err != nil {
+		return fmt.Errorf("failed to put statistics to world state: %v", err)
+	}
+
+	return nil
+}
+
+// statisticsChange accumulates the changes of the statistics counters in a transaction. A
+// transaction writes one delta per counter, so a transaction changing several credentials adds
+// all of them before writing the deltas once.
+type statisticsChange map[string]int64
+
+// recordCredentialChange updates the statistics counters for a credential changing from before
+// to after. before is nil for a new credential and after is nil for a deleted one. It must be
+// called once per transaction, use statisticsChange for transactions changing several credentials.
+func recordCredentialChange(ctx contractapi.TransactionContextInterface, before *CredentialData, after *CredentialData) error {
+	change := statisticsChange{}
+	if err := change.add(ctx, before, after); err != nil {
+		return err
+	}
+
+	return change.write(ctx)
+}
+
+// add adds the counter changes of a credential changing from before to after
+func (change statisticsChange) add(ctx contractapi.TransactionContextInterface, before *CredentialData, after *CredentialData) error {
+	for name, value := range credentialCounters(before) {
+		change[name] -= value
+	}
+	for name, value := range credentialCounters(after) {
+		change[name] += value
+	}
+
+	if after != nil && (before == nil || before.VerificationStatus != after.VerificationStatus) {
+		timestamp, err := txTimestamp(ctx)
+		if err != nil {
+			return err
+		}
+		now, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			return fmt.Errorf("invalid transaction timestamp %s: %v", timestamp, err)
+		}
+
+		switch after.VerificationStatus {
+		case "Revoked":
+			change[counterRevoked+now.Format("2006-01")]++
+		case "Verified":
+			// Credentials created before CreatedAt existed have no measurable time to verify, and
+			// re-approvals after a name change were already measured
+			if createdAt, err := time.Parse(time.RFC3339, after.CreatedAt); err == nil && before != nil && before.VerificationStatus != statusPendingReview {
+				change[counterVerifyCount]++
+				change[counterVerifySeconds] += int64(now.Sub(createdAt).Seconds())
+			}
+		}
+	}
+
+	return nil
+}
+
+// write writes the accumulated change of each counter as a delta keyed by the transaction ID.
+// The counters are not read, so transactions changing the same counters do not conflict.
+func (change statisticsChange) write(ctx contractapi.TransactionContextInterface) error {
+	for name, delta := range change {
+		if delta == 0 {
+			continue
+		}
+
+		key, err := ctx.GetStub().CreateCompositeKey(statisticsDeltaObjectType, []string{name, ctx.GetStub().GetTxID()})
+		if err != nil {
+			return fmt.Errorf("failed to create statistics key: %v", err)
+		}
+		if err := putCounter(ctx, key, delta); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// credentialCounters returns the counters a credential contributes to
+func credentialCounters(data *CredentialData) map[string]int64 {
+	if data == nil {
+		return nil
+	}
+
+	issuer := credentialIssuer(data)
+
+	return map[string]int64{
+		counterType + data.CredentialType:                      1,
+		counterStatus + data.VerificationStatus:                1,
+		counterIssuer + issuer + "/" + data.VerificationStatus: 1,
+	}
+}
+
+// credentialIssuer returns the institution of an academic credential or the company of a
+// professional one
+func credentialIssuer(data *CredentialData) string {
+	if data.CredentialType == credentialTypeProfessional {
+		return data.Company
+	}
+
+	return data.Institution
+}
@@ -0,0 +1,126 @@
+package chaincode_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgertest"
+	"github.com/stretchr/testify/require"
+)
+
+// getStatistics reads the statistics with GetStatistics
+func getStatistics(t *testing.T, ledger *ledgertest.Stub) *chaincode.Statistics {
+	t.Helper()
+
+	var statistics *chaincode.Statistics
+	err := ledger.Evaluate(recruiter, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		statistics, err = (&chaincode.AdminContract{}).GetStatistics(ctx)
+		return err
+	})
+	require.NoError(t, err)
+	return statistics
+}
+
+func TestStatistics(t *testing.T) {
+	ledger := newLedger()
+	require.Equal(t, &chaincode.Statistics{
+		ByType:         map[string]int64{},
+		ByStatus:       map[string]int64{},
+		ByIssuer:       map[string]map[string]int64{},
+		RevokedByMonth: map[string]int64{},
+	}, getStatistics(t, ledger))
+
+	createAcademic(t, ledger, "cred1", "talent1")
+	createProfessional(t, ledger, "cred2", "talent1")
+	createProfessional(t, ledger, "cred3", "talent2")
+
+	// The academic credential is verified an hour after its creation
+	ledger.Advance(time.Hour)
+	verify(t, ledger, "cred1", "Verified")
+	verify(t, ledger, "cred2", "Revoked")
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.CredentialContract{}).DeleteTalentCredential(ctx, "cred3")
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, &chaincode.Statistics{
+		ByType:   map[string]int64{"academic": 1, "professional": 1},
+		ByStatus: map[string]int64{"Verified": 1, "Revoked": 1},
+		ByIssuer: map[string]map[string]int64{
+			"Concordia University": {"Verified": 1},
+			"Acme":                 {"Revoked": 1},
+		},
+		RevokedByMonth: map[string]int64{"2024-01": 1},
+		TimeToVerify: chaincode.TimeToVerify{
+			Count:          1,
+			TotalSeconds:   3603,
+			AverageSeconds: 3603,
+		},
+	}, getStatistics(t, ledger))
+}
+
+func TestStatisticsOfTransactionsChangingSeveralCredentials(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+	createProfessional(t, ledger, "cred2", "talent1")
+	verify(t, ledger, "cred1", "Verified")
+	verify(t, ledger, "cred2", "Verified")
+
+	// The reissue supersedes cred1 and creates cred1-v2 in one transaction
+	err := ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := (&chaincode.IssuerContract{}).ReissueCredential(ctx, "cred1", "cred1-v2", nil)
+		return err
+	})
+	require.NoError(t, err)
+	statistics := getStatistics(t, ledger)
+	require.Equal(t, map[string]int64{"academic": 2, "professional": 1}, statistics.ByType)
+	require.Equal(t, map[string]int64{"Superseded": 1, "Verified": 2}, statistics.ByStatus)
+
+	// The name change sends both verified credentials of the talent back to review
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.TalentContract{}).UpdateTalentName(ctx, "talent1", "Janet", "Doe", evidenceHash)
+	})
+	require.NoError(t, err)
+	statistics = getStatistics(t, ledger)
+	require.Equal(t, map[string]int64{"Superseded": 1, "PendingReview": 2}, statistics.ByStatus)
+}
+
+func TestCompactStatistics(t *testing.T) {
+	ledger := newLedger()
+	createAcademic(t, ledger, "cred1", "talent1")
+	createProfessional(t, ledger, "cred2", "talent1")
+	verify(t, ledger, "cred1", "Verified")
+	before := getStatistics(t, ledger)
+
+	// The deltas of the four transactions are folded in, leaving the statistics unchanged
+	compact := func(identity *ledgertest.Identity) (int, error) {
+		var folded int
+		err := ledger.Submit(identity, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			folded, err = (&chaincode.AdminContract{}).CompactStatistics(ctx)
+			return err
+		})
+		return folded, err
+	}
+	folded, err := compact(registrar)
+	require.NoError(t, err)
+	require.Greater(t, folded, 0)
+	require.Equal(t, before, getStatistics(t, ledger))
+	folded, err = compact(registrar)
+	require.NoError(t, err)
+	require.Zero(t, folded)
+
+	// Later deltas add to the compacted counters, which drop out at zero
+	verify(t, ledger, "cred2", "Revoked")
+	statistics := getStatistics(t, ledger)
+	require.Equal(t, map[string]int64{"Verified": 1, "Revoked": 1}, statistics.ByStatus)
+	_, err = compact(registrar)
+	require.NoError(t, err)
+	require.Equal(t, statistics, getStatistics(t, ledger))
+
+	_, err = compact(recruiter)
+	requireErrorCode(t, err, chaincode.ErrForbidden)
+}
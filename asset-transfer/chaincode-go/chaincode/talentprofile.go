@@ -0,0 +1,261 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// TalentContract provides functions for managing talent profiles
+type TalentContract struct {
+	contractapi.Contract
+}
+
+// GetEvaluateTransactions lists the read-only functions, which are tagged "evaluate" in the
+// contract metadata so that clients query them instead of submitting transactions
+func (c *TalentContract) GetEvaluateTransactions() []string {
+	return []string{
+		"TalentProfileExists",
+		"GetTalentProfile",
+	}
+}
+
+// TalentProfile is the canonical record of a talent, shared by all of their credentials
+type TalentProfile struct {
+	TalentID      string   `json:"TalentID"`      // Talent identifier
+	FirstName     string   `json:"FirstName"`     // Canonical first name, copied onto every credential of the talent
+	LastName      string   `json:"LastName"`      // Canonical last name, copied onto every credential of the talent
+	ContactHash   string   `json:"ContactHash"`   // Hash of the talent's contact details (the details themselves stay off-chain)
+	CredentialIDs []string `json:"CredentialIDs"` // IDs of the credentials issued to the talent
+	// Number of the talent's credentials awaiting verification, kept by the functions changing
+	// their status so that the pending quota does not read every credential
+	PendingCredentials int `json:"PendingCredentials"`
+}
+
+// TalentProfileDetails is a talent profile together with its resolved credentials
+type TalentProfileDetails struct {
+	TalentProfile
+	Credentials []*CredentialEnvelope `json:"Credentials"`
+}
+
+// talentProfileKey returns the world state key of a talent profile
+func talentProfileKey(ctx contractapi.TransactionContextInterface, talentID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(talentProfileObjectType, []string{talentID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create talent profile key: %v", err)
+	}
+
+	return key, nil
+}
+
+// CreateTalentProfile registers a new talent profile
+func (c *TalentContract) CreateTalentProfile(ctx contractapi.TransactionContextInterface, talentID string, firstName string, lastName string, contactHash string) error {
+	if err := validateID("talentID", talentID); err != nil {
+		return err
+	}
+	if err := validateName("firstName", firstName); err != nil {
+		return err
+	}
+	if err := validateName("lastName", lastName); err != nil {
+		return err
+	}
+	if err := validateContactHash(contactHash); err != nil {
+		return err
+	}
+
+	exists, err := c.TalentProfileExists(ctx, talentID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return newError(ErrAlreadyExists, "the talent profile %s already exists", talentID)
+	}
+
+	profile := TalentProfile{
+		TalentID:      talentID,
+		FirstName:     firstName,
+		LastName:      lastName,
+		ContactHash:   contactHash,
+		CredentialIDs: []string{},
+	}
+
+	return putTalentProfile(ctx, &profile)
+}
+
+// TalentProfileExists returns true when the talent profile with given ID exists in world state
+func (c *TalentContract) TalentProfileExists(ctx contractapi.TransactionContextInterface, talentID string) (bool, error) {
+	if err := validateID("talentID", talentID); err != nil {
+		return false, err
+	}
+
+	key, err := talentProfileKey(ctx, talentID)
+	if err != nil {
+		return false, err
+	}
+
+	profileJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	return profileJSON != nil, nil
+}
+
+// GetTalentProfile retrieves a talent profile along with all of its credentials
+func (c *TalentContract) GetTalentProfile(ctx contractapi.TransactionContextInterface, talentID string) (*TalentProfileDetails, error) {
+	if err := validateID("talentID", talentID); err != nil {
+		return nil, err
+	}
+
+	profile, err := readTalentProfile(ctx, talentID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, newError(ErrNotFound, "the talent profile %s does not exist", talentID)
+	}
+
+	details := TalentProfileDetails{
+		TalentProfile: *profile,
+		Credentials:   []*CredentialEnvelope{},
+	}
+	for _, credentialID := range profile.CredentialIDs {
+		credential, err := readCredential(ctx, credentialID)
+		if err != nil {
+			return nil, err
+		}
+		if err := addEndorsementCounts(ctx, credentialID, credential); err != nil {
+			return nil, err
+		}
+		details.Credentials = append(details.Credentials, credential)
+	}
+
+	return &details, nil
+}
+
+// UpdateTalentName changes the name of a talent once, on the profile, and carries it over to all of their credentials.
+// evidenceHash is the SHA-256 of the document supporting the change; verified credentials need to be re-approved.
+func (c *TalentContract) UpdateTalentName(ctx contractapi.TransactionContextInterface, talentID string, newFirstName string, newLastName string, evidenceHash string) error {
+	if err := validateID("talentID", talentID); err != nil {
+		return err
+	}
+	if err := validateName("newFirstName", newFirstName); err != nil {
+		return err
+	}
+	if err := validateName("newLastName", newLastName); err != nil {
+		return err
+	}
+
+	profile, err := readTalentProfile(ctx, talentID)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return newError(ErrNotFound, "the talent profile %s does not exist", talentID)
+	}
+
+	return renameTalent(ctx, profile, newFirstName, newLastName, evidenceHash)
+}
+
+// readTalentProfile returns the talent profile with given ID, or nil if it does not exist
+func readTalentProfile(ctx contractapi.TransactionContextInterface, talentID string) (*TalentProfile, error) {
+	key, err := talentProfileKey(ctx, talentID)
+	if err != nil {
+		return nil, err
+	}
+
+	profileJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if profileJSON == nil {
+		return nil, nil
+	}
+
+	var profile TalentProfile
+	err = json.Unmarshal(profileJSON, &profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal talent profile: %v", err)
+	}
+	if profile.CredentialIDs == nil {
+		profile.CredentialIDs = []string{}
+	}
+
+	return &profile, nil
+}
+
+// putTalentProfile writes a talent profile to world state
+func putTalentProfile(ctx contractapi.TransactionContextInterface, profile *TalentProfile) error {
+	key, err := talentProfileKey(ctx, profile.TalentID)
+	if err != nil {
+		return err
+	}
+
+	profileJSON, err := json.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal talent profile: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(key, profileJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put talent profile to world state. %v", err)
+	}
+
+	return nil
+}
+
+// linkCredentialToProfile attaches a new credential to the profile of its talent.
+// The profile is created from the given name if the talent does not have one yet,
+// otherwise the given name must match the canonical one.
+func linkCredentialToProfile(ctx contractapi.TransactionContextInterface, talentID string, credentialID string, firstName string, lastName string) (*TalentProfile, error) {
+	profile, err := readTalentProfile(ctx, talentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile == nil {
+		if firstName == "" || lastName == "" {
+			return nil, newError(ErrInvalidArgument, "firstName and lastName are required for talent %s, who has no profile yet", talentID)
+		}
+		profile = &TalentProfile{
+			TalentID:      talentID,
+			FirstName:     firstName,
+			LastName:      lastName,
+			CredentialIDs: []string{},
+		}
+	} else if (firstName != "" && firstName != profile.FirstName) || (lastName != "" && lastName != profile.LastName) {
+		return nil, newError(ErrConflict, "the name %s %s does not match the talent profile %s, use UpdateTalentName to change it", firstName, lastName, talentID)
+	}
+
+	// New credentials are pending
+	profile.CredentialIDs = append(profile.CredentialIDs, credentialID)
+	profile.countPending(1)
+	if err := putTalentProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// unlinkCredentialFromProfile detaches a deleted credential from the profile of its talent
+func unlinkCredentialFromProfile(ctx contractapi.TransactionContextInterface, credential *CredentialData) error {
+	profile, err := readTalentProfile(ctx, credential.TalentID)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return nil
+	}
+
+	credentialIDs := []string{}
+	for _, id := range profile.CredentialIDs {
+		if id != credential.CredentialID {
+			credentialIDs = append(credentialIDs, id)
+		}
+	}
+	profile.CredentialIDs = credentialIDs
+	profile.countPending(pendingChange(credential, nil))
+
+	return putTalentProfile(ctx, profile)
+}
@@ -0,0 +1,76 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+const contactHash = "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+func TestCreateTalentProfile(t *testing.T) {
+	ledger := newLedger()
+	talents := chaincode.TalentContract{}
+
+	err := ledger.Submit(talent1, func(ctx contractapi.TransactionContextInterface) error {
+		return talents.CreateTalentProfile(ctx, "talent1", "Jane", "Doe", contactHash)
+	})
+	require.NoError(t, err)
+
+	ctx := ledger.BeginTx(recruiter)
+	exists, err := talents.TalentProfileExists(ctx, "talent1")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	profile, err := talents.GetTalentProfile(ctx, "talent1")
+	require.NoError(t, err)
+	require.Equal(t, chaincode.TalentProfile{
+		TalentID:      "talent1",
+		FirstName:     "Jane",
+		LastName:      "Doe",
+		ContactHash:   contactHash,
+		CredentialIDs: []string{},
+	}, profile.TalentProfile)
+	require.Empty(t, profile.Credentials)
+
+	err = ledger.Submit(talent1, func(ctx contractapi.TransactionContextInterface) error {
+		return talents.CreateTalentProfile(ctx, "talent1", "Jane", "Doe", "")
+	})
+	requireErrorCode(t, err, chaincode.ErrAlreadyExists)
+
+	err = ledger.Submit(talent1, func(ctx contractapi.TransactionContextInterface) error {
+		return talents.CreateTalentProfile(ctx, "talent2", "Jane", "Doe", "not-a-hash")
+	})
+	requireErrorCode(t, err, chaincode.ErrInvalidArgument)
+}
+
+func TestGetTalentProfileResolvesCredentials(t *testing.T) {
+	ledger := newLedger()
+	talents := chaincode.TalentContract{}
+
+	err := ledger.Submit(talent1, func(ctx contractapi.TransactionContextInterface) error {
+		return talents.CreateTalentProfile(ctx, "talent1", "Jane", "Doe", "")
+	})
+	require.NoError(t, err)
+
+	// Credentials of a talent with a profile take the name from the profile
+	err = ledger.Submit(registrar, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := (&chaincode.CredentialContract{}).CreateAcademicCredential(ctx, "cred1", "talent1", "", "", "Go", "BSc Computer Science", "Concordia University")
+		return err
+	})
+	require.NoError(t, err)
+	createProfessional(t, ledger, "cred2", "talent1")
+
+	ctx := ledger.BeginTx(recruiter)
+	profile, err := talents.GetTalentProfile(ctx, "talent1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"cred1", "cred2"}, profile.CredentialIDs)
+	require.Len(t, profile.Credentials, 2)
+	require.Equal(t, "Doe", profile.Credentials[0].Data.LastName)
+	require.Equal(t, "Acme", profile.Credentials[1].Data.Company)
+
+	_, err = talents.GetTalentProfile(ctx, "talent2")
+	requireErrorCode(t, err, chaincode.ErrNotFound)
+}
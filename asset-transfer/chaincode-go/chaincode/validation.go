@@ -0,0 +1,139 @@
+package chaincode
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Validation limits for transaction arguments
+const (
+	maxIDLength   = 64
+	maxNameLength = 100
+	maxTextLength = 512
+	hashLength    = 64
+)
+
+var (
+	// IDs start with a letter or digit and only contain URL-safe characters
+	idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]*$`)
+	// Names are letters (any script) separated by spaces, apostrophes, hyphens or periods
+	namePattern = regexp.MustCompile(`^[\p{L}\p{M}][\p{L}\p{M}' .-]*$`)
+	// Contact and evidence hashes are hex-encoded SHA-256 digests
+	hashPattern = regexp.MustCompile(`^[0-9a-f]+$`)
+)
+
+// verificationStatuses are the statuses a credential can be given through UpdateVerificationStatus
+var verificationStatuses = map[string]bool{
+	"Pending":  true,
+	"Verified": true,
+	"Revoked":  true,
+}
+
+// validateID checks an identifier such as a credential or talent ID
+func validateID(field string, value string) error {
+	if value == "" {
+		return newError(ErrInvalidArgument, "%s is required", field)
+	}
+	if len(value) > maxIDLength {
+		return newError(ErrInvalidArgument, "%s must be at most %d characters", field, maxIDLength)
+	}
+	if !idPattern.MatchString(value) {
+		return newError(ErrInvalidArgument, "%s %q may only contain letters, digits, '.', '_', ':' and '-'", field, value)
+	}
+
+	return nil
+}
+
+// validateName checks a first or last name
+func validateName(field string, value string) error {
+	if value == "" {
+		return newError(ErrInvalidArgument, "%s is required", field)
+	}
+	if utf8.RuneCountInString(value) > maxNameLength {
+		return newError(ErrInvalidArgument, "%s must be at most %d characters", field, maxNameLength)
+	}
+	if !namePattern.MatchString(value) {
+		return newError(ErrInvalidArgument, "%s %q may only contain letters, spaces, apostrophes, hyphens and periods", field, value)
+	}
+
+	return nil
+}
+
+// validateText checks a free-text field. Empty values are only accepted when the field is optional.
+func validateText(field string, value string, required bool) error {
+	if value == "" {
+		if required {
+			return newError(ErrInvalidArgument, "%s is required", field)
+		}
+		return nil
+	}
+	if !utf8.ValidString(value) {
+		return newError(ErrInvalidArgument, "%s must be valid UTF-8", field)
+	}
+	if utf8.RuneCountInString(value) > maxTextLength {
+		return newError(ErrInvalidArgument, "%s must be at most %d characters", field, maxTextLength)
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return newError(ErrInvalidArgument, "%s must not contain control characters", field)
+		}
+	}
+
+	return nil
+}
+
+// validateContactHash checks an optional hex-encoded SHA-256 contact hash
+func validateContactHash(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) != hashLength || !hashPattern.MatchString(value) {
+		return newError(ErrInvalidArgument, "contactHash must be a lowercase hex-encoded SHA-256 digest")
+	}
+
+	return nil
+}
+
+// validateEvidenceHash checks the required hex-encoded SHA-256 hash of the document supporting
+// a name change
+func validateEvidenceHash(value string) error {
+	if value == "" {
+		return newError(ErrInvalidArgument, "evidenceHash is required")
+	}
+	if len(value) != hashLength || !hashPattern.MatchString(value) {
+		return newError(ErrInvalidArgument, "evidenceHash must be a lowercase hex-encoded SHA-256 digest")
+	}
+
+	return nil
+}
+
+// validateVerificationStatus checks a credential verification status
+func validateVerificationStatus(status string) error {
+	if !verificationStatuses[status] {
+		return newError(ErrInvalidArgument, "status %q is not one of Pending, Verified, Revoked", status)
+	}
+
+	return nil
+}
+
+// validateCredentialFields checks the fields shared by the credential creation functions
+func validateCredentialFields(credentialID string, talentID string, firstName string, lastName string, skills string) error {
+	if err := validateID("credentialID", credentialID); err != nil {
+		return err
+	}
+	if err := validateID("talentID", talentID); err != nil {
+		return err
+	}
+	// The name may be omitted when the talent already has a profile
+	if firstName != "" || lastName != "" {
+		if err := validateName("firstName", firstName); err != nil {
+			return err
+		}
+		if err := validateName("lastName", lastName); err != nil {
+			return err
+		}
+	}
+
+	return validateText("skills", skills, false)
+}
@@ -44,7 +44,7 @@ function CredentialForm({ baseUrl }) {
       };
 
       const res = await axios.post(endpoint, payload);
-      alert(`Success: ${res.data.message}`);
+      alert(`Success: ${res.data.message} (${res.data.data.credentialId})`);
     } catch (err) {
       alert(`Error: ${err.response?.data?.error || "Unknown error"}`);
     }
@@ -67,7 +67,7 @@ function CredentialForm({ baseUrl }) {
         </button>
       </div>
 
-      <input type="text" name="id" placeholder="Credential ID" className="input" onChange={handleChange} />
+      <input type="text" name="id" placeholder="Credential ID (optional, assigned by the ledger if empty)" className="input" onChange={handleChange} />
       <input type="text" name="firstName" placeholder="First Name" className="input" onChange={handleChange} />
       <input type="text" name="lastName" placeholder="Last Name" className="input" onChange={handleChange} />
       <input type="text" name="talentID" placeholder="Talent ID" className="input" onChange={handleChange} />
@@ -38,7 +38,8 @@ function CredentialList({ baseUrl }) {
         } else {
           parsedData = [];
         }
-        setCredentials(parsedData);
+        // Credentials come wrapped in a typed envelope: { type, schemaVersion, data }
+        setCredentials(parsedData.map((envelope) => envelope.data ?? envelope));
       } else {
         throw new Error(res.data?.error || "API returned unsuccessful response");
       }
@@ -123,11 +124,14 @@ function CredentialList({ baseUrl }) {
     const newFirst = prompt("New first name:", currentFirst);
     const newLast = prompt("New last name:", currentLast);
     if (!newFirst || !newLast || (newFirst === currentFirst && newLast === currentLast)) return;
+    const evidenceHash = prompt("SHA-256 of the supporting document (e.g. legal name-change certificate):");
+    if (!evidenceHash) return;
 
     try {
       await axios.put(`${baseUrl}/credentials/${id}/name`, {
         newFirstName: newFirst,
         newLastName: newLast,
+        evidenceHash: evidenceHash.trim().toLowerCase(),
         chaincodeid: "basic",
         channelid: "mychannel"
       });
@@ -213,6 +217,7 @@ function CredentialList({ baseUrl }) {
             <option value="all">All</option>
             <option value="Verified">Verified</option>
             <option value="Pending">Pending</option>
+            <option value="PendingReview">Pending review</option>
           </select>
         </div>
         <div className="flex gap-2 ml-auto">
@@ -271,6 +276,7 @@ function CredentialList({ baseUrl }) {
                     <span className={`px-2 py-1 rounded text-sm ${
                       cred.VerificationStatus === "Verified" ? "bg-green-100 text-green-800" : 
                       cred.VerificationStatus === "Pending" ? "bg-yellow-100 text-yellow-800" : 
+                      cred.VerificationStatus === "PendingReview" ? "bg-orange-100 text-orange-800" : 
                       "bg-gray-100 text-gray-800"
                     }`}>
                       {cred.VerificationStatus}
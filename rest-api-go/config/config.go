@@ -0,0 +1,612 @@
+// Package config loads the settings of the REST server of an organization from a JSON config
+// file, environment variables and command-line flags, in increasing order of precedence.
+//
+// Settings left empty are completed from the Fabric connection profile, when one is given, and
+// then from the layout of the test network: the certificate, key and TLS CA certificate paths
+// are derived from the crypto path of the organization, as generated by cryptogen.
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"rest-api-go/web"
+)
+
+// Defaults of the settings, which start the server of Org1 on the test network
+const (
+	DefaultMSPID        = "Org1MSP"
+	DefaultCryptoPath   = "../talent-credentials-network/organizations/peerOrganizations/org1.example.com"
+	DefaultUser         = "User1"
+	DefaultPeerEndpoint = "dns:///localhost:7051"
+	DefaultChannelID    = "mychannel"
+	DefaultChaincodeID  = "basic"
+)
+
+// DefaultAllowedFunctions are the chaincode functions reachable through the generated
+// /functions routes, unless overridden
+var DefaultAllowedFunctions = []string{
+	"credentials:CreateAcademicCredential",
+	"credentials:CreateProfessionalCredential",
+	"credentials:CredentialExists",
+	"credentials:GetAllCredentials",
+	"credentials:GetCredentialsByCompany",
+	"credentials:GetCredentialsByInstitution",
+	"credentials:GetCredentialsByTalent",
+	"credentials:GetTalentCredential",
+	"credentials:UpdateSkills",
+	"endorsements:EndorseSkill",
+	"endorsements:GetEndorsements",
+	"talents:CreateTalentProfile",
+	"talents:GetTalentProfile",
+	"talents:TalentProfileExists",
+	"talents:UpdateTalentName",
+}
+
+// Duration is a time.Duration written as "5s" or "1m30s" in config files
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5s\": %w", err)
+	}
+	return (*durationValue)(d).Set(s)
+}
+
+// Timeouts bounds the Gateway calls, zero fields taking the web.DefaultTimeouts
+type Timeouts struct {
+	Evaluate     Duration `json:"evaluate"`
+	Endorse      Duration `json:"endorse"`
+	Submit       Duration `json:"submit"`
+	CommitStatus Duration `json:"commitStatus"`
+}
+
+// Resilience configures the retries, circuit breakers, reconnections and number of the Gateway
+// connections, zero fields taking the web.DefaultResilience
+type Resilience struct {
+	EvaluateAttempts  Count    `json:"evaluateAttempts"`
+	RetryDelay        Duration `json:"retryDelay"`
+	BreakerFailures   Count    `json:"breakerFailures"`
+	BreakerTimeout    Duration `json:"breakerTimeout"`
+	ReconnectMaxDelay Duration `json:"reconnectMaxDelay"`
+	MaxGateways       Count    `json:"maxGateways"`
+}
+
+// Count is a positive number of attempts or failures
+type Count int
+
+// UnmarshalJSON parses a positive number
+func (c *Count) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("count must be a number: %w", err)
+	}
+	return (*countValue)(c).Set(fmt.Sprint(n))
+}
+
+// ClientConfig holds a client identity and the peer it connects to
+type ClientConfig struct {
+	MSPID string `json:"mspId"`
+
+	// CryptoPath is the directory of the organization in the crypto material of the network,
+	// from which the paths below are derived when left empty
+	CryptoPath  string `json:"cryptoPath"`
+	User        string `json:"user"` // User whose certificate and key are derived from CryptoPath
+	CertPath    string `json:"certPath"`
+	KeyPath     string `json:"keyPath"` // Directory holding the private key
+	TLSCertPath string `json:"tlsCertPath"`
+
+	PeerEndpoint string `json:"peerEndpoint"`
+	GatewayPeer  string `json:"gatewayPeer"` // Host name expected in the TLS certificate of the peer
+	// ConnectionProfile is a connection profile in the format of organizations/ccp-template.json
+	ConnectionProfile string `json:"connectionProfile"`
+
+	// tlsCertPEM is the TLS CA certificate found inline in the connection profile
+	tlsCertPEM []byte
+}
+
+// IdentityConfig is a further identity requests can be made as, selected by its name
+type IdentityConfig struct {
+	Name string `json:"name"` // Defaults to the MSP ID without its "MSP" suffix
+	ClientConfig
+}
+
+// AuthConfig configures the authentication of requests with JWT bearer tokens, enabled by
+// setting the issuer
+type AuthConfig struct {
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+	JWKSURL  string `json:"jwksUrl"` // Defaults to the jwks_uri of the OpenID discovery document of the issuer
+
+	RolesClaim    string `json:"rolesClaim"`    // Defaults to "roles"
+	IdentityClaim string `json:"identityClaim"` // Claim naming the identity the requests are made as
+	// RoleIdentities maps roles to the identity the requests of their holders are made as
+	RoleIdentities map[string]string `json:"roleIdentities"`
+}
+
+// CAConfig locates the Fabric CA of the organization, which registers and enrolls the users of
+// the wallet on behalf of its registrar
+type CAConfig struct {
+	URL             string `json:"url"`
+	CAName          string `json:"caName"`
+	TLSCertPath     string `json:"tlsCertPath"` // TLS CA certificate of the CA server
+	RegistrarID     string `json:"registrarId"` // Defaults to "admin"
+	RegistrarSecret string `json:"registrarSecret"`
+
+	// tlsCertPEM is the TLS CA certificate found inline in the connection profile
+	tlsCertPEM []byte
+}
+
+// Config holds the settings of the REST server of an organization
+type Config struct {
+	OrgName string `json:"orgName"` // Defaults to the MSP ID without its "MSP" suffix
+	ClientConfig
+
+	ListenAddress string   `json:"listenAddress"`
+	Timeouts      Timeouts `json:"timeouts"`
+	// ShutdownTimeout bounds the draining of the in-flight requests on shutdown
+	ShutdownTimeout Duration   `json:"shutdownTimeout"`
+	Resilience      Resilience `json:"resilience"`
+
+	ChannelID        string   `json:"channelId"`
+	ChaincodeID      string   `json:"chaincodeId"`
+	AllowedFunctions []string `json:"allowedFunctions"`
+	// FieldEncryptionKeyStore is the directory of the field encryption keys, if any
+	FieldEncryptionKeyStore string `json:"fieldEncryptionKeystore"`
+
+	// Auth enables the authentication of requests with bearer tokens, when it has an issuer
+	Auth AuthConfig `json:"auth"`
+
+	// WalletPath is the directory of the identities of the users enrolled through the API,
+	// encrypted with the base64-encoded AES key of WalletKeyFile. It enables the /users routes.
+	WalletPath    string   `json:"walletPath"`
+	WalletKeyFile string   `json:"walletKeyFile"`
+	CA            CAConfig `json:"ca"`
+
+	// Identities are the further identities requests can be made as, through the token claims
+	// or the X-Fabric-Identity header. They are only read from the config file.
+	Identities []IdentityConfig `json:"identities"`
+}
+
+// setting is a configuration entry settable from an environment variable and a flag
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	value func(config *Config) flag.Value
+}
+
+// settings lists the entries of Config with their flag and environment variable
+var settings = []setting{
+	{"org-name", "ORG_NAME", "organization name", func(c *Config) flag.Value { return (*stringValue)(&c.OrgName) }},
+	{"msp-id", "MSP_ID", "MSP ID of the organization", func(c *Config) flag.Value { return (*stringValue)(&c.MSPID) }},
+	{"crypto-path", "CRYPTO_PATH", "crypto material directory of the organization", func(c *Config) flag.Value { return (*stringValue)(&c.CryptoPath) }},
+	{"user", "FABRIC_USER", "user whose certificate and key are read from the crypto path", func(c *Config) flag.Value { return (*stringValue)(&c.User) }},
+	{"cert-path", "CERT_PATH", "client certificate file", func(c *Config) flag.Value { return (*stringValue)(&c.CertPath) }},
+	{"key-path", "KEY_PATH", "directory of the client private key", func(c *Config) flag.Value { return (*stringValue)(&c.KeyPath) }},
+	{"tls-cert-path", "TLS_CERT_PATH", "TLS CA certificate file of the peer", func(c *Config) flag.Value { return (*stringValue)(&c.TLSCertPath) }},
+	{"peer-endpoint", "PEER_ENDPOINT", "gRPC endpoint of the Gateway peer", func(c *Config) flag.Value { return (*stringValue)(&c.PeerEndpoint) }},
+	{"gateway-peer", "GATEWAY_PEER", "host name in the TLS certificate of the peer", func(c *Config) flag.Value { return (*stringValue)(&c.GatewayPeer) }},
+	{"connection-profile", "CONNECTION_PROFILE", "Fabric connection profile (JSON)", func(c *Config) flag.Value { return (*stringValue)(&c.ConnectionProfile) }},
+	{"listen", "LISTEN_ADDRESS", "address the HTTP server listens on", func(c *Config) flag.Value { return (*stringValue)(&c.ListenAddress) }},
+	{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "time allowed to drain the in-flight requests on shutdown", func(c *Config) flag.Value { return (*durationValue)(&c.ShutdownTimeout) }},
+	{"evaluate-timeout", "EVALUATE_TIMEOUT", "timeout of evaluations", func(c *Config) flag.Value { return (*durationValue)(&c.Timeouts.Evaluate) }},
+	{"endorse-timeout", "ENDORSE_TIMEOUT", "timeout of endorsements", func(c *Config) flag.Value { return (*durationValue)(&c.Timeouts.Endorse) }},
+	{"submit-timeout", "SUBMIT_TIMEOUT", "timeout of submissions to the orderer", func(c *Config) flag.Value { return (*durationValue)(&c.Timeouts.Submit) }},
+	{"commit-status-timeout", "COMMIT_STATUS_TIMEOUT", "timeout of waiting for commits", func(c *Config) flag.Value { return (*durationValue)(&c.Timeouts.CommitStatus) }},
+	{"evaluate-attempts", "EVALUATE_ATTEMPTS", "attempts of evaluations failing with Unavailable", func(c *Config) flag.Value { return (*countValue)(&c.Resilience.EvaluateAttempts) }},
+	{"retry-delay", "RETRY_DELAY", "delay before retrying an evaluation, doubled on each retry", func(c *Config) flag.Value { return (*durationValue)(&c.Resilience.RetryDelay) }},
+	{"breaker-failures", "BREAKER_FAILURES", "consecutive Unavailable failures opening the circuit breaker of a peer", func(c *Config) flag.Value { return (*countValue)(&c.Resilience.BreakerFailures) }},
+	{"breaker-timeout", "BREAKER_TIMEOUT", "time the circuit breaker of a peer stays open", func(c *Config) flag.Value { return (*durationValue)(&c.Resilience.BreakerTimeout) }},
+	{"reconnect-max-delay", "RECONNECT_MAX_DELAY", "maximum delay between the connection attempts to a peer", func(c *Config) flag.Value { return (*durationValue)(&c.Resilience.ReconnectMaxDelay) }},
+	{"max-gateways", "MAX_GATEWAYS", "Gateway connections kept open, the least recently used idle one being closed", func(c *Config) flag.Value { return (*countValue)(&c.Resilience.MaxGateways) }},
+	{"channel", "CHANNEL_ID", "channel of the chaincode", func(c *Config) flag.Value { return (*stringValue)(&c.ChannelID) }},
+	{"chaincode", "CHAINCODE_ID", "name of the chaincode", func(c *Config) flag.Value { return (*stringValue)(&c.ChaincodeID) }},
+	{"allowed-functions", "ALLOWED_FUNCTIONS", "comma-separated allowlist of the generated routes", func(c *Config) flag.Value { return (*listValue)(&c.AllowedFunctions) }},
+	{"auth-issuer", "AUTH_ISSUER", "issuer of the bearer tokens, enabling authentication", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.Issuer) }},
+	{"auth-audience", "AUTH_AUDIENCE", "audience expected in the bearer tokens", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.Audience) }},
+	{"auth-jwks-url", "AUTH_JWKS_URL", "JWKS of the issuer, instead of its discovery document", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.JWKSURL) }},
+	{"auth-roles-claim", "AUTH_ROLES_CLAIM", "claim holding the roles of the caller", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.RolesClaim) }},
+	{"auth-identity-claim", "AUTH_IDENTITY_CLAIM", "claim naming the identity the requests are made as", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.IdentityClaim) }},
+	{"wallet-path", "WALLET_PATH", "directory of the wallet of the enrolled users", func(c *Config) flag.Value { return (*stringValue)(&c.WalletPath) }},
+	{"wallet-key-file", "WALLET_KEY_FILE", "file holding the AES key of the wallet", func(c *Config) flag.Value { return (*stringValue)(&c.WalletKeyFile) }},
+	{"ca-url", "CA_URL", "URL of the Fabric CA of the organization", func(c *Config) flag.Value { return (*stringValue)(&c.CA.URL) }},
+	{"ca-name", "CA_NAME", "name of the CA on the Fabric CA server", func(c *Config) flag.Value { return (*stringValue)(&c.CA.CAName) }},
+	{"ca-tls-cert-path", "CA_TLS_CERT_PATH", "TLS CA certificate file of the Fabric CA", func(c *Config) flag.Value { return (*stringValue)(&c.CA.TLSCertPath) }},
+	{"ca-registrar-id", "CA_REGISTRAR_ID", "enrollment ID of the registrar", func(c *Config) flag.Value { return (*stringValue)(&c.CA.RegistrarID) }},
+	{"ca-registrar-secret", "CA_REGISTRAR_SECRET", "enrollment secret of the registrar", func(c *Config) flag.Value { return (*stringValue)(&c.CA.RegistrarSecret) }},
+	{"field-encryption-keystore", "FIELD_ENCRYPTION_KEYSTORE", "directory of the field encryption keys", func(c *Config) flag.Value { return (*stringValue)(&c.FieldEncryptionKeyStore) }},
+}
+
+// configFileFlag and configFileEnv name the config file
+const (
+	configFileFlag = "config"
+	configFileEnv  = "REST_CONFIG"
+)
+
+// newFlagSet creates the flags of the settings, writing to config
+func newFlagSet(name string, config *Config, configFile *string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(configFile, configFileFlag, "", "JSON config file (env "+configFileEnv+")")
+	for _, s := range settings {
+		flags.Var(s.value(config), s.flag, s.usage+" (env "+s.env+")")
+	}
+
+	return flags
+}
+
+// Load reads the configuration from the config file named by -config or REST_CONFIG, the
+// environment variables and the command-line arguments, then completes it
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	// The flags are parsed twice: first to find the config file, then over the file and
+	// environment settings, which they override
+	var configFile string
+	if err := newFlagSet("rest-api", &Config{}, &configFile).Parse(args); err != nil {
+		return nil, err
+	}
+	if configFile == "" {
+		configFile = getenv(configFileEnv)
+	}
+
+	config := &Config{}
+	if configFile != "" {
+		if err := config.readFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if env := getenv(s.env); env != "" {
+			if err := s.value(config).Set(env); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", s.env, err)
+			}
+		}
+	}
+
+	if err := newFlagSet("rest-api", config, &configFile).Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := config.complete(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// readFile reads a JSON config file
+func (config *Config) readFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", filename, err)
+	}
+
+	return nil
+}
+
+// DefaultRegistrarID is the enrollment ID of the bootstrap admin of the CAs of the test network
+const DefaultRegistrarID = "admin"
+
+// complete fills the empty settings from the connection profile, then from the defaults
+func (config *Config) complete() error {
+	// The CA of the profile is only used with a wallet
+	if config.WalletPath == "" && config.CA.URL != "" {
+		return errors.New("the CA settings require a wallet path")
+	}
+
+	if config.ConnectionProfile != "" {
+		profile, err := LoadConnectionProfile(config.ConnectionProfile)
+		if err != nil {
+			return err
+		}
+		if err := config.applyProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	if config.MSPID == "" {
+		config.MSPID = DefaultMSPID
+	}
+	if config.OrgName == "" {
+		config.OrgName = strings.TrimSuffix(config.MSPID, "MSP")
+	}
+	if config.User == "" {
+		config.User = DefaultUser
+	}
+	if config.PeerEndpoint == "" {
+		config.PeerEndpoint = DefaultPeerEndpoint
+	}
+	if config.ListenAddress == "" {
+		config.ListenAddress = web.DefaultListenAddress
+	}
+	if config.ChannelID == "" {
+		config.ChannelID = DefaultChannelID
+	}
+	if config.ChaincodeID == "" {
+		config.ChaincodeID = DefaultChaincodeID
+	}
+	if len(config.AllowedFunctions) == 0 {
+		config.AllowedFunctions = DefaultAllowedFunctions
+	}
+
+	if config.CryptoPath == "" && !config.hasCredentials() {
+		config.CryptoPath = DefaultCryptoPath
+	}
+	config.derivePaths()
+
+	names := map[string]bool{config.OrgName: true}
+	for i := range config.Identities {
+		identity := &config.Identities[i]
+		if err := identity.complete(); err != nil {
+			return err
+		}
+		if names[identity.Name] {
+			return fmt.Errorf("identity %s is configured twice", identity.Name)
+		}
+		names[identity.Name] = true
+	}
+
+	if config.Auth.Issuer == "" && (config.Auth.Audience != "" || config.Auth.JWKSURL != "" || config.Auth.RolesClaim != "" || config.Auth.IdentityClaim != "" || len(config.Auth.RoleIdentities) > 0) {
+		return errors.New("authentication settings require an issuer")
+	}
+	if config.WalletPath != "" {
+		if config.WalletKeyFile == "" || config.CA.URL == "" || config.CA.RegistrarSecret == "" {
+			return errors.New("the wallet requires a key file, a CA URL and a registrar secret")
+		}
+		if config.CA.RegistrarID == "" {
+			config.CA.RegistrarID = DefaultRegistrarID
+		}
+	}
+
+	for role, name := range config.Auth.RoleIdentities {
+		if !names[name] {
+			return fmt.Errorf("role %s is mapped to unknown identity %s", role, name)
+		}
+	}
+
+	return nil
+}
+
+// complete fills the empty settings of a further identity, which has no defaults but the user
+func (identity *IdentityConfig) complete() error {
+	if identity.ConnectionProfile != "" {
+		profile, err := LoadConnectionProfile(identity.ConnectionProfile)
+		if err != nil {
+			return err
+		}
+		orgName, err := identity.applyProfile(profile)
+		if err != nil {
+			return err
+		}
+		if identity.Name == "" {
+			identity.Name = orgName
+		}
+	}
+
+	if identity.MSPID == "" {
+		return errors.New("identity without MSP ID")
+	}
+	if identity.Name == "" {
+		identity.Name = strings.TrimSuffix(identity.MSPID, "MSP")
+	}
+	if identity.User == "" {
+		identity.User = DefaultUser
+	}
+	identity.derivePaths()
+
+	if identity.PeerEndpoint == "" || !identity.hasCredentials() {
+		return fmt.Errorf("identity %s needs a peer endpoint, and a crypto path or certificate, key and TLS CA certificate paths", identity.Name)
+	}
+
+	return nil
+}
+
+// hasCredentials tells whether the certificate, key and TLS CA certificate of the client are all set
+func (client *ClientConfig) hasCredentials() bool {
+	return client.CertPath != "" && client.KeyPath != "" && (client.TLSCertPath != "" || client.tlsCertPEM != nil)
+}
+
+// derivePaths fills the empty paths from the crypto path, as laid out by cryptogen, e.g. for
+// org1.example.com: users/User1@org1.example.com/msp/signcerts/User1@org1.example.com-cert.pem
+func (client *ClientConfig) derivePaths() {
+	if client.CryptoPath == "" {
+		return
+	}
+	domain := filepath.Base(client.CryptoPath)
+	userMSP := filepath.Join(client.CryptoPath, "users", client.User+"@"+domain, "msp")
+	if client.GatewayPeer == "" {
+		client.GatewayPeer = "peer0." + domain
+	}
+	if client.CertPath == "" {
+		client.CertPath = filepath.Join(userMSP, "signcerts", client.User+"@"+domain+"-cert.pem")
+	}
+	if client.KeyPath == "" {
+		client.KeyPath = filepath.Join(userMSP, "keystore")
+	}
+	if client.TLSCertPath == "" && client.tlsCertPEM == nil {
+		client.TLSCertPath = filepath.Join(client.CryptoPath, "peers", client.GatewayPeer, "msp", "tlscacerts", "tlsca."+domain+"-cert.pem")
+	}
+}
+
+// OrgSetup returns the setup of the organization, loading its field encryption keystore
+func (config *Config) OrgSetup() (web.OrgSetup, error) {
+	setup := web.OrgSetup{
+		OrgName:      config.OrgName,
+		MSPID:        config.MSPID,
+		CryptoPath:   config.CryptoPath,
+		CertPath:     config.CertPath,
+		KeyPath:      config.KeyPath,
+		TLSCertPath:  config.TLSCertPath,
+		TLSCertPEM:   config.tlsCertPEM,
+		PeerEndpoint: config.PeerEndpoint,
+		GatewayPeer:  config.GatewayPeer,
+		Timeouts: web.Timeouts{
+			Evaluate:     time.Duration(config.Timeouts.Evaluate),
+			Endorse:      time.Duration(config.Timeouts.Endorse),
+			Submit:       time.Duration(config.Timeouts.Submit),
+			CommitStatus: time.Duration(config.Timeouts.CommitStatus),
+		},
+		Resilience: web.Resilience{
+			EvaluateAttempts:  int(config.Resilience.EvaluateAttempts),
+			RetryDelay:        time.Duration(config.Resilience.RetryDelay),
+			BreakerFailures:   int(config.Resilience.BreakerFailures),
+			BreakerTimeout:    time.Duration(config.Resilience.BreakerTimeout),
+			ReconnectMaxDelay: time.Duration(config.Resilience.ReconnectMaxDelay),
+			MaxGateways:       int(config.Resilience.MaxGateways),
+		},
+		ListenAddress:   config.ListenAddress,
+		ShutdownTimeout: time.Duration(config.ShutdownTimeout),
+
+		ChannelID:        config.ChannelID,
+		ChaincodeID:      config.ChaincodeID,
+		AllowedFunctions: config.AllowedFunctions,
+	}
+	for _, identity := range config.Identities {
+		setup.Identities = append(setup.Identities, web.Identity{
+			Name:         identity.Name,
+			MSPID:        identity.MSPID,
+			CertPath:     identity.CertPath,
+			KeyPath:      identity.KeyPath,
+			TLSCertPath:  identity.TLSCertPath,
+			TLSCertPEM:   identity.tlsCertPEM,
+			PeerEndpoint: identity.PeerEndpoint,
+			GatewayPeer:  identity.GatewayPeer,
+		})
+	}
+
+	if config.Auth.Issuer != "" {
+		setup.Auth = &web.AuthConfig{
+			Issuer:         config.Auth.Issuer,
+			Audience:       config.Auth.Audience,
+			JWKSURL:        config.Auth.JWKSURL,
+			RolesClaim:     config.Auth.RolesClaim,
+			IdentityClaim:  config.Auth.IdentityClaim,
+			RoleIdentities: config.Auth.RoleIdentities,
+		}
+	}
+
+	if config.WalletPath != "" {
+		if err := config.setupUsers(&setup); err != nil {
+			return web.OrgSetup{}, err
+		}
+	}
+
+	// Field encryption is enabled for the organization when the keystore holds its key
+	if config.FieldEncryptionKeyStore != "" {
+		keyStore, err := web.LoadKeyStore(config.FieldEncryptionKeyStore)
+		if err != nil {
+			return web.OrgSetup{}, fmt.Errorf("failed to load the field encryption keystore: %w", err)
+		}
+		setup.KeyStore = keyStore
+	}
+
+	return setup, nil
+}
+
+// setupUsers opens the wallet and sets up the client of the CA
+func (config *Config) setupUsers(setup *web.OrgSetup) error {
+	key, err := web.LoadWalletKey(config.WalletKeyFile)
+	if err != nil {
+		return err
+	}
+	wallet, err := web.NewFileWallet(config.WalletPath, key)
+	if err != nil {
+		return err
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	tlsCertPEM := config.CA.tlsCertPEM
+	if config.CA.TLSCertPath != "" {
+		if tlsCertPEM, err = os.ReadFile(config.CA.TLSCertPath); err != nil {
+			return fmt.Errorf("failed to read the TLS certificate of the CA: %w", err)
+		}
+	}
+	if tlsCertPEM != nil {
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(tlsCertPEM) {
+			return errors.New("invalid TLS certificate of the CA")
+		}
+		httpClient.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}}
+	}
+
+	setup.Wallet = wallet
+	setup.CA = &web.CAClient{URL: config.CA.URL, CAName: config.CA.CAName, HTTPClient: httpClient}
+	setup.Registrar = web.Registrar{EnrollmentID: config.CA.RegistrarID, Secret: config.CA.RegistrarSecret}
+
+	return nil
+}
+
+// stringValue is a flag.Value setting a string
+type stringValue string
+
+func (v *stringValue) String() string { return string(*v) }
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+// durationValue is a flag.Value setting a Duration
+type durationValue Duration
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if d <= 0 {
+		return errors.New("duration must be positive")
+	}
+
+	*v = durationValue(d)
+	return nil
+}
+
+// countValue is a flag.Value setting a Count
+type countValue Count
+
+func (v *countValue) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *countValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	if n <= 0 {
+		return errors.New("count must be positive")
+	}
+
+	*v = countValue(n)
+	return nil
+}
+
+// listValue is a flag.Value setting a comma-separated list
+type listValue []string
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+
+func (v *listValue) Set(s string) error {
+	*v = strings.Split(s, ",")
+	return nil
+}
@@ -0,0 +1,279 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"rest-api-go/config"
+	"rest-api-go/web"
+)
+
+// env returns a getenv function reading from a map
+func env(vars map[string]string) func(string) string {
+	return func(key string) string {
+		return vars[key]
+	}
+}
+
+// writeConfig writes a config file in a temporary directory
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o600))
+	return filename
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := config.Load(nil, env(nil))
+	require.NoError(t, err)
+
+	setup, err := cfg.OrgSetup()
+	require.NoError(t, err)
+	cryptoPath := config.DefaultCryptoPath
+	require.Equal(t, web.OrgSetup{
+		OrgName:          "Org1",
+		MSPID:            "Org1MSP",
+		CryptoPath:       cryptoPath,
+		CertPath:         cryptoPath + "/users/User1@org1.example.com/msp/signcerts/User1@org1.example.com-cert.pem",
+		KeyPath:          cryptoPath + "/users/User1@org1.example.com/msp/keystore",
+		TLSCertPath:      cryptoPath + "/peers/peer0.org1.example.com/msp/tlscacerts/tlsca.org1.example.com-cert.pem",
+		PeerEndpoint:     "dns:///localhost:7051",
+		GatewayPeer:      "peer0.org1.example.com",
+		ListenAddress:    ":3000",
+		ChannelID:        "mychannel",
+		ChaincodeID:      "basic",
+		AllowedFunctions: config.DefaultAllowedFunctions,
+	}, setup)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	filename := writeConfig(t, `{
+		"mspId": "Org2MSP",
+		"cryptoPath": "crypto/org2.example.com",
+		"peerEndpoint": "dns:///localhost:9051",
+		"listenAddress": ":3001",
+		"timeouts": {"evaluate": "2s", "endorse": "20s"},
+		"shutdownTimeout": "45s",
+		"allowedFunctions": ["*"]
+	}`)
+
+	// The environment overrides the file, and the flags override both
+	cfg, err := config.Load(
+		[]string{"-config", filename, "-listen", ":4000", "-endorse-timeout", "30s"},
+		env(map[string]string{
+			"LISTEN_ADDRESS":    ":3500",
+			"SUBMIT_TIMEOUT":    "10s",
+			"FABRIC_USER":       "Admin",
+			"ALLOWED_FUNCTIONS": "credentials:GetAllCredentials,talents:GetTalentProfile",
+		}),
+	)
+	require.NoError(t, err)
+
+	setup, err := cfg.OrgSetup()
+	require.NoError(t, err)
+	require.Equal(t, "Org2", setup.OrgName)
+	require.Equal(t, "Org2MSP", setup.MSPID)
+	require.Equal(t, "crypto/org2.example.com/users/Admin@org2.example.com/msp/signcerts/Admin@org2.example.com-cert.pem", setup.CertPath)
+	require.Equal(t, "peer0.org2.example.com", setup.GatewayPeer)
+	require.Equal(t, "dns:///localhost:9051", setup.PeerEndpoint)
+	require.Equal(t, ":4000", setup.ListenAddress)
+	require.Equal(t, web.Timeouts{Evaluate: 2 * time.Second, Endorse: 30 * time.Second, Submit: 10 * time.Second}, setup.Timeouts)
+	require.Equal(t, 45*time.Second, setup.ShutdownTimeout)
+	require.Equal(t, []string{"credentials:GetAllCredentials", "talents:GetTalentProfile"}, setup.AllowedFunctions)
+}
+
+func TestLoadResilience(t *testing.T) {
+	filename := writeConfig(t, `{"resilience": {"evaluateAttempts": 5, "breakerTimeout": "30s"}}`)
+
+	cfg, err := config.Load([]string{"-config", filename, "-breaker-failures", "2"}, env(map[string]string{"RECONNECT_MAX_DELAY": "1m"}))
+	require.NoError(t, err)
+
+	setup, err := cfg.OrgSetup()
+	require.NoError(t, err)
+	require.Equal(t, web.Resilience{EvaluateAttempts: 5, BreakerFailures: 2, BreakerTimeout: 30 * time.Second, ReconnectMaxDelay: time.Minute}, setup.Resilience)
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	filename := writeConfig(t, `{"channelId": "credentials"}`)
+
+	cfg, err := config.Load(nil, env(map[string]string{"REST_CONFIG": filename}))
+	require.NoError(t, err)
+	require.Equal(t, "credentials", cfg.ChannelID)
+}
+
+func TestLoadConnectionProfile(t *testing.T) {
+	cfg, err := config.Load([]string{"-connection-profile", "testdata/connection-org2.json", "-crypto-path", "crypto/org2.example.com"}, env(nil))
+	require.NoError(t, err)
+
+	setup, err := cfg.OrgSetup()
+	require.NoError(t, err)
+	require.Equal(t, "Org2", setup.OrgName)
+	require.Equal(t, "Org2MSP", setup.MSPID)
+	require.Equal(t, "dns:///localhost:9051", setup.PeerEndpoint)
+	require.Equal(t, "peer0.org2.example.com", setup.GatewayPeer)
+	require.Equal(t, "-----BEGIN CERTIFICATE-----\nMIICFjCCAb2gAwIBAgIUTEST\n-----END CERTIFICATE-----\n", string(setup.TLSCertPEM))
+	require.Empty(t, setup.TLSCertPath)
+	require.Equal(t, 300*time.Second, setup.Timeouts.Endorse)
+	require.Equal(t, "crypto/org2.example.com/users/User1@org2.example.com/msp/keystore", setup.KeyPath)
+
+	// Explicit settings take precedence over the profile
+	cfg, err = config.Load([]string{"-connection-profile", "testdata/connection-org2.json", "-peer-endpoint", "dns:///peer0.org2.example.com:9051", "-tls-cert-path", "tlsca.pem"}, env(nil))
+	require.NoError(t, err)
+	setup, err = cfg.OrgSetup()
+	require.NoError(t, err)
+	require.Equal(t, "dns:///peer0.org2.example.com:9051", setup.PeerEndpoint)
+	require.Equal(t, "tlsca.pem", setup.TLSCertPath)
+	require.Nil(t, setup.TLSCertPEM)
+}
+
+func TestLoadIdentities(t *testing.T) {
+	filename := writeConfig(t, `{
+		"identities": [
+			{"connectionProfile": "testdata/connection-org2.json", "cryptoPath": "crypto/org2.example.com"},
+			{"name": "Org1Admin", "mspId": "Org1MSP", "user": "Admin", "cryptoPath": "crypto/org1.example.com", "peerEndpoint": "dns:///localhost:7051"}
+		]
+	}`)
+
+	cfg, err := config.Load([]string{"-config", filename}, env(nil))
+	require.NoError(t, err)
+
+	setup, err := cfg.OrgSetup()
+	require.NoError(t, err)
+	require.Equal(t, "Org1", setup.OrgName)
+	require.Equal(t, []web.Identity{
+		{
+			Name:         "Org2",
+			MSPID:        "Org2MSP",
+			CertPath:     "crypto/org2.example.com/users/User1@org2.example.com/msp/signcerts/User1@org2.example.com-cert.pem",
+			KeyPath:      "crypto/org2.example.com/users/User1@org2.example.com/msp/keystore",
+			TLSCertPEM:   []byte("-----BEGIN CERTIFICATE-----\nMIICFjCCAb2gAwIBAgIUTEST\n-----END CERTIFICATE-----\n"),
+			PeerEndpoint: "dns:///localhost:9051",
+			GatewayPeer:  "peer0.org2.example.com",
+		},
+		{
+			Name:         "Org1Admin",
+			MSPID:        "Org1MSP",
+			CertPath:     "crypto/org1.example.com/users/Admin@org1.example.com/msp/signcerts/Admin@org1.example.com-cert.pem",
+			KeyPath:      "crypto/org1.example.com/users/Admin@org1.example.com/msp/keystore",
+			TLSCertPath:  "crypto/org1.example.com/peers/peer0.org1.example.com/msp/tlscacerts/tlsca.org1.example.com-cert.pem",
+			PeerEndpoint: "dns:///localhost:7051",
+			GatewayPeer:  "peer0.org1.example.com",
+		},
+	}, setup.Identities)
+}
+
+func TestLoadAuth(t *testing.T) {
+	filename := writeConfig(t, `{
+		"auth": {
+			"issuer": "https://issuer.example.com/realms/credentials",
+			"rolesClaim": "realm_access.roles",
+			"roleIdentities": {"org2-member": "Org2"}
+		},
+		"identities": [{"mspId": "Org2MSP", "cryptoPath": "crypto/org2.example.com", "peerEndpoint": "dns:///localhost:9051"}]
+	}`)
+
+	cfg, err := config.Load([]string{"-config", filename, "-auth-audience", "credentials-api"}, env(nil))
+	require.NoError(t, err)
+	setup, err := cfg.OrgSetup()
+	require.NoError(t, err)
+	require.Equal(t, &web.AuthConfig{
+		Issuer:         "https://issuer.example.com/realms/credentials",
+		Audience:       "credentials-api",
+		RolesClaim:     "realm_access.roles",
+		RoleIdentities: map[string]string{"org2-member": "Org2"},
+	}, setup.Auth)
+
+	// Authentication is disabled without an issuer
+	cfg, err = config.Load(nil, env(nil))
+	require.NoError(t, err)
+	setup, err = cfg.OrgSetup()
+	require.NoError(t, err)
+	require.Nil(t, setup.Auth)
+}
+
+func TestLoadWallet(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "wallet.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="), 0o600))
+
+	// The CA of the profile is used with a wallet
+	cfg, err := config.Load([]string{
+		"-connection-profile", "testdata/connection-org2.json",
+		"-wallet-path", filepath.Join(dir, "wallet"),
+		"-wallet-key-file", keyFile,
+	}, env(map[string]string{"CA_REGISTRAR_SECRET": "adminpw"}))
+	require.NoError(t, err)
+	require.Equal(t, config.CAConfig{URL: "https://localhost:8054", CAName: "ca-org2", RegistrarID: "admin", RegistrarSecret: "adminpw"}, withoutTLSCert(cfg.CA))
+
+	// The inline TLS certificate of the profile is not a valid certificate
+	_, err = cfg.OrgSetup()
+	require.Error(t, err)
+
+	cfg, err = config.Load([]string{
+		"-wallet-path", filepath.Join(dir, "wallet"),
+		"-wallet-key-file", keyFile,
+		"-ca-url", "http://localhost:7054",
+		"-ca-name", "ca-org1",
+		"-ca-registrar-secret", "adminpw",
+	}, env(nil))
+	require.NoError(t, err)
+	setup, err := cfg.OrgSetup()
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:7054", setup.CA.URL)
+	require.Equal(t, "ca-org1", setup.CA.CAName)
+	require.Equal(t, web.Registrar{EnrollmentID: "admin", Secret: "adminpw"}, setup.Registrar)
+	require.NotNil(t, setup.Wallet)
+
+	// Without a wallet, the CA of the profile is ignored
+	cfg, err = config.Load([]string{"-connection-profile", "testdata/connection-org2.json"}, env(nil))
+	require.NoError(t, err)
+	setup, err = cfg.OrgSetup()
+	require.NoError(t, err)
+	require.Nil(t, setup.CA)
+	require.Nil(t, setup.Wallet)
+}
+
+// withoutTLSCert returns the CA settings without the inline TLS certificate, which tests cannot set
+func withoutTLSCert(ca config.CAConfig) config.CAConfig {
+	return config.CAConfig{URL: ca.URL, CAName: ca.CAName, TLSCertPath: ca.TLSCertPath, RegistrarID: ca.RegistrarID, RegistrarSecret: ca.RegistrarSecret}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for name, test := range map[string]struct {
+		args []string
+		env  map[string]string
+	}{
+		"missing file":                        {args: []string{"-config", "testdata/missing.json"}},
+		"unknown field":                       {args: []string{"-config", writeConfig(t, `{"port": 3000}`)}},
+		"file duration":                       {args: []string{"-config", writeConfig(t, `{"timeouts": {"submit": 5}}`)}},
+		"flag duration":                       {args: []string{"-submit-timeout", "5"}},
+		"env duration":                        {env: map[string]string{"COMMIT_STATUS_TIMEOUT": "-1m"}},
+		"file count":                          {args: []string{"-config", writeConfig(t, `{"resilience": {"evaluateAttempts": -1}}`)}},
+		"flag count":                          {args: []string{"-breaker-failures", "0"}},
+		"env count":                           {env: map[string]string{"EVALUATE_ATTEMPTS": "three"}},
+		"unknown flag":                        {args: []string{"-port", "3000"}},
+		"missing profile":                     {args: []string{"-connection-profile", "testdata/missing.json"}},
+		"profile without client organization": {args: []string{"-connection-profile", writeConfig(t, `{"client": {"organization": "Org3"}}`)}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := config.Load(test.args, env(test.env))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestOrgSetupLoadsKeyStore(t *testing.T) {
+	_, err := (&config.Config{FieldEncryptionKeyStore: "testdata/missing"}).OrgSetup()
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Org1MSP.key"), []byte("MDEyMzQ1Njc4OWFiY2RlZg=="), 0o600))
+	setup, err := (&config.Config{ClientConfig: config.ClientConfig{MSPID: "Org1MSP"}, FieldEncryptionKeyStore: dir}).OrgSetup()
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789abcdef"), setup.KeyStore.Key("Org1MSP"))
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConnectionProfile is the part of a Fabric connection profile, as generated from
+// organizations/ccp-template.json by ccp-generate.sh, read by the REST server
+type ConnectionProfile struct {
+	Name   string `json:"name"`
+	Client struct {
+		Organization string `json:"organization"`
+		Connection   struct {
+			Timeout struct {
+				Peer struct {
+					Endorser string `json:"endorser"` // Seconds
+				} `json:"peer"`
+			} `json:"timeout"`
+		} `json:"connection"`
+	} `json:"client"`
+	Organizations map[string]struct {
+		MSPID                  string   `json:"mspid"`
+		Peers                  []string `json:"peers"`
+		CertificateAuthorities []string `json:"certificateAuthorities"`
+	} `json:"organizations"`
+	Peers map[string]struct {
+		URL        string `json:"url"`
+		TLSCACerts struct {
+			PEM  string `json:"pem"`
+			Path string `json:"path"`
+		} `json:"tlsCACerts"`
+		GRPCOptions map[string]interface{} `json:"grpcOptions"`
+	} `json:"peers"`
+	CertificateAuthorities map[string]struct {
+		URL        string `json:"url"`
+		CAName     string `json:"caName"`
+		TLSCACerts struct {
+			PEM  pemList `json:"pem"`
+			Path string  `json:"path"`
+		} `json:"tlsCACerts"`
+	} `json:"certificateAuthorities"`
+}
+
+// pemList holds PEM certificates given as one string or a list of strings
+type pemList []string
+
+// UnmarshalJSON accepts a string or a list of strings
+func (list *pemList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*list = pemList{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(list))
+}
+
+// LoadConnectionProfile reads a JSON connection profile
+func LoadConnectionProfile(filename string) (*ConnectionProfile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connection profile: %w", err)
+	}
+
+	var profile ConnectionProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("invalid connection profile %s: %w", filename, err)
+	}
+
+	return &profile, nil
+}
+
+// applyProfile fills the empty settings with the organization of the client in the profile
+// and its first peer
+func (config *Config) applyProfile(profile *ConnectionProfile) error {
+	orgName, err := config.ClientConfig.applyProfile(profile)
+	if err != nil {
+		return err
+	}
+	if config.OrgName == "" {
+		config.OrgName = orgName
+	}
+
+	if err := config.CA.applyProfile(profile); err != nil {
+		return err
+	}
+
+	if config.Timeouts.Endorse == 0 && profile.Client.Connection.Timeout.Peer.Endorser != "" {
+		seconds, err := strconv.Atoi(strings.TrimSpace(profile.Client.Connection.Timeout.Peer.Endorser))
+		if err != nil || seconds <= 0 {
+			return fmt.Errorf("invalid endorser timeout %q in connection profile %s", profile.Client.Connection.Timeout.Peer.Endorser, profile.Name)
+		}
+		config.Timeouts.Endorse = Duration(time.Duration(seconds) * time.Second)
+	}
+
+	return nil
+}
+
+// applyProfile fills the empty client settings with the first peer of the client organization
+// in the profile, and returns the name of that organization
+func (client *ClientConfig) applyProfile(profile *ConnectionProfile) (string, error) {
+	org, ok := profile.Organizations[profile.Client.Organization]
+	if !ok {
+		return "", fmt.Errorf("connection profile %s does not define the client organization %q", profile.Name, profile.Client.Organization)
+	}
+	if len(org.Peers) == 0 {
+		return "", fmt.Errorf("connection profile %s lists no peer for %s", profile.Name, profile.Client.Organization)
+	}
+	peerName := org.Peers[0]
+	peer, ok := profile.Peers[peerName]
+	if !ok {
+		return "", fmt.Errorf("connection profile %s does not define peer %s", profile.Name, peerName)
+	}
+
+	if client.MSPID == "" {
+		client.MSPID = org.MSPID
+	}
+
+	if client.PeerEndpoint == "" {
+		endpoint, err := url.Parse(peer.URL)
+		if err != nil || endpoint.Host == "" {
+			return "", fmt.Errorf("invalid URL %q of peer %s", peer.URL, peerName)
+		}
+		client.PeerEndpoint = "dns:///" + endpoint.Host
+	}
+
+	if client.GatewayPeer == "" {
+		client.GatewayPeer = peerName
+		if override, ok := peer.GRPCOptions["ssl-target-name-override"].(string); ok && override != "" {
+			client.GatewayPeer = override
+		}
+	}
+
+	if client.TLSCertPath == "" {
+		if peer.TLSCACerts.PEM != "" {
+			client.tlsCertPEM = []byte(peer.TLSCACerts.PEM)
+		} else {
+			client.TLSCertPath = peer.TLSCACerts.Path
+		}
+	}
+
+	return profile.Client.Organization, nil
+}
+
+// applyProfile fills the empty CA settings with the first CA of the client organization in the
+// profile, if it lists any
+func (ca *CAConfig) applyProfile(profile *ConnectionProfile) error {
+	org := profile.Organizations[profile.Client.Organization]
+	if len(org.CertificateAuthorities) == 0 {
+		return nil
+	}
+	caName := org.CertificateAuthorities[0]
+	authority, ok := profile.CertificateAuthorities[caName]
+	if !ok {
+		return fmt.Errorf("connection profile %s does not define CA %s", profile.Name, caName)
+	}
+
+	if ca.URL == "" {
+		ca.URL = authority.URL
+	}
+	if ca.CAName == "" {
+		ca.CAName = authority.CAName
+	}
+	if ca.TLSCertPath == "" {
+		if len(authority.TLSCACerts.PEM) > 0 {
+			ca.tlsCertPEM = []byte(strings.Join(authority.TLSCACerts.PEM, "\n"))
+		} else {
+			ca.TLSCertPath = authority.TLSCACerts.Path
+		}
+	}
+
+	return nil
+}
@@ -1,51 +1,41 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"rest-api-go/config"
 	"rest-api-go/web"
+	"syscall"
 )
 
 func main() {
-	// Initialize setup for Org1
-	cryptoPath := "../../talent-credentials-network/organizations/peerOrganizations/org1.example.com"
-	orgConfig := web.OrgSetup{
-		OrgName:      "Org1",
-		MSPID:        "Org1MSP",
-		CertPath:     cryptoPath + "/users/User1@org1.example.com/msp/signcerts/User1@org1.example.com-cert.pem",
-		KeyPath:      cryptoPath + "/users/User1@org1.example.com/msp/keystore/",
-		TLSCertPath:  cryptoPath + "/peers/peer0.org1.example.com/msp/tlscacerts/tlsca.org1.example.com-cert.pem",
-		PeerEndpoint: "dns:///localhost:7051",
-		GatewayPeer:  "peer0.org1.example.com",
+	// Load the setup of the organization from the config file, environment and flags
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+
+	orgConfig, err := cfg.OrgSetup()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
 	}
 
 	orgSetup, err := web.Initialize(orgConfig)
 	if err != nil {
-		log.Fatalf("Error initializing setup for Org1: %s", err)
+		log.Fatalf("Error initializing setup for %s: %s", orgConfig.OrgName, err)
 	}
 
-	fmt.Println("Server running at http://localhost:3000/")
-	web.Serve(*orgSetup)
+	// Serve until interrupted or terminated, then drain the requests and close the connections
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := web.Serve(ctx, orgSetup); err != nil {
+		log.Fatalf("Error serving %s: %s", orgSetup.OrgName, err)
+	}
 }
-
-// func main() {
-// 	// Initialize setup for Org2
-// 	cryptoPath := "../../talent-credentials-network/organizations/peerOrganizations/org2.example.com"
-// 	orgConfig := web.OrgSetup{
-// 		OrgName:      "Org2",
-// 		MSPID:        "Org2MSP",
-// 		CertPath:     cryptoPath + "/users/User1@org2.example.com/msp/signcerts/User1@org2.example.com-cert.pem",
-// 		KeyPath:      cryptoPath + "/users/User1@org2.example.com/msp/keystore/",
-// 		TLSCertPath:  cryptoPath + "/peers/peer0.org2.example.com/msp/tlscacerts/tlsca.org2.example.com-cert.pem",
-// 		PeerEndpoint: "dns:///localhost:9051",
-// 		GatewayPeer:  "peer0.org2.example.com",
-// 	}
-
-// 	orgSetup, err := web.Initialize(orgConfig)
-// 	if err != nil {
-// 		log.Fatalf("Error initializing setup for Org2: %s", err)
-// 	}
-
-// 	fmt.Println("Server running at http://localhost:3000/")
-// 	web.Serve(*orgSetup)
-// }
\ No newline at end of file
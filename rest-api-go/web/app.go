@@ -1,13 +1,15 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/hyperledger/fabric-gateway/pkg/client"
 )
 
 // OrgSetup contains organization's config to interact with the network.
@@ -20,7 +22,52 @@ type OrgSetup struct {
 	TLSCertPath  string
 	PeerEndpoint string
 	GatewayPeer  string
-	Gateway      client.Gateway
+	// TLSCertPEM is the TLS CA certificate of the peer, used instead of TLSCertPath when set,
+	// e.g. when it comes inline from a connection profile
+	TLSCertPEM []byte
+	// Timeouts bounds the Gateway calls, zero fields taking the DefaultTimeouts
+	Timeouts Timeouts
+	// Resilience configures the retries, circuit breakers and reconnections of the Gateway
+	// connections, zero fields taking the DefaultResilience
+	Resilience Resilience
+	// ListenAddress is the address the HTTP server listens on, ":3000" by default
+	ListenAddress string
+	// ShutdownTimeout bounds the draining of the in-flight requests on shutdown,
+	// DefaultShutdownTimeout if zero
+	ShutdownTimeout time.Duration
+	// Contracts provides the chaincode contracts, through the Gateway connection made by Initialize
+	Contracts ContractProvider
+	// Identities are the other identities requests can be made as, e.g. of other organizations
+	Identities []Identity
+	// Clients are the identities requests can be made as, by name, including the organization's
+	Clients map[string]*Client
+	// Gateways holds the Gateway connections of the identities
+	Gateways *GatewayPool
+
+	// ChannelID and ChaincodeID locate the chaincode whose metadata drives the /functions routes
+	ChannelID   string
+	ChaincodeID string
+	// AllowedFunctions lists the chaincode functions reachable through the generated routes and
+	// the custom query endpoint, as "contract:function", bare "function" names, or "*" for all
+	AllowedFunctions []string
+	// KeyStore holds the keys passed to the chaincode to encrypt and decrypt credential fields
+	KeyStore *KeyStore
+	// Auth enables the authentication of requests with bearer tokens, when set
+	Auth *AuthConfig
+	// MSPRoles are the roles of unauthenticated requests by MSP ID, DefaultMSPRoles if nil; they
+	// do not grant the admin role, which requires authentication
+	MSPRoles map[string][]string
+	// CA registers and enrolls the users of the organization, with the identity of Registrar
+	CA        *CAClient
+	Registrar Registrar
+	// Wallet stores the identities of the enrolled users, by user ID
+	Wallet *Wallet
+
+	// Metrics are served on /metrics and measure the requests and transactions, when set
+	Metrics *Metrics
+
+	// stopping is closed when the server starts shutting down
+	stopping chan struct{}
 }
 
 // APIResponse standardizes the API response format
@@ -29,6 +76,7 @@ type APIResponse struct {
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"` // Machine-readable chaincode error code, e.g. NOT_FOUND
 }
 
 // CORSMiddleware adds CORS headers to enable cross-origin requests
@@ -37,7 +85,8 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*") // In production, specify your frontend domain instead of *
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key, X-Fabric-Identity")
+		w.Header().Set("Access-Control-Expose-Headers", "Location")
 		
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
@@ -50,12 +99,20 @@ func CORSMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// Serve starts http web server with proper routing
-func Serve(setup OrgSetup) {
+// NewRouter creates the handler of all routes, wrapped in the CORS middleware
+func NewRouter(setup *OrgSetup) http.Handler {
 	router := mux.NewRouter()
 
+	// Enroll a registered user into the wallet (POST). It creates the identity of the caller,
+	// rather than acting as one.
+	router.HandleFunc("/users/enroll", setup.EnrollUserHandler).Methods("POST")
+
+	// The other routes resolve the identity of each request
+	api := router.NewRoute().Subrouter()
+	api.Use(setup.IdentityMiddleware)
+
 	// Credentials routes
-	credentials := router.PathPrefix("/credentials").Subrouter()
+	credentials := api.PathPrefix("/credentials").Subrouter()
 	
 	// Create academic credential (POST)
 	credentials.HandleFunc("/academic", setup.CreateAcademicCredentialHandler).Methods("POST")
@@ -69,6 +126,9 @@ func Serve(setup OrgSetup) {
 	// Revoke credential (PUT)
 	credentials.HandleFunc("/{id}/revoke", setup.RevokeCredentialHandler).Methods("PUT")
 	
+	// Reissue credential with corrections (POST)
+	credentials.HandleFunc("/{id}/reissue", setup.ReissueCredentialHandler).Methods("POST")
+	
 	// Delete credential (DELETE)
 	credentials.HandleFunc("/{id}", setup.DeleteCredentialHandler).Methods("DELETE")
 
@@ -78,8 +138,11 @@ func Serve(setup OrgSetup) {
 	// Update name (PUT)
 	credentials.HandleFunc("/{id}/name", setup.UpdateNameHandler).Methods("PUT")
 
-	// Get credential by type (GET) - supports ?type=academic|professional|base
-	credentials.HandleFunc("/{id}", setup.GetCredentialByTypeHandler).Methods("GET")
+	// Endorse a skill of a professional credential (POST)
+	credentials.HandleFunc("/{id}/skills/{skill}/endorsements", setup.EndorseSkillHandler).Methods("POST")
+
+	// List the skill endorsements of a credential (GET)
+	credentials.HandleFunc("/{id}/endorsements", setup.GetEndorsementsHandler).Methods("GET")
 
 	// Get all credentials (GET)
 	credentials.HandleFunc("/all", setup.GetAllCredentialsHandler).Methods("GET")
@@ -89,28 +152,120 @@ func Serve(setup OrgSetup) {
 	
 	// Custom query with function and args (GET)
 	credentials.HandleFunc("/query", setup.CustomQueryHandler).Methods("GET")
+
+	// Get credential by type (GET) - supports ?type=academic|professional|base.
+	// Registered after /all and /query, which it would match otherwise.
+	credentials.HandleFunc("/{id}", setup.GetCredentialByTypeHandler).Methods("GET")
 	
-	// Apply CORS middleware to all routes
-	corsRouter := CORSMiddleware(router)
-	
+	// Talent profile routes
+	talents := api.PathPrefix("/talents").Subrouter()
+
+	// Create talent profile (POST)
+	talents.HandleFunc("", setup.CreateTalentProfileHandler).Methods("POST")
+
+	// Get talent profile with its credentials (GET)
+	talents.HandleFunc("/{talentId}", setup.GetTalentProfileHandler).Methods("GET")
+
+	// Update talent name on the profile and all its credentials (PUT)
+	talents.HandleFunc("/{talentId}/name", setup.UpdateTalentNameHandler).Methods("PUT")
+
+	// Register a user with the CA of the organization (POST)
+	api.HandleFunc("/users/register", setup.RegisterUserHandler).Methods("POST")
+
+	// Credential statistics (GET), defaults to the configured channel and chaincode
+	api.HandleFunc("/stats", setup.GetStatisticsHandler).Methods("GET")
+
+	// Chaincode events as server-sent events (GET), defaults to the configured channel and chaincode
+	api.HandleFunc("/events", setup.ChaincodeEventsHandler).Methods("GET")
+
+	// Routes generated from the contract metadata (GET for read-only functions, POST otherwise),
+	// loaded on the first request reaching the chaincode
+	api.PathPrefix("/functions").Handler(&functionRoutes{setup: setup})
+
+	// Probes of the container orchestration, answered without authentication; the other
+	// routes apply CORS middleware, then authenticate each request
+	probes := mux.NewRouter()
+	probes.HandleFunc("/healthz", setup.HealthHandler).Methods("GET")
+	probes.HandleFunc("/readyz", setup.ReadinessHandler).Methods("GET")
+	if setup.Metrics != nil {
+		probes.Handle("/metrics", setup.Metrics.Handler()).Methods("GET")
+	}
+	probes.NotFoundHandler = CORSMiddleware(setup.AuthMiddleware(router))
+
+	// Measure every request, by the route it matches
+	return setup.Metrics.Middleware(probes, router, probes)
+}
+
+// DefaultListenAddress is the address the HTTP server listens on unless configured otherwise
+const DefaultListenAddress = ":3000"
+
+// DefaultShutdownTimeout bounds the draining of the in-flight requests on shutdown unless
+// configured otherwise
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Serve starts http web server with proper routing, until ctx is done. It then shuts down
+// gracefully: readiness fails, no new connection is accepted, event streams end, in-flight
+// requests are drained for up to ShutdownTimeout, and the Gateway connections are closed.
+func Serve(ctx context.Context, setup *OrgSetup) error {
+	address := setup.ListenAddress
+	if address == "" {
+		address = DefaultListenAddress
+	}
+
 	// Set up the server
-	http.Handle("/", corsRouter)
+	setup.stopping = make(chan struct{})
+	server := &http.Server{Addr: address, Handler: NewRouter(setup)}
 	
 	// Start the server
-	fmt.Println("Listening (http://localhost:3000/)...")
-	if err := http.ListenAndServe(":3000", nil); err != nil {
-		log.Fatalf("Server error: %v", err)
+	serveErr := make(chan error, 1)
+	go func() {
+		fmt.Printf("Listening (%s)...\n", address)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+		err = fmt.Errorf("server error: %w", err)
+	case <-ctx.Done():
+		log.Println("Shutting down, draining in-flight requests...")
+		close(setup.stopping)
+
+		timeout := setup.ShutdownTimeout
+		if timeout == 0 {
+			timeout = DefaultShutdownTimeout
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+			err = fmt.Errorf("failed to drain in-flight requests: %w", shutdownErr)
+		}
+	}
+
+	if setup.Gateways != nil {
+		if closeErr := setup.Gateways.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close Gateway connections: %w", closeErr))
+		}
 	}
+	log.Println("Server stopped")
+
+	return err
 }
 
 // HandleError sends standardized error responses
 func HandleError(w http.ResponseWriter, errMsg string, statusCode int) {
+	handleErrorWithCode(w, errMsg, "", statusCode)
+}
+
+// handleErrorWithCode sends a standardized error response carrying a chaincode error code
+func handleErrorWithCode(w http.ResponseWriter, errMsg string, code string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	
+
 	resp := APIResponse{
 		Success: false,
 		Error:   errMsg,
+		Code:    code,
 	}
 	
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -121,8 +276,19 @@ func HandleError(w http.ResponseWriter, errMsg string, statusCode int) {
 
 // HandleSuccess sends standardized success responses
 func HandleSuccess(w http.ResponseWriter, message string, data interface{}) {
+	handleSuccessWithStatus(w, message, data, http.StatusOK)
+}
+
+// HandleCreated sends a 201 success response with the location of the created resource
+func HandleCreated(w http.ResponseWriter, message string, location string, data interface{}) {
+	w.Header().Set("Location", location)
+	handleSuccessWithStatus(w, message, data, http.StatusCreated)
+}
+
+// handleSuccessWithStatus sends a standardized success response with given status
+func handleSuccessWithStatus(w http.ResponseWriter, message string, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(statusCode)
 	
 	resp := APIResponse{
 		Success: true,
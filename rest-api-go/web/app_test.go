@@ -0,0 +1,42 @@
+package web_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"rest-api-go/web/webtest"
+)
+
+func TestCORSHeaders(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("admin:GetStatistics", "{}")
+	router := newRouter(contracts)
+
+	for name, resp := range map[string]*http.Response{
+		"success":  serve(t, router, "GET", "/stats", nil).Result(),
+		"error":    serve(t, router, "GET", "/credentials/all", nil).Result(),
+		"no route": serve(t, router, "GET", "/unknown", nil).Result(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+			require.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", resp.Header.Get("Access-Control-Allow-Methods"))
+			require.Equal(t, "Content-Type, Authorization, Idempotency-Key, X-Fabric-Identity", resp.Header.Get("Access-Control-Allow-Headers"))
+			require.Equal(t, "Location", resp.Header.Get("Access-Control-Expose-Headers"))
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	contracts := webtest.NewContracts()
+	router := newRouter(contracts)
+	calls := len(contracts.Calls())
+
+	// Preflight requests are answered without reaching the handlers
+	rec := serve(t, router, "OPTIONS", "/credentials/academic", nil, "Origin", "http://localhost:8080", "Access-Control-Request-Method", "POST")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Empty(t, rec.Body.String())
+	require.Len(t, contracts.Calls(), calls)
+}
@@ -0,0 +1,146 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Roles granted to the callers of the API
+const (
+	// RoleIssuer approves, revokes and reissues credentials
+	RoleIssuer = "issuer"
+)
+
+// DefaultRolesClaim is the claim holding the roles of a caller unless configured otherwise
+const DefaultRolesClaim = "roles"
+
+// DefaultMSPRoles are the roles of requests made without authentication, by the MSP ID of the
+// identity they are made as: the members of Org1 issue credentials
+var DefaultMSPRoles = map[string][]string{
+	"Org1MSP": {RoleIssuer},
+}
+
+// AuthConfig configures the authentication of requests with JWT bearer tokens
+type AuthConfig struct {
+	// Issuer is the expected "iss" of the tokens, whose OpenID discovery document locates the
+	// JWKS unless JWKSURL is set
+	Issuer   string
+	Audience string // Expected in the "aud" of the tokens, unless empty
+	JWKSURL  string
+
+	// RolesClaim holds the roles of the caller, as a string or a list; a dotted name such as
+	// "realm_access.roles" reaches into nested claims
+	RolesClaim string
+	// IdentityClaim, if set, names the identity the requests of the caller are made as
+	IdentityClaim string
+	// RoleIdentities maps roles to the identity the requests are made as, the first role of the
+	// caller with an identity applying when the token does not name one
+	RoleIdentities map[string]string
+
+	// HTTPClient fetches the keys of the issuer, a client with a 10s timeout if nil
+	HTTPClient *http.Client
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject  string
+	Roles    []string
+	Identity string // Identity the requests are made as, the organization's when empty
+}
+
+// principalContextKey locates the principal of a request in its context
+type principalContextKey struct{}
+
+// PrincipalFromContext returns the authenticated caller of a request, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return principal, ok
+}
+
+// AuthMiddleware requires a valid bearer token on every request, and makes the request as the
+// identity mapped from its claims. Without an auth config, requests pass unauthenticated.
+func (setup *OrgSetup) AuthMiddleware(next http.Handler) http.Handler {
+	if setup.Auth == nil {
+		return next
+	}
+
+	config := *setup.Auth
+	if config.RolesClaim == "" {
+		config.RolesClaim = DefaultRolesClaim
+	}
+	verifier := NewTokenVerifier(config.Issuer, config.Audience, config.JWKSURL, config.HTTPClient)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer`)
+			HandleError(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := verifier.Verify(r.Context(), token)
+		if err != nil {
+			log.Printf("Rejected bearer token: %v", err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			HandleError(w, "Invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		principal := config.principal(claims)
+		ctx := context.WithValue(r.Context(), principalContextKey{}, principal)
+		if principal.Identity != "" {
+			ctx = ContextWithIdentity(ctx, principal.Identity)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// principal maps the claims of a token to the caller
+func (config AuthConfig) principal(claims Claims) *Principal {
+	principal := &Principal{
+		Subject: claims.String("sub"),
+		Roles:   claims.Strings(config.RolesClaim),
+	}
+
+	if config.IdentityClaim != "" {
+		principal.Identity = claims.String(config.IdentityClaim)
+	}
+	for _, role := range principal.Roles {
+		if principal.Identity != "" {
+			break
+		}
+		principal.Identity = config.RoleIdentities[role]
+	}
+
+	return principal
+}
+
+// bearerToken returns the token of the Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// hasRole tells whether the caller of a request has a role: from its token when authenticated,
+// otherwise from the MSP of the identity the request is made as. Only authenticated callers
+// administer the users of the organization.
+func (setup *OrgSetup) hasRole(r *http.Request, role string) bool {
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		return contains(principal.Roles, role)
+	}
+	if role == RoleAdmin {
+		return false
+	}
+
+	client := setup.client(r)
+	mspRoles := setup.MSPRoles
+	if mspRoles == nil {
+		mspRoles = DefaultMSPRoles
+	}
+	return contains(mspRoles[client.MSPID], role)
+}
@@ -0,0 +1,208 @@
+package web_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"rest-api-go/web"
+	"rest-api-go/web/webtest"
+)
+
+// newAuthRouter creates the router of an Org1 setup, which can also make requests as Org2,
+// authenticating requests with the tokens of issuer
+func newAuthRouter(issuer *webtest.Issuer, auth web.AuthConfig, org1 *webtest.Contracts, org2 *webtest.Contracts) http.Handler {
+	auth.Issuer = issuer.URL
+	return web.NewRouter(&web.OrgSetup{
+		OrgName:   "Org1",
+		MSPID:     "Org1MSP",
+		Contracts: org1,
+		Clients: map[string]*web.Client{
+			"Org1": {Name: "Org1", MSPID: "Org1MSP", Contracts: org1},
+			"Org2": {Name: "Org2", MSPID: "Org2MSP", Contracts: org2},
+		},
+		ChannelID:        channelID,
+		ChaincodeID:      chaincodeID,
+		AllowedFunctions: []string{"*"},
+		Auth:             &auth,
+	})
+}
+
+// bearer returns the Authorization header of a token
+func bearer(token string) []string {
+	return []string{"Authorization", "Bearer " + token}
+}
+
+func TestAuthenticationRequired(t *testing.T) {
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	org1 := webtest.NewContracts()
+	router := newAuthRouter(issuer, web.AuthConfig{Audience: "credentials-api"}, org1, webtest.NewContracts())
+	calls := len(org1.Calls())
+
+	valid := issuer.Token(map[string]interface{}{"aud": "credentials-api"})
+	header, claims, signature := splitToken(valid)
+	for name, headers := range map[string][]string{
+		"no token":        nil,
+		"basic auth":      {"Authorization", "Basic dXNlcjpwYXNz"},
+		"malformed":       bearer("not a token"),
+		"expired":         bearer(issuer.Token(map[string]interface{}{"aud": "credentials-api", "exp": time.Now().Add(-time.Hour).Unix()})),
+		"not valid yet":   bearer(issuer.Token(map[string]interface{}{"aud": "credentials-api", "nbf": time.Now().Add(time.Hour).Unix()})),
+		"other audience":  bearer(issuer.Token(map[string]interface{}{"aud": "other-api"})),
+		"other issuer":    bearer(issuer.Token(map[string]interface{}{"aud": "credentials-api", "iss": "https://issuer.example.com"})),
+		"tampered claims": bearer(header + "." + encodeClaims(`{"iss":"`+issuer.URL+`","aud":"credentials-api","roles":["issuer"],"exp":9999999999}`) + "." + signature),
+		"unsigned":        bearer(encodeClaims(`{"alg":"none","kid":"test-key"}`) + "." + claims + "."),
+		"unknown key":     bearer(encodeClaims(`{"alg":"RS256","kid":"other-key"}`) + "." + claims + "." + signature),
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, headers...)
+			resp := decode(t, rec, http.StatusUnauthorized)
+			require.False(t, resp.Success)
+			require.Contains(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
+		})
+	}
+	require.Len(t, org1.Calls(), calls)
+
+	org1.Return("credentials:GetAllCredentials", "[]")
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, bearer(valid)...), http.StatusOK)
+}
+
+func TestRoleAuthorization(t *testing.T) {
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	org1 := webtest.NewContracts()
+	org1.Return("issuers:UpdateVerificationStatus", "")
+	router := newAuthRouter(issuer, web.AuthConfig{RolesClaim: "realm_access.roles"}, org1, webtest.NewContracts())
+
+	issuerToken := issuer.Token(map[string]interface{}{"realm_access": map[string]interface{}{"roles": []string{"viewer", "issuer"}}})
+	decode(t, serve(t, router, "PUT", "/credentials/cred1/revoke"+chaincodeQuery, nil, bearer(issuerToken)...), http.StatusOK)
+	requireCall(t, lastCall(t, org1), true, "issuers:UpdateVerificationStatus", "cred1", "Revoked", "Org1")
+
+	// The MSP of the identity no longer grants the role
+	calls := len(org1.Calls())
+	viewerToken := issuer.Token(map[string]interface{}{"realm_access": map[string]interface{}{"roles": "viewer"}})
+	for _, path := range []string{"/credentials/cred1/approve", "/credentials/cred1/revoke"} {
+		resp := decode(t, serve(t, router, "PUT", path+chaincodeQuery, nil, bearer(viewerToken)...), http.StatusForbidden)
+		require.Contains(t, resp.Error, "Permission denied")
+	}
+	resp := decode(t, serve(t, router, "POST", "/credentials/cred1/reissue", web.ReissueRequest{ChainCodeID: chaincodeID, ChannelID: channelID}, bearer(viewerToken)...), http.StatusForbidden)
+	require.Contains(t, resp.Error, "Permission denied")
+	resp = decode(t, serve(t, router, "DELETE", "/credentials/cred1"+chaincodeQuery, nil, bearer(viewerToken)...), http.StatusForbidden)
+	require.Contains(t, resp.Error, "Permission denied")
+	require.Len(t, org1.Calls(), calls)
+
+	// Admins delete credentials too
+	org1.Return("credentials:DeleteTalentCredential", "")
+	adminToken := issuer.Token(map[string]interface{}{"realm_access": map[string]interface{}{"roles": "admin"}})
+	decode(t, serve(t, router, "DELETE", "/credentials/cred1"+chaincodeQuery, nil, bearer(adminToken)...), http.StatusOK)
+	requireCall(t, lastCall(t, org1), true, "credentials:DeleteTalentCredential", "cred1")
+}
+
+func TestClaimsSelectIdentity(t *testing.T) {
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	org1 := webtest.NewContracts()
+	org2 := webtest.NewContracts()
+	org1.Return("credentials:GetAllCredentials", "[]")
+	org2.Return("credentials:GetAllCredentials", "[]")
+	router := newAuthRouter(issuer, web.AuthConfig{
+		IdentityClaim:  "fabric_identity",
+		RoleIdentities: map[string]string{"org2-member": "Org2"},
+	}, org1, org2)
+
+	// A role mapped to an identity, which the header cannot override
+	token := issuer.Token(map[string]interface{}{"roles": []string{"viewer", "org2-member"}})
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, bearer(token)...), http.StatusOK)
+	require.Len(t, org2.Calls(), 1)
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, append(bearer(token), web.IdentityHeader, "Org1")...), http.StatusForbidden)
+	require.Len(t, org2.Calls(), 1)
+
+	// The identity claim takes precedence over the roles
+	token = issuer.Token(map[string]interface{}{"roles": "org2-member", "fabric_identity": "Org1"})
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, bearer(token)...), http.StatusOK)
+	requireCall(t, lastCall(t, org1), false, "credentials:GetAllCredentials")
+	require.Len(t, org2.Calls(), 1)
+
+	// Without a mapping, requests are made as the organization
+	calls := len(org1.Calls())
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, bearer(issuer.Token(nil))...), http.StatusOK)
+	require.Len(t, org1.Calls(), calls+1)
+	require.Len(t, org2.Calls(), 1)
+
+	// An identity that is not configured is refused
+	token = issuer.Token(map[string]interface{}{"fabric_identity": "Org3"})
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, bearer(token)...), http.StatusForbidden)
+}
+
+func TestKeysFetchedOnceForConcurrentRequests(t *testing.T) {
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	issuer.KeysDelay = 100 * time.Millisecond
+	org1 := webtest.NewContracts()
+	org1.Return("credentials:GetAllCredentials", "[]")
+	router := newAuthRouter(issuer, web.AuthConfig{}, org1, webtest.NewContracts())
+
+	token := issuer.Token(nil)
+	var wg sync.WaitGroup
+	codes := make([]int, 10)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, bearer(token)...).Code
+		}(i)
+	}
+	wg.Wait()
+
+	for _, code := range codes {
+		require.Equal(t, http.StatusOK, code)
+	}
+	require.Equal(t, 1, issuer.KeyFetches())
+}
+
+func TestRequestsStopWaitingForKeysWhenCancelled(t *testing.T) {
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	issuer.KeysDelay = time.Second
+	router := newAuthRouter(issuer, web.AuthConfig{}, webtest.NewContracts(), webtest.NewContracts())
+	token := issuer.Token(nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, bearer(token)...)
+	}()
+	require.Eventually(t, func() bool { return issuer.KeyFetches() == 1 }, time.Second, time.Millisecond)
+
+	// A request waiting for the fetch in progress gives up with its context
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/credentials/all"+chaincodeQuery, nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+
+	<-done
+	require.Equal(t, 1, issuer.KeyFetches())
+}
+
+// splitToken returns the encoded header, claims and signature of a token
+func splitToken(token string) (string, string, string) {
+	parts := strings.Split(token, ".")
+	return parts[0], parts[1], parts[2]
+}
+
+// encodeClaims encodes a JSON segment of a token
+func encodeClaims(json string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(json))
+}
@@ -0,0 +1,133 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Phases of a submitted transaction, whose durations are observed
+const (
+	PhaseEndorse      = "endorse"
+	PhaseSubmit       = "submit"
+	PhaseCommitStatus = "commit_status"
+)
+
+// Proposal is a chaincode call: the function, its arguments and the transient data passed to
+// the endorsing peers
+type Proposal struct {
+	Function  string
+	Args      []string
+	Transient map[string][]byte
+	// ObservePhase, when set, is told how long each phase of a submitted transaction took,
+	// whether it succeeded or not
+	ObservePhase func(phase string, duration time.Duration)
+}
+
+// observe reports the duration of a phase started at start, if observed
+func (proposal Proposal) observe(phase string, start time.Time) {
+	if proposal.ObservePhase != nil {
+		proposal.ObservePhase(phase, time.Since(start))
+	}
+}
+
+// Contract submits and evaluates the transactions of a chaincode and listens to its events
+type Contract interface {
+	// Evaluate runs a query on a peer without submitting a transaction, until ctx is done
+	Evaluate(ctx context.Context, proposal Proposal) ([]byte, error)
+	// Submit endorses a transaction, submits it to the orderer and waits for it to commit
+	Submit(proposal Proposal) (*TransactionResult, error)
+	// ChaincodeEvents returns the events emitted by the chaincode from now on, until ctx is done
+	ChaincodeEvents(ctx context.Context) (<-chan *client.ChaincodeEvent, error)
+}
+
+// ContractProvider returns the contract of a chaincode deployed on a channel
+type ContractProvider interface {
+	Contract(channelID string, chaincodeID string) Contract
+}
+
+// GatewayContracts provides the contracts reachable through a Fabric Gateway connection
+type GatewayContracts struct {
+	Gateway *client.Gateway
+}
+
+// Contract returns the contract of a chaincode deployed on a channel
+func (contracts GatewayContracts) Contract(channelID string, chaincodeID string) Contract {
+	network := contracts.Gateway.GetNetwork(channelID)
+	return &gatewayContract{
+		network:     network,
+		contract:    network.GetContract(chaincodeID),
+		chaincodeID: chaincodeID,
+	}
+}
+
+// gatewayContract is a Contract backed by a Fabric Gateway connection
+type gatewayContract struct {
+	network     *client.Network
+	contract    *client.Contract
+	chaincodeID string
+}
+
+// proposalOptions converts a proposal into the options of the Gateway client
+func proposalOptions(proposal Proposal) []client.ProposalOption {
+	options := []client.ProposalOption{client.WithArguments(proposal.Args...)}
+	if len(proposal.Transient) > 0 {
+		options = append(options, client.WithTransient(proposal.Transient))
+	}
+
+	return options
+}
+
+// Evaluate runs a query on a peer without submitting a transaction, until ctx is done
+func (c *gatewayContract) Evaluate(ctx context.Context, proposal Proposal) ([]byte, error) {
+	return c.contract.EvaluateWithContext(ctx, proposal.Function, proposalOptions(proposal)...)
+}
+
+// Submit endorses a transaction, submits it to the orderer and waits for it to commit
+func (c *gatewayContract) Submit(proposal Proposal) (*TransactionResult, error) {
+	// Create the transaction proposal
+	txnProposal, err := c.contract.NewProposal(proposal.Function, proposalOptions(proposal)...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating txn proposal: %w", err)
+	}
+
+	// Endorse the transaction
+	start := time.Now()
+	txnEndorsed, err := txnProposal.Endorse()
+	proposal.observe(PhaseEndorse, start)
+	if err != nil {
+		return nil, fmt.Errorf("error endorsing txn: %w", err)
+	}
+
+	// Submit the transaction
+	start = time.Now()
+	txnCommit, err := txnEndorsed.Submit()
+	proposal.observe(PhaseSubmit, start)
+	if err != nil {
+		return nil, fmt.Errorf("error submitting transaction: %w", err)
+	}
+
+	// Wait for the commit, which fails when the transaction is invalidated, e.g. by an MVCC conflict
+	start = time.Now()
+	status, err := txnCommit.Status()
+	proposal.observe(PhaseCommitStatus, start)
+	if err != nil {
+		return nil, fmt.Errorf("error getting commit status: %w", err)
+	}
+	if !status.Successful {
+		commitErr := &client.CommitError{TransactionID: status.TransactionID, Code: status.Code}
+		return nil, fmt.Errorf("transaction %s failed to commit with status code %d (%s): %w", status.TransactionID, int32(status.Code), status.Code, commitErr)
+	}
+
+	return &TransactionResult{
+		TxID:     txnCommit.TransactionID(),
+		Response: string(txnEndorsed.Result()),
+	}, nil
+}
+
+// ChaincodeEvents returns the events emitted by the chaincode from now on, until ctx is done
+func (c *gatewayContract) ChaincodeEvents(ctx context.Context) (<-chan *client.ChaincodeEvent, error) {
+	return c.network.ChaincodeEvents(ctx, c.chaincodeID)
+}
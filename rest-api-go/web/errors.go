@@ -0,0 +1,108 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Error codes returned by the chaincode as the first token of its error messages
+const (
+	CodeNotFound        = "NOT_FOUND"
+	CodeAlreadyExists   = "ALREADY_EXISTS"
+	CodeForbidden       = "FORBIDDEN"
+	CodeInvalidArgument = "INVALID_ARGUMENT"
+	CodeConflict        = "CONFLICT"
+	CodeAlreadyApplied  = "ALREADY_APPLIED"
+	CodeQuotaExceeded   = "QUOTA_EXCEEDED"
+
+	CodeIdempotencyKeyReused     = "IDEMPOTENCY_KEY_REUSED"
+	CodeNotAccredited            = "NOT_ACCREDITED"
+	CodeAccreditationUnavailable = "ACCREDITATION_UNAVAILABLE"
+)
+
+// codeStatuses maps chaincode error codes to HTTP statuses
+var codeStatuses = map[string]int{
+	CodeNotFound:        http.StatusNotFound,
+	CodeAlreadyExists:   http.StatusConflict,
+	CodeForbidden:       http.StatusForbidden,
+	CodeInvalidArgument: http.StatusBadRequest,
+	CodeConflict:        http.StatusConflict,
+	CodeAlreadyApplied:  http.StatusConflict,
+	CodeQuotaExceeded:   http.StatusTooManyRequests,
+
+	CodeIdempotencyKeyReused:     http.StatusUnprocessableEntity,
+	CodeNotAccredited:            http.StatusForbidden,
+	CodeAccreditationUnavailable: http.StatusServiceUnavailable,
+}
+
+// chaincodeErrorPattern finds "CODE: message" in the messages relayed by the peers
+var chaincodeErrorPattern = regexp.MustCompile(`\b(NOT_FOUND|ALREADY_EXISTS|FORBIDDEN|INVALID_ARGUMENT|CONFLICT|ALREADY_APPLIED|QUOTA_EXCEEDED|IDEMPOTENCY_KEY_REUSED|NOT_ACCREDITED|ACCREDITATION_UNAVAILABLE): (.*)$`)
+
+// ChaincodeError is a failure reported by the chaincode with a machine-readable code
+type ChaincodeError struct {
+	Code    string
+	Message string
+}
+
+func (e *ChaincodeError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// ParseChaincodeError extracts the chaincode error code and message from a Gateway error.
+// Endorsement and evaluation failures carry the chaincode message in the gRPC status
+// details of each peer; commit failures caused by concurrent writes map to CONFLICT.
+// It returns nil when the error does not carry a chaincode error code.
+func ParseChaincodeError(err error) *ChaincodeError {
+	var chaincodeErr *ChaincodeError
+	if errors.As(err, &chaincodeErr) {
+		return chaincodeErr
+	}
+
+	var commitErr *client.CommitError
+	if errors.As(err, &commitErr) && commitErr.Code == peer.TxValidationCode_MVCC_READ_CONFLICT {
+		return &ChaincodeError{Code: CodeConflict, Message: err.Error()}
+	}
+
+	messages := []string{}
+	if st, ok := status.FromError(err); ok {
+		for _, detail := range st.Details() {
+			if errorDetail, ok := detail.(*gateway.ErrorDetail); ok {
+				messages = append(messages, errorDetail.GetMessage())
+			}
+		}
+		messages = append(messages, st.Message())
+	}
+	messages = append(messages, err.Error())
+
+	for _, message := range messages {
+		if match := chaincodeErrorPattern.FindStringSubmatch(strings.TrimSpace(message)); match != nil {
+			return &ChaincodeError{Code: match[1], Message: match[2]}
+		}
+	}
+
+	return nil
+}
+
+// HandleTransactionError sends the error response for a failed chaincode call. Chaincode
+// error codes are mapped to their status in codeStatuses, an unreachable peer is reported as
+// a 503, anything else as a 500.
+func HandleTransactionError(w http.ResponseWriter, prefix string, err error) {
+	chaincodeErr := ParseChaincodeError(err)
+	if chaincodeErr == nil {
+		if IsUnavailable(err) {
+			HandleError(w, prefix+": "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		HandleError(w, prefix+": "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	handleErrorWithCode(w, prefix+": "+chaincodeErr.Message, chaincodeErr.Code, codeStatuses[chaincodeErr.Code])
+}
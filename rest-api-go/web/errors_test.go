@@ -0,0 +1,75 @@
+package web_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"rest-api-go/web"
+	"rest-api-go/web/webtest"
+)
+
+func TestParseChaincodeError(t *testing.T) {
+	chaincodeErr := web.ParseChaincodeError(webtest.ChaincodeError(web.CodeNotFound, "credential cred1 does not exist"))
+	require.Equal(t, &web.ChaincodeError{Code: web.CodeNotFound, Message: "credential cred1 does not exist"}, chaincodeErr)
+
+	chaincodeErr = web.ParseChaincodeError(webtest.ConflictError("tx1"))
+	require.NotNil(t, chaincodeErr)
+	require.Equal(t, web.CodeConflict, chaincodeErr.Code)
+	require.Contains(t, chaincodeErr.Message, "MVCC_READ_CONFLICT")
+
+	require.Nil(t, web.ParseChaincodeError(errors.New("connection refused")))
+}
+
+func TestTransactionErrorStatuses(t *testing.T) {
+	for name, test := range map[string]struct {
+		err    error
+		status int
+		code   string
+	}{
+		"not found":                 {webtest.ChaincodeError(web.CodeNotFound, "credential cred1 does not exist"), http.StatusNotFound, web.CodeNotFound},
+		"already exists":            {webtest.ChaincodeError(web.CodeAlreadyExists, "credential cred1 already exists"), http.StatusConflict, web.CodeAlreadyExists},
+		"forbidden":                 {webtest.ChaincodeError(web.CodeForbidden, "only issuers can update credentials"), http.StatusForbidden, web.CodeForbidden},
+		"invalid argument":          {webtest.ChaincodeError(web.CodeInvalidArgument, "invalid skills"), http.StatusBadRequest, web.CodeInvalidArgument},
+		"conflict":                  {webtest.ChaincodeError(web.CodeConflict, "name does not match the talent profile"), http.StatusConflict, web.CodeConflict},
+		"already applied":           {webtest.ChaincodeError(web.CodeAlreadyApplied, "idempotency key key1 was already used"), http.StatusConflict, web.CodeAlreadyApplied},
+		"quota exceeded":            {webtest.ChaincodeError(web.CodeQuotaExceeded, "daily quota of 5 credentials reached"), http.StatusTooManyRequests, web.CodeQuotaExceeded},
+		"key reused":                {webtest.ChaincodeError(web.CodeIdempotencyKeyReused, "idempotency key key1 was already used for another request"), http.StatusUnprocessableEntity, web.CodeIdempotencyKeyReused},
+		"not accredited":            {webtest.ChaincodeError(web.CodeNotAccredited, "the institution \"Diploma Mill\" is not currently accredited"), http.StatusForbidden, web.CodeNotAccredited},
+		"accreditation unavailable": {webtest.ChaincodeError(web.CodeAccreditationUnavailable, "accreditation chaincode accreditation failed: registry unavailable"), http.StatusServiceUnavailable, web.CodeAccreditationUnavailable},
+		"MVCC conflict":             {webtest.ConflictError("tx1"), http.StatusConflict, web.CodeConflict},
+		"peer unavailable":          {status.Error(codes.Unavailable, "connection refused"), http.StatusServiceUnavailable, ""},
+		"breaker open":              {fmt.Errorf("%w: localhost:7051", web.ErrPeerUnavailable), http.StatusServiceUnavailable, ""},
+		"other":                     {errors.New("connection refused"), http.StatusInternalServerError, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			contracts := webtest.NewContracts()
+			contracts.Fail("credentials:UpdateSkills", test.err)
+			contracts.Fail("credentials:GetTalentCredential", test.err)
+			router := newRouter(contracts)
+
+			// Submitted and evaluated functions report errors the same way
+			request := web.UpdateSkillsRequest{ChainCodeID: chaincodeID, ChannelID: channelID, NewSkills: "Go"}
+			resp := decode(t, serve(t, router, "PUT", "/credentials/cred1/skills", request), test.status)
+			require.False(t, resp.Success)
+			require.Equal(t, test.code, resp.Code)
+			require.Contains(t, resp.Error, "Transaction failed: ")
+
+			resp = decode(t, serve(t, router, "GET", "/credentials/cred1"+chaincodeQuery, nil), test.status)
+			require.Equal(t, test.code, resp.Code)
+		})
+	}
+}
+
+func TestUnknownFunction(t *testing.T) {
+	router := newRouter(webtest.NewContracts())
+
+	resp := decode(t, serve(t, router, "GET", "/stats", nil), http.StatusNotFound)
+	require.Equal(t, web.CodeNotFound, resp.Code)
+	require.Equal(t, "Query failed: function GetStatistics is not part of contract admin", resp.Error)
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// ChaincodeEvent is a chaincode event as streamed to clients
+type ChaincodeEvent struct {
+	BlockNumber   uint64      `json:"blockNumber"`
+	TransactionID string      `json:"transactionId"`
+	EventName     string      `json:"eventName"`
+	Payload       interface{} `json:"payload"` // Decoded when the chaincode emitted JSON, a string otherwise
+}
+
+// newChaincodeEvent converts an event received from the Gateway
+func newChaincodeEvent(event *client.ChaincodeEvent) ChaincodeEvent {
+	var payload interface{}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		payload = string(event.Payload)
+	}
+
+	return ChaincodeEvent{
+		BlockNumber:   event.BlockNumber,
+		TransactionID: event.TransactionID,
+		EventName:     event.EventName,
+		Payload:       payload,
+	}
+}
+
+// ChaincodeEventsHandler streams the events emitted by the chaincode from now on as
+// server-sent events, named after the chaincode event, until the client disconnects
+func (setup *OrgSetup) ChaincodeEventsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received Chaincode Events request")
+
+	chaincodeID := r.URL.Query().Get("chaincodeid")
+	channelID := r.URL.Query().Get("channelid")
+	if chaincodeID == "" {
+		chaincodeID = setup.ChaincodeID
+	}
+	if channelID == "" {
+		channelID = setup.ChannelID
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		HandleError(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// The channel is closed once the request context is done
+	events, err := setup.client(r).Contracts.Contract(channelID, chaincodeID).ChaincodeEvents(r.Context())
+	if err != nil {
+		HandleTransactionError(w, "Failed to listen to chaincode events", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// The stream ends when the server shuts down, so that it does not hold up the draining
+	for {
+		var event *client.ChaincodeEvent
+		select {
+		case event, ok = <-events:
+			if !ok {
+				return
+			}
+		case <-setup.stopping:
+			return
+		}
+
+		data, err := json.Marshal(newChaincodeEvent(event))
+		if err != nil {
+			log.Printf("Error encoding chaincode event: %v", err)
+			continue
+		}
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.TransactionID, event.EventName, data)
+		flusher.Flush()
+	}
+}
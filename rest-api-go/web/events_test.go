@@ -0,0 +1,72 @@
+package web_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/stretchr/testify/require"
+
+	"rest-api-go/web/webtest"
+)
+
+// readEvent reads the lines of the next server-sent event
+func readEvent(t *testing.T, reader *bufio.Reader) []string {
+	t.Helper()
+
+	lines := []string{}
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestChaincodeEvents(t *testing.T) {
+	contracts := webtest.NewContracts()
+	server := httptest.NewServer(newRouter(contracts))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// The handler subscribes before responding, so the events emitted from now on are streamed
+	contracts.Emit("otherchannel", chaincodeID, &client.ChaincodeEvent{TransactionID: "tx0", EventName: "Ignored"})
+	contracts.Emit(channelID, chaincodeID, &client.ChaincodeEvent{
+		BlockNumber:   7,
+		TransactionID: "tx1",
+		ChaincodeName: chaincodeID,
+		EventName:     "CredentialCreated",
+		Payload:       []byte(`{"credentialId":"cred1"}`),
+	})
+	contracts.Emit(channelID, chaincodeID, &client.ChaincodeEvent{
+		BlockNumber:   8,
+		TransactionID: "tx2",
+		ChaincodeName: chaincodeID,
+		EventName:     "Note",
+		Payload:       []byte("plain text"),
+	})
+
+	reader := bufio.NewReader(resp.Body)
+	require.Equal(t, []string{
+		"id: tx1",
+		"event: CredentialCreated",
+		`data: {"blockNumber":7,"transactionId":"tx1","eventName":"CredentialCreated","payload":{"credentialId":"cred1"}}`,
+	}, readEvent(t, reader))
+	require.Equal(t, []string{
+		"id: tx2",
+		"event: Note",
+		`data: {"blockNumber":8,"transactionId":"tx2","eventName":"Note","payload":"plain text"}`,
+	}, readEvent(t, reader))
+}
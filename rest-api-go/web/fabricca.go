@@ -0,0 +1,262 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+)
+
+// Certificate attributes given to the users registered through the API, which the chaincode
+// reads with GetAttributeValue
+const (
+	AttributeRole     = "role"
+	AttributeTalentID = "talentID"
+)
+
+// attributesOID is the certificate extension in which Fabric CA stores the attributes
+var attributesOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// CertificateAttributes returns the attributes Fabric CA added to a PEM-encoded certificate
+func CertificateAttributes(certificatePEM []byte) (map[string]string, error) {
+	certificate, err := identity.CertificateFromPEM(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := struct {
+		Attrs map[string]string `json:"attrs"`
+	}{Attrs: map[string]string{}}
+	for _, extension := range certificate.Extensions {
+		if extension.Id.Equal(attributesOID) {
+			if err := json.Unmarshal(extension.Value, &attributes); err != nil {
+				return nil, fmt.Errorf("invalid certificate attributes: %w", err)
+			}
+		}
+	}
+
+	return attributes.Attrs, nil
+}
+
+// CAClient calls the REST API of a Fabric CA
+type CAClient struct {
+	URL        string // e.g. https://localhost:7054
+	CAName     string // Name of the CA of the server, its default CA if empty
+	HTTPClient *http.Client
+}
+
+// CAAttribute is an attribute of a registered identity, added to its enrollment certificates
+// when ECert is set
+type CAAttribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	ECert bool   `json:"ecert"`
+}
+
+// CARegistration registers an identity with the CA
+type CARegistration struct {
+	Name           string        `json:"id"`
+	Type           string        `json:"type,omitempty"`
+	Secret         string        `json:"secret,omitempty"` // Generated by the CA if empty
+	Affiliation    string        `json:"affiliation"`
+	MaxEnrollments int           `json:"max_enrollments,omitempty"`
+	Attributes     []CAAttribute `json:"attrs,omitempty"`
+	CAName         string        `json:"caname,omitempty"`
+}
+
+// CAError is an error returned by the CA
+type CAError struct {
+	StatusCode int // HTTP status of the response
+	Code       int // Fabric CA error code, e.g. 20 for authentication failures
+	Message    string
+}
+
+func (err *CAError) Error() string {
+	return fmt.Sprintf("CA error %d: %s", err.Code, err.Message)
+}
+
+// caResponse is the envelope of the responses of the CA
+type caResponse struct {
+	Success bool            `json:"success"`
+	Result  json.RawMessage `json:"result"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Register registers an identity on behalf of a registrar, and returns its enrollment secret
+func (ca *CAClient) Register(ctx context.Context, registrar *WalletIdentity, registration CARegistration) (string, error) {
+	registration.CAName = ca.CAName
+	body, err := json.Marshal(registration)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := ca.newRequest(ctx, "register", body)
+	if err != nil {
+		return "", err
+	}
+	token, err := caToken(registrar, req.Method, req.URL.RequestURI(), body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", token)
+
+	var result struct {
+		Secret string `json:"secret"`
+	}
+	if err := ca.do(req, &result); err != nil {
+		return "", err
+	}
+
+	return result.Secret, nil
+}
+
+// Enroll enrolls an identity with a new P-256 key, requesting the given certificate
+// attributes when the identity has them, and returns its certificate and private key
+func (ca *CAClient) Enroll(ctx context.Context, enrollmentID string, secret string, attributes ...string) (*WalletIdentity, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: enrollmentID},
+	}, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate request: %w", err)
+	}
+
+	type attributeRequest struct {
+		Name     string `json:"name"`
+		Optional bool   `json:"optional"`
+	}
+	request := struct {
+		CertificateRequest string             `json:"certificate_request"`
+		AttributeRequests  []attributeRequest `json:"attr_reqs,omitempty"`
+		CAName             string             `json:"caname,omitempty"`
+	}{
+		CertificateRequest: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})),
+		CAName:             ca.CAName,
+	}
+	for _, name := range attributes {
+		request.AttributeRequests = append(request.AttributeRequests, attributeRequest{Name: name, Optional: true})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := ca.newRequest(ctx, "enroll", body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(enrollmentID, secret)
+
+	var result struct {
+		Cert string `json:"Cert"`
+	}
+	if err := ca.do(req, &result); err != nil {
+		return nil, err
+	}
+	certificate, err := base64.StdEncoding.DecodeString(result.Cert)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate returned by the CA: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WalletIdentity{
+		Certificate: certificate,
+		PrivateKey:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// newRequest creates a request to an endpoint of the API
+func (ca *CAClient) newRequest(ctx context.Context, endpoint string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(ca.URL, "/")+"/api/v1/"+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// do sends a request and decodes the result of its response
+func (ca *CAClient) do(req *http.Request, result interface{}) error {
+	client := ca.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the CA: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope caResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("invalid response from the CA (%s): %w", resp.Status, err)
+	}
+	if !envelope.Success || resp.StatusCode >= 300 {
+		caErr := &CAError{StatusCode: resp.StatusCode, Message: resp.Status}
+		if len(envelope.Errors) > 0 {
+			caErr.Code = envelope.Errors[0].Code
+			caErr.Message = envelope.Errors[0].Message
+		}
+		return caErr
+	}
+
+	return json.Unmarshal(envelope.Result, result)
+}
+
+// caToken creates the token authenticating a request of a registrar: its certificate and its
+// signature of the method, URI, body and certificate of the request
+func caToken(registrar *WalletIdentity, method string, uri string, body []byte) (string, error) {
+	privateKey, err := identity.PrivateKeyFromPEM(registrar.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid registrar private key: %w", err)
+	}
+	ecdsaKey, ok := privateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return "", errors.New("registrar private key is not an ECDSA key")
+	}
+
+	b64Cert := base64.StdEncoding.EncodeToString(registrar.Certificate)
+	payload := method + "." + base64.StdEncoding.EncodeToString([]byte(uri)) + "." + base64.StdEncoding.EncodeToString(body) + "." + b64Cert
+	digest := sha256.Sum256([]byte(payload))
+
+	r, s, err := ecdsa.Sign(rand.Reader, ecdsaKey, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// The CA only accepts signatures in their low-S form
+	halfOrder := new(big.Int).Rsh(ecdsaKey.Curve.Params().N, 1)
+	if s.Cmp(halfOrder) > 0 {
+		s.Sub(ecdsaKey.Curve.Params().N, s)
+	}
+	signature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		return "", err
+	}
+
+	return b64Cert + "." + base64.StdEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// readinessFunction is the lightweight evaluation checking that the peer of an identity
+// answers: a single world state read of a credential that need not exist
+const (
+	readinessFunction     = "credentials:CredentialExists"
+	readinessCredentialID = "readiness-probe"
+)
+
+// readinessTimeout bounds the check of each identity, as probes expect a quick answer
+const readinessTimeout = 3 * time.Second
+
+// Readiness reports whether the peers of the identities answer
+type Readiness struct {
+	Ready      bool              `json:"ready"`
+	Identities map[string]string `json:"identities"` // "ok", or why the identity cannot be used
+}
+
+// HealthHandler answers the liveness probe while the server is running
+func (setup *OrgSetup) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	HandleSuccess(w, "Alive", nil)
+}
+
+// ReadinessHandler answers the readiness probe: 200 when the peers of all the configured
+// identities answer an evaluation, 503 otherwise or once the server is shutting down
+func (setup *OrgSetup) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-setup.stopping:
+		writeReadiness(w, Readiness{Identities: map[string]string{}}, "Shutting down")
+		return
+	default:
+	}
+
+	clients := setup.Clients
+	if len(clients) == 0 {
+		clients = map[string]*Client{setup.OrgName: setup.defaultClient()}
+	}
+
+	readiness := Readiness{Ready: true, Identities: make(map[string]string)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, client := range clients {
+		wg.Add(1)
+		go func(name string, client *Client) {
+			defer wg.Done()
+			err := setup.checkReadiness(client)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				readiness.Ready = false
+				readiness.Identities[name] = err.Error()
+				return
+			}
+			readiness.Identities[name] = "ok"
+		}(name, client)
+	}
+	wg.Wait()
+
+	if !readiness.Ready {
+		log.Printf("Not ready: %v", readiness.Identities)
+		writeReadiness(w, readiness, "Peers unavailable")
+		return
+	}
+	HandleSuccess(w, "Ready", readiness)
+}
+
+// checkReadiness evaluates the readiness function as a client, within the readiness timeout
+func (setup *OrgSetup) checkReadiness(client *Client) error {
+	// Cancelled on return, so that an evaluation outliving the timeout stops its retries
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := executeQuery(ctx, setup, client, setup.ChannelID, setup.ChaincodeID, readinessFunction, []string{readinessCredentialID})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(readinessTimeout):
+		return errors.New("no answer within " + readinessTimeout.String())
+	}
+}
+
+// writeReadiness sends a failed readiness report with 503
+func writeReadiness(w http.ResponseWriter, readiness Readiness, errMsg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+
+	resp := APIResponse{Success: false, Error: errMsg, Data: readiness}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
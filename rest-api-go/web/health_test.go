@@ -0,0 +1,149 @@
+package web_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"rest-api-go/web"
+	"rest-api-go/web/webtest"
+)
+
+func TestHealth(t *testing.T) {
+	contracts := webtest.NewContracts()
+	router := web.NewRouter(&web.OrgSetup{OrgName: "Org1", MSPID: "Org1MSP", Contracts: contracts, ChannelID: channelID, ChaincodeID: chaincodeID})
+
+	resp := decode(t, serve(t, router, "GET", "/healthz", nil), http.StatusOK)
+	require.True(t, resp.Success)
+
+	// Ready once the peer answers the readiness evaluation
+	rec := serve(t, router, "GET", "/readyz", nil)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	contracts.Return("credentials:CredentialExists", "false")
+	var readiness web.Readiness
+	decodeData(t, serve(t, router, "GET", "/readyz", nil), http.StatusOK, &readiness)
+	require.Equal(t, web.Readiness{Ready: true, Identities: map[string]string{"Org1": "ok"}}, readiness)
+
+	calls := contracts.Calls()
+	call := calls[len(calls)-1]
+	require.False(t, call.Submitted)
+	require.Equal(t, "credentials:CredentialExists", call.Function)
+}
+
+func TestReadinessReportsUnavailablePeers(t *testing.T) {
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	org1 := webtest.NewContracts()
+	org1.Return("credentials:CredentialExists", "false")
+	org2 := webtest.NewContracts()
+	org2.Fail("credentials:CredentialExists", status.Error(codes.Unavailable, "connection refused"))
+
+	// The probes need no token
+	router := newAuthRouter(issuer, web.AuthConfig{}, org1, org2)
+	resp := decode(t, serve(t, router, "GET", "/healthz", nil), http.StatusOK)
+	require.True(t, resp.Success)
+
+	resp = decode(t, serve(t, router, "GET", "/readyz", nil), http.StatusServiceUnavailable)
+	require.False(t, resp.Success)
+	var readiness web.Readiness
+	require.NoError(t, json.Unmarshal(resp.Data, &readiness))
+	require.False(t, readiness.Ready)
+	require.Equal(t, "ok", readiness.Identities["Org1"])
+	require.Contains(t, readiness.Identities["Org2"], "connection refused")
+}
+
+// freeAddress returns a local address no server listens on
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+func TestServeShutsDownGracefully(t *testing.T) {
+	contracts := webtest.NewContracts()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	contracts.Handle("credentials:UpdateSkills", func(webtest.Call) ([]byte, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+
+	address := freeAddress(t)
+	url := "http://" + address
+	setup := &web.OrgSetup{
+		OrgName:         "Org1",
+		MSPID:           "Org1MSP",
+		Contracts:       contracts,
+		ChannelID:       channelID,
+		ChaincodeID:     chaincodeID,
+		ListenAddress:   address,
+		ShutdownTimeout: 5 * time.Second,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- web.Serve(ctx, setup) }()
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(url + "/healthz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// An event stream and a transaction in flight
+	events, err := http.Get(url + "/events")
+	require.NoError(t, err)
+	defer events.Body.Close()
+	require.Equal(t, http.StatusOK, events.StatusCode)
+
+	updated := make(chan *http.Response, 1)
+	go func() {
+		body, _ := json.Marshal(web.UpdateSkillsRequest{ChainCodeID: chaincodeID, ChannelID: channelID, NewSkills: "Go"})
+		req, _ := http.NewRequest("PUT", url+"/credentials/cred1/skills", bytes.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+		}
+		updated <- resp
+	}()
+	<-started
+
+	// On shutdown the event stream ends and new connections are refused, while the
+	// transaction completes
+	cancel()
+	_, err = io.ReadAll(events.Body)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(url + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(release)
+	resp := <-updated
+	require.NotNil(t, resp)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, <-served)
+}
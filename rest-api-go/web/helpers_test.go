@@ -0,0 +1,110 @@
+package web_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"rest-api-go/web"
+	"rest-api-go/web/webtest"
+)
+
+const (
+	channelID   = "mychannel"
+	chaincodeID = "basic"
+)
+
+// response is a web.APIResponse whose data is kept as JSON
+type response struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Error   string          `json:"error"`
+	Code    string          `json:"code"`
+}
+
+// newRouter creates the router of an Org1 setup backed by fake contracts
+func newRouter(contracts *webtest.Contracts) http.Handler {
+	return newRouterAs("Org1MSP", contracts)
+}
+
+// newRouterAs creates the router of the setup of an organization backed by fake contracts
+func newRouterAs(mspID string, contracts *webtest.Contracts) http.Handler {
+	return web.NewRouter(&web.OrgSetup{
+		MSPID:            mspID,
+		Contracts:        contracts,
+		ChannelID:        channelID,
+		ChaincodeID:      chaincodeID,
+		AllowedFunctions: []string{"*"},
+	})
+}
+
+// serve sends a request to the router, with body encoded as JSON unless it is nil
+func serve(t *testing.T, router http.Handler, method string, target string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var encoded []byte
+	if body != nil {
+		var err error
+		encoded, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+
+	req := httptest.NewRequest(method, target, bytes.NewReader(encoded))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode checks the status of a response and decodes its body
+func decode(t *testing.T, rec *httptest.ResponseRecorder, expectedStatus int) response {
+	t.Helper()
+
+	require.Equal(t, expectedStatus, rec.Code, rec.Body.String())
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var resp response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+// decodeData checks the status of a successful response and decodes its data into v
+func decodeData(t *testing.T, rec *httptest.ResponseRecorder, expectedStatus int, v interface{}) {
+	t.Helper()
+
+	resp := decode(t, rec, expectedStatus)
+	require.True(t, resp.Success)
+	require.NoError(t, json.Unmarshal(resp.Data, v))
+}
+
+// lastCall returns the last chaincode call received by the fake contracts
+func lastCall(t *testing.T, contracts *webtest.Contracts) webtest.Call {
+	t.Helper()
+
+	calls := contracts.Calls()
+	require.NotEmpty(t, calls)
+	return calls[len(calls)-1]
+}
+
+// requireCall checks a chaincode call went to the test chaincode with the given arguments
+func requireCall(t *testing.T, call webtest.Call, submitted bool, function string, args ...string) {
+	t.Helper()
+
+	require.Equal(t, channelID, call.ChannelID)
+	require.Equal(t, chaincodeID, call.ChaincodeID)
+	require.Equal(t, submitted, call.Submitted)
+	require.Equal(t, function, call.Function)
+	if len(args) == 0 {
+		require.Empty(t, call.Args)
+	} else {
+		require.Equal(t, args, call.Args)
+	}
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// IdentityHeader is the request header naming the identity a request is made as
+const IdentityHeader = "X-Fabric-Identity"
+
+// Client is an identity requests are made as, with the contracts reachable through its connection
+type Client struct {
+	Name      string
+	MSPID     string
+	Contracts ContractProvider
+}
+
+// identityContextKey and clientContextKey locate the identity of a request in its context
+type (
+	identityContextKey struct{}
+	clientContextKey   struct{}
+)
+
+// ContextWithIdentity returns a context in which requests are made as the named identity.
+// Authentication sets it from the claims of the token.
+func ContextWithIdentity(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, name)
+}
+
+// IdentityMiddleware resolves the identity each request is made as, from its X-Fabric-Identity
+// header or else its context: a configured identity or a user of the wallet. The header is only
+// accepted from an authenticated caller whose token maps to the identity it names, so that it
+// never reaches a configured identity the caller is not mapped to. Requests naming no identity
+// are made as the organization's.
+func (setup *OrgSetup) IdentityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, _ := r.Context().Value(identityContextKey{}).(string)
+		if header := r.Header.Get(IdentityHeader); header != "" {
+			if principal, ok := PrincipalFromContext(r.Context()); !ok || principal.Identity != header {
+				HandleError(w, "Permission denied: "+IdentityHeader+" must name the identity the authenticated caller is mapped to", http.StatusForbidden)
+				return
+			}
+			name = header
+		}
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client, ok := setup.Clients[name]
+		if !ok {
+			var err error
+			client, err = setup.walletClient(name)
+			if errors.Is(err, ErrIdentityNotFound) {
+				HandleError(w, "Unknown identity "+name, http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				log.Printf("Error loading identity %s: %v", name, err)
+				HandleError(w, "Failed to load identity "+name, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientContextKey{}, client)))
+	})
+}
+
+// client returns the identity a request is made as
+func (setup *OrgSetup) client(r *http.Request) *Client {
+	if client, ok := r.Context().Value(clientContextKey{}).(*Client); ok {
+		return client
+	}
+
+	return setup.defaultClient()
+}
+
+// defaultClient returns the identity of the organization
+func (setup *OrgSetup) defaultClient() *Client {
+	return &Client{Name: setup.OrgName, MSPID: setup.MSPID, Contracts: setup.Contracts}
+}
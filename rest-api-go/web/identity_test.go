@@ -0,0 +1,100 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"rest-api-go/web"
+	"rest-api-go/web/webtest"
+)
+
+// newMultiIdentityRouter creates the router of an Org1 setup whose requests can also be made
+// as Org2, each identity with its own fake contracts
+func newMultiIdentityRouter(org1 *webtest.Contracts, org2 *webtest.Contracts) http.Handler {
+	return web.NewRouter(&web.OrgSetup{
+		OrgName:   "Org1",
+		MSPID:     "Org1MSP",
+		Contracts: org1,
+		Clients: map[string]*web.Client{
+			"Org1": {Name: "Org1", MSPID: "Org1MSP", Contracts: org1},
+			"Org2": {Name: "Org2", MSPID: "Org2MSP", Contracts: org2},
+		},
+		ChannelID:        channelID,
+		ChaincodeID:      chaincodeID,
+		AllowedFunctions: []string{"*"},
+	})
+}
+
+func TestIdentityHeader(t *testing.T) {
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	org1 := webtest.NewContracts()
+	org2 := webtest.NewContracts()
+	org1.Return("credentials:GetAllCredentials", "[]")
+	org2.Return("credentials:GetAllCredentials", "[]")
+	router := newAuthRouter(issuer, web.AuthConfig{RoleIdentities: map[string]string{"org2-member": "Org2"}}, org1, org2)
+
+	// Requests are made as the organization unless the header names another identity
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, bearer(issuer.Token(nil))...), http.StatusOK)
+	requireCall(t, lastCall(t, org1), false, "credentials:GetAllCredentials")
+
+	calls := len(org1.Calls())
+	token := issuer.Token(map[string]interface{}{"roles": "org2-member"})
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, append(bearer(token), web.IdentityHeader, "Org2")...), http.StatusOK)
+	require.Len(t, org1.Calls(), calls)
+	requireCall(t, lastCall(t, org2), false, "credentials:GetAllCredentials")
+
+	// The header names the identity the caller is mapped to only, whatever the subject of the token
+	for _, claims := range []map[string]interface{}{nil, {"sub": "Org2"}} {
+		resp := decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, append(bearer(issuer.Token(claims)), web.IdentityHeader, "Org2")...), http.StatusForbidden)
+		require.Contains(t, resp.Error, "Permission denied")
+	}
+	require.Len(t, org1.Calls(), calls)
+	require.Len(t, org2.Calls(), 1)
+}
+
+func TestIdentityHeaderRequiresAuthentication(t *testing.T) {
+	org1 := webtest.NewContracts()
+	org2 := webtest.NewContracts()
+	router := newMultiIdentityRouter(org1, org2)
+	calls := len(org1.Calls())
+
+	resp := decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, web.IdentityHeader, "Org2"), http.StatusForbidden)
+	require.Contains(t, resp.Error, "Permission denied")
+	require.Len(t, org1.Calls(), calls)
+	require.Empty(t, org2.Calls())
+}
+
+func TestUnknownIdentity(t *testing.T) {
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	org1 := webtest.NewContracts()
+	router := newAuthRouter(issuer, web.AuthConfig{IdentityClaim: "fabric_identity"}, org1, webtest.NewContracts())
+	calls := len(org1.Calls())
+
+	token := issuer.Token(map[string]interface{}{"fabric_identity": "Org3"})
+	resp := decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, append(bearer(token), web.IdentityHeader, "Org3")...), http.StatusForbidden)
+	require.Contains(t, resp.Error, "Org3")
+	require.Len(t, org1.Calls(), calls)
+}
+
+func TestContextIdentity(t *testing.T) {
+	org1 := webtest.NewContracts()
+	org2 := webtest.NewContracts()
+	org2.Return("credentials:GetAllCredentials", "[]")
+	router := newMultiIdentityRouter(org1, org2)
+	calls := len(org1.Calls())
+
+	// As set by authentication
+	req := httptest.NewRequest("GET", "/credentials/all"+chaincodeQuery, nil)
+	req = req.WithContext(web.ContextWithIdentity(req.Context(), "Org2"))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	requireCall(t, lastCall(t, org2), false, "credentials:GetAllCredentials")
+	require.Len(t, org1.Calls(), calls)
+}
@@ -8,95 +8,157 @@ import (
 	"path"
 	"time"
 
-	"github.com/hyperledger/fabric-gateway/pkg/client"
-	"github.com/hyperledger/fabric-gateway/pkg/hash"
 	"github.com/hyperledger/fabric-gateway/pkg/identity"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials"
 )
 
-// Initialize the setup for the organization.
+// Timeouts bounds the calls made through the Gateway
+type Timeouts struct {
+	Evaluate     time.Duration
+	Endorse      time.Duration
+	Submit       time.Duration
+	CommitStatus time.Duration
+}
+
+// DefaultTimeouts are the timeouts of the Gateway calls unless configured otherwise
+var DefaultTimeouts = Timeouts{
+	Evaluate:     5 * time.Second,
+	Endorse:      15 * time.Second,
+	Submit:       5 * time.Second,
+	CommitStatus: 1 * time.Minute,
+}
+
+// withDefaults returns the timeouts with their zero fields set to the defaults
+func (timeouts Timeouts) withDefaults() Timeouts {
+	if timeouts.Evaluate == 0 {
+		timeouts.Evaluate = DefaultTimeouts.Evaluate
+	}
+	if timeouts.Endorse == 0 {
+		timeouts.Endorse = DefaultTimeouts.Endorse
+	}
+	if timeouts.Submit == 0 {
+		timeouts.Submit = DefaultTimeouts.Submit
+	}
+	if timeouts.CommitStatus == 0 {
+		timeouts.CommitStatus = DefaultTimeouts.CommitStatus
+	}
+
+	return timeouts
+}
+
+// Initialize the setup for the organization. The Gateway connections of its identity and of
+// the other configured identities are made on first use.
 func Initialize(setup OrgSetup) (*OrgSetup, error) {
-	log.Printf("Initializing connection for %s...\n", setup.OrgName)
-	clientConnection := setup.newGrpcConnection()
-	id := setup.newIdentity()
-	sign := setup.newSign()
-
-	gateway, err := client.Connect(
-		id,
-		client.WithSign(sign),
-		client.WithHash(hash.SHA256),
-		client.WithClientConnection(clientConnection),
-		client.WithEvaluateTimeout(5*time.Second),
-		client.WithEndorseTimeout(15*time.Second),
-		client.WithSubmitTimeout(5*time.Second),
-		client.WithCommitStatusTimeout(1*time.Minute),
-	)
+	log.Printf("Initializing connections for %s...\n", setup.OrgName)
+	identities := append([]Identity{setup.identity()}, setup.Identities...)
+	pool, err := NewGatewayPool(setup.Timeouts, setup.Resilience, identities...)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	setup.Gateways = pool
+	if setup.Metrics == nil {
+		setup.Metrics = NewMetrics()
+	}
+	setup.Contracts = pool.Contracts(setup.OrgName)
+	setup.Clients = make(map[string]*Client)
+	for _, id := range identities {
+		setup.Clients[id.Name] = &Client{Name: id.Name, MSPID: id.MSPID, Contracts: pool.Contracts(id.Name)}
 	}
-	setup.Gateway = *gateway
 	log.Println("Initialization complete")
 	return &setup, nil
 }
 
-// newGrpcConnection creates a gRPC connection to the Gateway server.
-func (setup OrgSetup) newGrpcConnection() *grpc.ClientConn {
-	certificate, err := loadCertificate(setup.TLSCertPath)
+// identity returns the identity of the organization, named after it
+func (setup OrgSetup) identity() Identity {
+	return Identity{
+		Name:         setup.OrgName,
+		MSPID:        setup.MSPID,
+		CertPath:     setup.CertPath,
+		KeyPath:      setup.KeyPath,
+		TLSCertPath:  setup.TLSCertPath,
+		TLSCertPEM:   setup.TLSCertPEM,
+		PeerEndpoint: setup.PeerEndpoint,
+		GatewayPeer:  setup.GatewayPeer,
+	}
+}
+
+// newGrpcConnection creates a gRPC connection to the Gateway server. The connection is made on
+// its first call, and made again with exponential backoff whenever the peer is lost.
+func (id Identity) newGrpcConnection(resilience Resilience) (*grpc.ClientConn, error) {
+	var certificate *x509.Certificate
+	var err error
+	if len(id.TLSCertPEM) > 0 {
+		certificate, err = identity.CertificateFromPEM(id.TLSCertPEM)
+	} else {
+		certificate, err = loadCertificate(id.TLSCertPath)
+	}
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to load TLS certificate of %s: %w", id.PeerEndpoint, err)
 	}
 
 	certPool := x509.NewCertPool()
 	certPool.AddCert(certificate)
-	transportCredentials := credentials.NewClientTLSFromCert(certPool, setup.GatewayPeer)
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, id.GatewayPeer)
 
-	connection, err := grpc.Dial(setup.PeerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	reconnect := backoff.DefaultConfig
+	reconnect.MaxDelay = resilience.ReconnectMaxDelay
+	if reconnect.BaseDelay > reconnect.MaxDelay {
+		reconnect.BaseDelay = reconnect.MaxDelay
+	}
+	connection, err := grpc.NewClient(id.PeerEndpoint,
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: reconnect, MinConnectTimeout: 5 * time.Second}),
+	)
 	if err != nil {
-		panic(fmt.Errorf("failed to create gRPC connection: %w", err))
+		return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
 	}
 
-	return connection
+	return connection, nil
 }
 
 // newIdentity creates a client identity for this Gateway connection using an X.509 certificate.
-func (setup OrgSetup) newIdentity() *identity.X509Identity {
-	certificate, err := loadCertificate(setup.CertPath)
-	if err != nil {
-		panic(err)
+func (id Identity) newIdentity() (*identity.X509Identity, error) {
+	var certificate *x509.Certificate
+	var err error
+	if len(id.Certificate) > 0 {
+		certificate, err = identity.CertificateFromPEM(id.Certificate)
+	} else {
+		certificate, err = loadCertificate(id.CertPath)
 	}
-
-	id, err := identity.NewX509Identity(setup.MSPID, certificate)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to load certificate of %s: %w", id.Name, err)
 	}
 
-	return id
+	return identity.NewX509Identity(id.MSPID, certificate)
 }
 
 // newSign creates a function that generates a digital signature from a message digest using a private key.
-func (setup OrgSetup) newSign() identity.Sign {
-	files, err := os.ReadDir(setup.KeyPath)
-	if err != nil {
-		panic(fmt.Errorf("failed to read private key directory: %w", err))
-	}
-	privateKeyPEM, err := os.ReadFile(path.Join(setup.KeyPath, files[0].Name()))
-
-	if err != nil {
-		panic(fmt.Errorf("failed to read private key file: %w", err))
+func (id Identity) newSign() (identity.Sign, error) {
+	privateKeyPEM := id.PrivateKey
+	if len(privateKeyPEM) == 0 {
+		files, err := os.ReadDir(id.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key directory: %w", err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no private key in %s", id.KeyPath)
+		}
+		privateKeyPEM, err = os.ReadFile(path.Join(id.KeyPath, files[0].Name()))
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file: %w", err)
+		}
 	}
 
 	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
 	if err != nil {
-		panic(err)
-	}
-
-	sign, err := identity.NewPrivateKeySign(privateKey)
-	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to load private key of %s: %w", id.Name, err)
 	}
 
-	return sign
+	return identity.NewPrivateKeySign(privateKey)
 }
 
 func loadCertificate(filename string) (*x509.Certificate, error) {
@@ -1,15 +1,16 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/hyperledger/fabric-gateway/pkg/client"
 )
 
 // Credential models the credential data for requests
@@ -38,17 +39,38 @@ type CredentialRequest struct {
 // 	VerifiedBy string `json:"verifiedBy"`
 // }
 
+// IdempotencyKeyHeader is the request header carrying the optional idempotency key of a
+// transaction. A request replayed with the same key returns the result of the original one.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyTransientKey is the transient data entry in which the chaincode expects the key
+const idempotencyTransientKey = "idempotencyKey"
+
 // TransactionResult holds the result of a blockchain transaction
 type TransactionResult struct {
 	TxID     string `json:"transactionId"`
 	Response string `json:"response"`
+	Replayed bool   `json:"replayed,omitempty"` // The result is the one of an earlier request with the same idempotency key
+}
+
+// CredentialCreatedResult is returned by the create handlers with the ID of the new credential
+type CredentialCreatedResult struct {
+	TransactionResult
+	CredentialID string `json:"credentialId"`
+}
+
+// IdempotencyRecord is the chaincode record of a transaction submitted with an idempotency key
+type IdempotencyRecord struct {
+	IdempotencyKey string `json:"IdempotencyKey"`
+	Function       string `json:"Function"`
+	RequestHash    string `json:"RequestHash"` // SHA-256 of the JSON array of the function and its arguments
+	TxID           string `json:"TxID"`
+	ResultHash     string `json:"ResultHash"` // SHA-256 of the result, read from the block of the transaction
 }
 
 // ValidateCredential validates credential fields
 func ValidateCredential(cred *Credential, credType string) error {
-	if cred.CredentialID == "" {
-		return errors.New("credentialId is required")
-	}
+	// An empty credentialId lets the chaincode assign one
 	if cred.TalentID == "" {
 		return errors.New("talentId is required")
 	}
@@ -103,12 +125,11 @@ func (setup *OrgSetup) CreateAcademicCredentialHandler(w http.ResponseWriter, r
         return
     }
 
-    // Get the network and contract from the gateway
-    network := setup.Gateway.GetNetwork(request.ChannelID)
-    contract := network.GetContract(request.ChainCodeID)
+    // Get the client identity of the request
+    client := setup.client(r)
 
     // Define function name and arguments
-    function := "CreateAcademicCredential"
+    function := "credentials:CreateAcademicCredential"
     args := []string{
         request.Credential.CredentialID,
         request.Credential.TalentID,
@@ -123,14 +144,17 @@ func (setup *OrgSetup) CreateAcademicCredentialHandler(w http.ResponseWriter, r
     log.Printf("channel: %s, chaincode: %s, function: %s, args: %v\n", request.ChannelID, request.ChainCodeID, function, args)
 
     // Execute the transaction
-    result, err := executeTransaction(contract, function, args)
+    result, err := setup.executeTransaction(r.Context(), client, request.ChannelID, request.ChainCodeID, function, args, r.Header.Get(IdempotencyKeyHeader))
     if err != nil {
-        HandleError(w, "Transaction failed: "+err.Error(), http.StatusInternalServerError)
+        HandleTransactionError(w, "Transaction failed", err)
         return
     }
     
-    // Send success response
-    HandleSuccess(w, "Academic credential created successfully", result)
+    // The chaincode returns the ID, which it assigns when none was given
+    HandleCreated(w, "Academic credential created successfully", "/credentials/"+url.PathEscape(result.Response), CredentialCreatedResult{
+        TransactionResult: *result,
+        CredentialID:      result.Response,
+    })
 }
 
 
@@ -158,12 +182,11 @@ func (setup *OrgSetup) CreateProfessionalCredentialHandler(w http.ResponseWriter
         return
     }
 
-    // Get the network and contract from the gateway
-    network := setup.Gateway.GetNetwork(request.ChannelID)
-    contract := network.GetContract(request.ChainCodeID)
+    // Get the client identity of the request
+    client := setup.client(r)
 
     // Define function name and arguments
-    function := "CreateProfessionalCredential"
+    function := "credentials:CreateProfessionalCredential"
     args := []string{
         request.Credential.CredentialID,
         request.Credential.TalentID,
@@ -178,22 +201,25 @@ func (setup *OrgSetup) CreateProfessionalCredentialHandler(w http.ResponseWriter
     log.Printf("channel: %s, chaincode: %s, function: %s, args: %v\n", request.ChannelID, request.ChainCodeID, function, args)
 
     // Execute the transaction
-    result, err := executeTransaction(contract, function, args)
+    result, err := setup.executeTransaction(r.Context(), client, request.ChannelID, request.ChainCodeID, function, args, r.Header.Get(IdempotencyKeyHeader))
     if err != nil {
-        HandleError(w, "Transaction failed: "+err.Error(), http.StatusInternalServerError)
+        HandleTransactionError(w, "Transaction failed", err)
         return
     }
 
-    // Send success response
-    HandleSuccess(w, "Professional credential created successfully", result)
+    // The chaincode returns the ID, which it assigns when none was given
+    HandleCreated(w, "Professional credential created successfully", "/credentials/"+url.PathEscape(result.Response), CredentialCreatedResult{
+        TransactionResult: *result,
+        CredentialID:      result.Response,
+    })
 }
 
 func (setup *OrgSetup) ApproveCredentialHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Received Approve Credential request")
 
-	// Check if the client is from Org1MSP
-	if setup.MSPID != "Org1MSP" {
-		HandleError(w, "Permission denied: only users from Org1MSP can approve credentials", http.StatusForbidden)
+	// Only issuers can approve credentials
+	if !setup.hasRole(r, RoleIssuer) {
+		HandleError(w, "Permission denied: only issuers can approve credentials", http.StatusForbidden)
 		return
 	}
 
@@ -216,12 +242,11 @@ func (setup *OrgSetup) ApproveCredentialHandler(w http.ResponseWriter, r *http.R
 	// For simplicity, we hardcode "VerifiedBy" as Org1's name
 	verifiedBy := "Org1"
 
-	// Get the network and contract from the gateway
-	network := setup.Gateway.GetNetwork(channelID)
-	contract := network.GetContract(chaincodeID)
+	// Get the client identity of the request
+	client := setup.client(r)
 
 	// Define function name and arguments
-	function := "UpdateVerificationStatus"
+	function := "issuers:UpdateVerificationStatus"
 	args := []string{
 		credentialID,   // credentialID
 		"Verified",     // verification status
@@ -231,9 +256,9 @@ func (setup *OrgSetup) ApproveCredentialHandler(w http.ResponseWriter, r *http.R
 	log.Printf("channel: %s, chaincode: %s, function: %s, args: %v\n", channelID, chaincodeID, function, args)
 
 	// Execute the transaction
-	result, err := executeTransaction(contract, function, args)
+	result, err := setup.executeTransaction(r.Context(), client, channelID, chaincodeID, function, args, r.Header.Get(IdempotencyKeyHeader))
 	if err != nil {
-		HandleError(w, "Transaction failed: "+err.Error(), http.StatusInternalServerError)
+		HandleTransactionError(w, "Transaction failed", err)
 		return
 	}
 
@@ -244,9 +269,9 @@ func (setup *OrgSetup) ApproveCredentialHandler(w http.ResponseWriter, r *http.R
 func (setup *OrgSetup) RevokeCredentialHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Received Revoke Credential request")
 
-	// Ensure only Org1 can revoke credentials
-	if setup.MSPID != "Org1MSP" {
-		HandleError(w, "Permission denied: only users from Org1MSP can revoke credentials", http.StatusForbidden)
+	// Only issuers can revoke credentials
+	if !setup.hasRole(r, RoleIssuer) {
+		HandleError(w, "Permission denied: only issuers can revoke credentials", http.StatusForbidden)
 		return
 	}
 
@@ -269,12 +294,11 @@ func (setup *OrgSetup) RevokeCredentialHandler(w http.ResponseWriter, r *http.Re
 	// Hardcoded verifier for now
 	verifiedBy := "Org1"
 
-	// Get the network and contract
-	network := setup.Gateway.GetNetwork(channelID)
-	contract := network.GetContract(chaincodeID)
+	// Get the client identity of the request
+	client := setup.client(r)
 
 	// Prepare arguments
-	function := "UpdateVerificationStatus"
+	function := "issuers:UpdateVerificationStatus"
 	args := []string{
 		credentialID,
 		"Revoked",
@@ -284,9 +308,9 @@ func (setup *OrgSetup) RevokeCredentialHandler(w http.ResponseWriter, r *http.Re
 	log.Printf("channel: %s, chaincode: %s, function: %s, args: %v\n", channelID, chaincodeID, function, args)
 
 	// Execute transaction
-	result, err := executeTransaction(contract, function, args)
+	result, err := setup.executeTransaction(r.Context(), client, channelID, chaincodeID, function, args, r.Header.Get(IdempotencyKeyHeader))
 	if err != nil {
-		HandleError(w, "Transaction failed: "+err.Error(), http.StatusInternalServerError)
+		HandleTransactionError(w, "Transaction failed", err)
 		return
 	}
 
@@ -294,14 +318,64 @@ func (setup *OrgSetup) RevokeCredentialHandler(w http.ResponseWriter, r *http.Re
 	HandleSuccess(w, "Credential revoked successfully", result)
 }
 
+// ReissueRequest models the data for credential reissue requests
+type ReissueRequest struct {
+	ChainCodeID     string            `json:"chaincodeid"`
+	ChannelID       string            `json:"channelid"`
+	NewCredentialID string            `json:"newCredentialId,omitempty"` // Assigned by the ledger when empty
+	Corrections     map[string]string `json:"corrections"`               // e.g. {"Institution": "..."}
+}
+
+// ReissueCredentialHandler handles requests to replace a credential with a corrected copy
+func (setup *OrgSetup) ReissueCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received Reissue Credential request")
+
+	// Only issuers can reissue credentials
+	if !setup.hasRole(r, RoleIssuer) {
+		HandleError(w, "Permission denied: only issuers can reissue credentials", http.StatusForbidden)
+		return
+	}
+
+	credentialID := mux.Vars(r)["id"]
+
+	var req ReissueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		HandleError(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Corrections == nil {
+		req.Corrections = map[string]string{}
+	}
+	corrections, err := json.Marshal(req.Corrections)
+	if err != nil {
+		HandleError(w, "Invalid corrections: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client := setup.client(r)
+
+	args := []string{credentialID, req.NewCredentialID, string(corrections)}
+
+	result, err := setup.executeTransaction(r.Context(), client, req.ChannelID, req.ChainCodeID, "issuers:ReissueCredential", args, r.Header.Get(IdempotencyKeyHeader))
+	if err != nil {
+		HandleTransactionError(w, "Transaction failed", err)
+		return
+	}
+
+	HandleCreated(w, "Credential reissued successfully", "/credentials/"+url.PathEscape(result.Response), CredentialCreatedResult{
+		TransactionResult: *result,
+		CredentialID:      result.Response,
+	})
+}
+
 func (setup *OrgSetup) DeleteCredentialHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Received Delete Credential request")
 
-	// // Only Org1 can delete credentials (nope)
-	// if setup.MSPID != "Org1MSP" {
-	// 	HandleError(w, "Permission denied: only Org1MSP can delete credentials", http.StatusForbidden)
-	// 	return
-	// }
+	// Only issuers and admins can delete credentials
+	if !setup.hasRole(r, RoleIssuer) && !setup.hasRole(r, RoleAdmin) {
+		HandleError(w, "Permission denied: only issuers and admins can delete credentials", http.StatusForbidden)
+		return
+	}
 
 	vars := mux.Vars(r)
 	credentialID := vars["id"]
@@ -317,12 +391,11 @@ func (setup *OrgSetup) DeleteCredentialHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	network := setup.Gateway.GetNetwork(channelID)
-	contract := network.GetContract(chaincodeID)
+	client := setup.client(r)
 
-	result, err := executeTransaction(contract, "DeleteTalentCredential", []string{credentialID})
+	result, err := setup.executeTransaction(r.Context(), client, channelID, chaincodeID, "credentials:DeleteTalentCredential", []string{credentialID}, r.Header.Get(IdempotencyKeyHeader))
 	if err != nil {
-		HandleError(w, "Transaction failed: "+err.Error(), http.StatusInternalServerError)
+		HandleTransactionError(w, "Transaction failed", err)
 		return
 	}
 
@@ -347,14 +420,13 @@ func (setup *OrgSetup) UpdateSkillsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	network := setup.Gateway.GetNetwork(req.ChannelID)
-	contract := network.GetContract(req.ChainCodeID)
+	client := setup.client(r)
 
 	args := []string{credentialID, req.NewSkills}
 
-	result, err := executeTransaction(contract, "UpdateSkills", args)
+	result, err := setup.executeTransaction(r.Context(), client, req.ChannelID, req.ChainCodeID, "credentials:UpdateSkills", args, r.Header.Get(IdempotencyKeyHeader))
 	if err != nil {
-		HandleError(w, "Transaction failed: "+err.Error(), http.StatusInternalServerError)
+		HandleTransactionError(w, "Transaction failed", err)
 		return
 	}
 
@@ -364,6 +436,7 @@ func (setup *OrgSetup) UpdateSkillsHandler(w http.ResponseWriter, r *http.Reques
 type UpdateNameRequest struct {
 	NewFirstName string `json:"newFirstName"`
 	NewLastName  string `json:"newLastName"`
+	EvidenceHash string `json:"evidenceHash"` // SHA-256 of the document supporting the name change
 	ChainCodeID  string `json:"chaincodeid"`
 	ChannelID    string `json:"channelid"`
 }
@@ -380,43 +453,156 @@ func (setup *OrgSetup) UpdateNameHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	network := setup.Gateway.GetNetwork(req.ChannelID)
-	contract := network.GetContract(req.ChainCodeID)
+	client := setup.client(r)
 
-	args := []string{credentialID, req.NewFirstName, req.NewLastName}
+	args := []string{credentialID, req.NewFirstName, req.NewLastName, req.EvidenceHash}
 
-	result, err := executeTransaction(contract, "UpdateName", args)
+	result, err := setup.executeTransaction(r.Context(), client, req.ChannelID, req.ChainCodeID, "credentials:UpdateName", args, r.Header.Get(IdempotencyKeyHeader))
 	if err != nil {
-		HandleError(w, "Transaction failed: "+err.Error(), http.StatusInternalServerError)
+		HandleTransactionError(w, "Transaction failed", err)
 		return
 	}
 
 	HandleSuccess(w, "Name updated successfully", result)
 }
 
-// executeTransaction handles the common transaction execution logic
-func executeTransaction(contract *client.Contract, function string, args []string) (*TransactionResult, error) {
-	// Create the transaction proposal
-	txnProposal, err := contract.NewProposal(function, client.WithArguments(args...))
+// EndorseSkillRequest models the data for skill endorsement requests
+type EndorseSkillRequest struct {
+	ChainCodeID string `json:"chaincodeid"`
+	ChannelID   string `json:"channelid"`
+	Comment     string `json:"comment"`
+}
+
+// EndorseSkillHandler handles requests to endorse a skill of a professional credential
+func (setup *OrgSetup) EndorseSkillHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received Endorse Skill request")
+
+	vars := mux.Vars(r)
+	credentialID := vars["id"]
+	skill := vars["skill"]
+
+	var req EndorseSkillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		HandleError(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client := setup.client(r)
+
+	args := []string{credentialID, skill, req.Comment}
+
+	result, err := setup.executeTransaction(r.Context(), client, req.ChannelID, req.ChainCodeID, "endorsements:EndorseSkill", args, r.Header.Get(IdempotencyKeyHeader))
 	if err != nil {
-		return nil, fmt.Errorf("error creating txn proposal: %s", err)
+		HandleTransactionError(w, "Transaction failed", err)
+		return
 	}
-	
-	// Endorse the transaction
-	txnEndorsed, err := txnProposal.Endorse()
+
+	HandleSuccess(w, "Skill endorsed successfully", result)
+}
+
+// TalentProfileRequest models the data for talent profile creation requests
+type TalentProfileRequest struct {
+	ChainCodeID string `json:"chaincodeid"`
+	ChannelID   string `json:"channelid"`
+	TalentID    string `json:"talentId"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	ContactHash string `json:"contactHash"`
+}
+
+// CreateTalentProfileHandler handles requests to register a talent profile
+func (setup *OrgSetup) CreateTalentProfileHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received Create Talent Profile request")
+
+	var req TalentProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		HandleError(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.TalentID == "" || req.FirstName == "" || req.LastName == "" {
+		HandleError(w, "talentId, firstName and lastName are required", http.StatusBadRequest)
+		return
+	}
+
+	client := setup.client(r)
+
+	args := []string{req.TalentID, req.FirstName, req.LastName, req.ContactHash}
+
+	result, err := setup.executeTransaction(r.Context(), client, req.ChannelID, req.ChainCodeID, "talents:CreateTalentProfile", args, r.Header.Get(IdempotencyKeyHeader))
 	if err != nil {
-		return nil, fmt.Errorf("error endorsing txn: %s", err)
+		HandleTransactionError(w, "Transaction failed", err)
+		return
 	}
-	
-	// Submit the transaction
-	txnCommitted, err := txnEndorsed.Submit()
+
+	HandleSuccess(w, "Talent profile created successfully", result)
+}
+
+// UpdateTalentNameHandler handles requests to rename a talent across all of their credentials
+func (setup *OrgSetup) UpdateTalentNameHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received Update Talent Name request")
+
+	vars := mux.Vars(r)
+	talentID := vars["talentId"]
+
+	var req UpdateNameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		HandleError(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client := setup.client(r)
+
+	args := []string{talentID, req.NewFirstName, req.NewLastName, req.EvidenceHash}
+
+	result, err := setup.executeTransaction(r.Context(), client, req.ChannelID, req.ChainCodeID, "talents:UpdateTalentName", args, r.Header.Get(IdempotencyKeyHeader))
 	if err != nil {
-		return nil, fmt.Errorf("error submitting transaction: %s", err)
+		HandleTransactionError(w, "Transaction failed", err)
+		return
 	}
-	
-	// Return the result
-	return &TransactionResult{
-		TxID:     txnCommitted.TransactionID(),
-		Response: string(txnEndorsed.Result()),
-	}, nil
-}
\ No newline at end of file
+
+	HandleSuccess(w, "Talent name updated successfully", result)
+}
+
+// executeTransaction handles the common transaction execution logic. When an idempotency key
+// is given and the chaincode reports it as already applied, the original result is returned,
+// provided the key was recorded for the same request.
+func (setup *OrgSetup) executeTransaction(ctx context.Context, client *Client, channelID, chaincodeID, function string, args []string, idempotencyKey string) (*TransactionResult, error) {
+	contract := client.Contracts.Contract(channelID, chaincodeID)
+	proposal := Proposal{
+		Function:  function,
+		Args:      args,
+		Transient: setup.transientData(client.MSPID, idempotencyKey),
+	}
+	if setup.Metrics != nil {
+		proposal.ObservePhase = func(phase string, duration time.Duration) {
+			setup.Metrics.observePhase(function, phase, duration)
+		}
+	}
+
+	done := setup.Metrics.transactionStarted(function)
+	result, err := contract.Submit(proposal)
+	done()
+	if err == nil {
+		setup.Metrics.transactionCommitted(function, args)
+	}
+	if err == nil || idempotencyKey == "" {
+		return result, err
+	}
+
+	if chaincodeErr := ParseChaincodeError(err); chaincodeErr == nil || chaincodeErr.Code != CodeAlreadyApplied {
+		return nil, err
+	}
+	replayed, replayErr := replayTransaction(ctx, client, channelID, chaincodeID, function, args, idempotencyKey)
+	if replayErr != nil {
+		log.Printf("Could not replay %s with idempotency key %s: %v\n", function, idempotencyKey, replayErr)
+		var reusedErr *ChaincodeError
+		if errors.As(replayErr, &reusedErr) {
+			return nil, reusedErr
+		}
+		return nil, err
+	}
+
+	log.Printf("Replayed %s with idempotency key %s from transaction %s\n", function, idempotencyKey, replayed.TxID)
+	return replayed, nil
+}
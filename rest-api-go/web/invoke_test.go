@@ -0,0 +1,274 @@
+package web_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"rest-api-go/web"
+	"rest-api-go/web/webtest"
+)
+
+// chaincodeQuery is the query string selecting the test chaincode
+const chaincodeQuery = "?channelid=" + channelID + "&chaincodeid=" + chaincodeID
+
+func TestCreateAcademicCredential(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("credentials:CreateAcademicCredential", "cred1")
+	router := newRouter(contracts)
+
+	rec := serve(t, router, "POST", "/credentials/academic", web.CredentialRequest{
+		ChainCodeID: chaincodeID,
+		ChannelID:   channelID,
+		Credential: web.Credential{
+			TalentID:    "talent1",
+			FirstName:   "Jane",
+			LastName:    "Doe",
+			Skills:      "Go, SQL",
+			Education:   "BSc Computer Science",
+			Institution: "Concordia University",
+		},
+	})
+
+	var result web.CredentialCreatedResult
+	decodeData(t, rec, http.StatusCreated, &result)
+	require.Equal(t, "/credentials/cred1", rec.Header().Get("Location"))
+	require.Equal(t, web.CredentialCreatedResult{
+		TransactionResult: web.TransactionResult{TxID: "tx1", Response: "cred1"},
+		CredentialID:      "cred1",
+	}, result)
+	requireCall(t, lastCall(t, contracts), true, "credentials:CreateAcademicCredential", "", "talent1", "Jane", "Doe", "Go, SQL", "BSc Computer Science", "Concordia University")
+}
+
+func TestCreateProfessionalCredential(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("credentials:CreateProfessionalCredential", "cred 2")
+	router := newRouter(contracts)
+
+	rec := serve(t, router, "POST", "/credentials/professional", web.CredentialRequest{
+		ChainCodeID: chaincodeID,
+		ChannelID:   channelID,
+		Credential: web.Credential{
+			CredentialID:   "cred 2",
+			TalentID:       "talent1",
+			FirstName:      "Jane",
+			LastName:       "Doe",
+			Skills:         "Go",
+			WorkExperience: "5 years",
+			Company:        "Acme",
+		},
+	})
+
+	var result web.CredentialCreatedResult
+	decodeData(t, rec, http.StatusCreated, &result)
+	require.Equal(t, "/credentials/cred%202", rec.Header().Get("Location"))
+	require.Equal(t, "cred 2", result.CredentialID)
+	requireCall(t, lastCall(t, contracts), true, "credentials:CreateProfessionalCredential", "cred 2", "talent1", "Jane", "Doe", "Go", "5 years", "Acme")
+}
+
+func TestCreateCredentialValidatesRequest(t *testing.T) {
+	contracts := webtest.NewContracts()
+	router := newRouter(contracts)
+	calls := len(contracts.Calls())
+
+	for name, test := range map[string]struct {
+		path string
+		body interface{}
+	}{
+		"invalid JSON":        {"/credentials/academic", "not an object"},
+		"missing talent":      {"/credentials/academic", web.CredentialRequest{Credential: web.Credential{FirstName: "Jane", LastName: "Doe", Education: "BSc", Institution: "Concordia"}}},
+		"missing institution": {"/credentials/academic", web.CredentialRequest{Credential: web.Credential{TalentID: "talent1", FirstName: "Jane", LastName: "Doe", Education: "BSc"}}},
+		"missing company":     {"/credentials/professional", web.CredentialRequest{Credential: web.Credential{TalentID: "talent1", FirstName: "Jane", LastName: "Doe", WorkExperience: "5 years"}}},
+		"missing work":        {"/credentials/professional", web.CredentialRequest{Credential: web.Credential{TalentID: "talent1", FirstName: "Jane", LastName: "Doe", Company: "Acme"}}},
+		"missing name":        {"/credentials/professional", web.CredentialRequest{Credential: web.Credential{TalentID: "talent1", LastName: "Doe", WorkExperience: "5 years", Company: "Acme"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := decode(t, serve(t, router, "POST", test.path, test.body), http.StatusBadRequest)
+			require.False(t, resp.Success)
+			require.NotEmpty(t, resp.Error)
+		})
+	}
+
+	require.Len(t, contracts.Calls(), calls)
+}
+
+func TestUpdateVerificationStatus(t *testing.T) {
+	for path, status := range map[string]string{
+		"/credentials/cred1/approve": "Verified",
+		"/credentials/cred1/revoke":  "Revoked",
+	} {
+		t.Run(status, func(t *testing.T) {
+			contracts := webtest.NewContracts()
+			contracts.Return("issuers:UpdateVerificationStatus", "")
+
+			var result web.TransactionResult
+			decodeData(t, serve(t, newRouter(contracts), "PUT", path+chaincodeQuery, nil), http.StatusOK, &result)
+			require.Equal(t, web.TransactionResult{TxID: "tx1"}, result)
+			requireCall(t, lastCall(t, contracts), true, "issuers:UpdateVerificationStatus", "cred1", status, "Org1")
+
+			// Only Org1 verifies credentials
+			resp := decode(t, serve(t, newRouterAs("Org2MSP", contracts), "PUT", path+chaincodeQuery, nil), http.StatusForbidden)
+			require.Contains(t, resp.Error, "Permission denied")
+
+			decode(t, serve(t, newRouter(contracts), "PUT", path+"?channelid="+channelID, nil), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestReissueCredential(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("issuers:ReissueCredential", "cred2")
+	router := newRouter(contracts)
+
+	rec := serve(t, router, "POST", "/credentials/cred1/reissue", web.ReissueRequest{
+		ChainCodeID: chaincodeID,
+		ChannelID:   channelID,
+		Corrections: map[string]string{"Institution": "Concordia University"},
+	})
+
+	var result web.CredentialCreatedResult
+	decodeData(t, rec, http.StatusCreated, &result)
+	require.Equal(t, "/credentials/cred2", rec.Header().Get("Location"))
+	require.Equal(t, "cred2", result.CredentialID)
+	requireCall(t, lastCall(t, contracts), true, "issuers:ReissueCredential", "cred1", "", `{"Institution":"Concordia University"}`)
+
+	// Without corrections, the credential is reissued as is
+	serve(t, router, "POST", "/credentials/cred1/reissue", web.ReissueRequest{ChainCodeID: chaincodeID, ChannelID: channelID, NewCredentialID: "cred3"})
+	requireCall(t, lastCall(t, contracts), true, "issuers:ReissueCredential", "cred1", "cred3", "{}")
+
+	decode(t, serve(t, newRouterAs("Org2MSP", contracts), "POST", "/credentials/cred1/reissue", web.ReissueRequest{}), http.StatusForbidden)
+}
+
+func TestDeleteCredential(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("credentials:DeleteTalentCredential", "")
+	router := newRouter(contracts)
+
+	var result web.TransactionResult
+	decodeData(t, serve(t, router, "DELETE", "/credentials/cred1"+chaincodeQuery, nil), http.StatusOK, &result)
+	require.Equal(t, "tx1", result.TxID)
+	requireCall(t, lastCall(t, contracts), true, "credentials:DeleteTalentCredential", "cred1")
+
+	decode(t, serve(t, router, "DELETE", "/credentials/cred1", nil), http.StatusBadRequest)
+
+	// Only issuers and admins delete credentials
+	calls := len(contracts.Calls())
+	resp := decode(t, serve(t, newRouterAs("Org2MSP", contracts), "DELETE", "/credentials/cred1"+chaincodeQuery, nil), http.StatusForbidden)
+	require.Contains(t, resp.Error, "Permission denied")
+	require.Len(t, contracts.Calls(), calls)
+}
+
+func TestUpdateCredential(t *testing.T) {
+	for name, test := range map[string]struct {
+		method   string
+		path     string
+		body     interface{}
+		function string
+		args     []string
+	}{
+		"skills": {
+			"PUT", "/credentials/cred1/skills",
+			web.UpdateSkillsRequest{ChainCodeID: chaincodeID, ChannelID: channelID, NewSkills: "Go, Rust"},
+			"credentials:UpdateSkills", []string{"cred1", "Go, Rust"},
+		},
+		"name": {
+			"PUT", "/credentials/cred1/name",
+			web.UpdateNameRequest{ChainCodeID: chaincodeID, ChannelID: channelID, NewFirstName: "Janet", NewLastName: "Doe", EvidenceHash: "abc"},
+			"credentials:UpdateName", []string{"cred1", "Janet", "Doe", "abc"},
+		},
+		"endorsement": {
+			"POST", "/credentials/cred1/skills/Go/endorsements",
+			web.EndorseSkillRequest{ChainCodeID: chaincodeID, ChannelID: channelID, Comment: "Great mentor"},
+			"endorsements:EndorseSkill", []string{"cred1", "Go", "Great mentor"},
+		},
+		"talent profile": {
+			"POST", "/talents",
+			web.TalentProfileRequest{ChainCodeID: chaincodeID, ChannelID: channelID, TalentID: "talent1", FirstName: "Jane", LastName: "Doe", ContactHash: "def"},
+			"talents:CreateTalentProfile", []string{"talent1", "Jane", "Doe", "def"},
+		},
+		"talent name": {
+			"PUT", "/talents/talent1/name",
+			web.UpdateNameRequest{ChainCodeID: chaincodeID, ChannelID: channelID, NewFirstName: "Janet", NewLastName: "Doe", EvidenceHash: "abc"},
+			"talents:UpdateTalentName", []string{"talent1", "Janet", "Doe", "abc"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			contracts := webtest.NewContracts()
+			contracts.Return(test.function, "")
+			router := newRouter(contracts)
+
+			var result web.TransactionResult
+			decodeData(t, serve(t, router, test.method, test.path, test.body), http.StatusOK, &result)
+			require.Equal(t, "tx1", result.TxID)
+			requireCall(t, lastCall(t, contracts), true, test.function, test.args...)
+
+			decode(t, serve(t, router, test.method, test.path, "not an object"), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestCreateTalentProfileValidatesRequest(t *testing.T) {
+	contracts := webtest.NewContracts()
+	router := newRouter(contracts)
+
+	resp := decode(t, serve(t, router, "POST", "/talents", web.TalentProfileRequest{ChainCodeID: chaincodeID, ChannelID: channelID, TalentID: "talent1"}), http.StatusBadRequest)
+	require.Equal(t, "talentId, firstName and lastName are required", resp.Error)
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("credentials:UpdateSkills", "")
+	router := newRouter(contracts)
+	request := web.UpdateSkillsRequest{ChainCodeID: chaincodeID, ChannelID: channelID, NewSkills: "Go"}
+
+	var result web.TransactionResult
+	decodeData(t, serve(t, router, "PUT", "/credentials/cred1/skills", request, web.IdempotencyKeyHeader, "key1"), http.StatusOK, &result)
+	require.Equal(t, map[string][]byte{"idempotencyKey": []byte("key1")}, lastCall(t, contracts).Transient)
+
+	// The replay returns the result of the original transaction
+	contracts.Fail("credentials:UpdateSkills", webtest.ChaincodeError(web.CodeAlreadyApplied, "idempotency key key1 was already used by transaction tx1"))
+	requestJSON, err := json.Marshal([]string{"credentials:UpdateSkills", "cred1", "Go"})
+	require.NoError(t, err)
+	requestHash := sha256.Sum256(requestJSON)
+	resultHash := sha256.Sum256([]byte("ok"))
+	idempotencyRecord := web.IdempotencyRecord{
+		IdempotencyKey: "key1",
+		Function:       "credentials:UpdateSkills",
+		RequestHash:    hex.EncodeToString(requestHash[:]),
+		TxID:           "tx1",
+		ResultHash:     hex.EncodeToString(resultHash[:]),
+	}
+	record, err := json.Marshal(idempotencyRecord)
+	require.NoError(t, err)
+	contracts.Return("admin:GetIdempotencyRecord", string(record))
+	contracts.Return("GetTransactionByID", webtest.ProcessedTransaction("ok"))
+
+	decodeData(t, serve(t, router, "PUT", "/credentials/cred1/skills", request, web.IdempotencyKeyHeader, "key1"), http.StatusOK, &result)
+	require.Equal(t, web.TransactionResult{TxID: "tx1", Response: "ok", Replayed: true}, result)
+	calls := contracts.Calls()
+	requireCall(t, calls[len(calls)-2], false, "admin:GetIdempotencyRecord", "key1")
+	call := lastCall(t, contracts)
+	require.Equal(t, "qscc", call.ChaincodeID)
+	require.Equal(t, "GetTransactionByID", call.Function)
+	require.Equal(t, []string{channelID, "tx1"}, call.Args)
+
+	// The result read from the block must match the hash of the record
+	contracts.Return("GetTransactionByID", webtest.ProcessedTransaction("tampered"))
+	resp := decode(t, serve(t, router, "PUT", "/credentials/cred1/skills", request, web.IdempotencyKeyHeader, "key1"), http.StatusConflict)
+	require.Equal(t, web.CodeAlreadyApplied, resp.Code)
+
+	// A key recorded for another request is not replayed
+	contracts.Return("GetTransactionByID", webtest.ProcessedTransaction("ok"))
+	resp = decode(t, serve(t, router, "PUT", "/credentials/cred1/skills", web.UpdateSkillsRequest{ChainCodeID: chaincodeID, ChannelID: channelID, NewSkills: "Rust"}, web.IdempotencyKeyHeader, "key1"), http.StatusUnprocessableEntity)
+	require.Equal(t, web.CodeIdempotencyKeyReused, resp.Code)
+	require.Equal(t, "admin:GetIdempotencyRecord", lastCall(t, contracts).Function)
+
+	// Without the key, there is nothing to replay
+	resp = decode(t, serve(t, router, "PUT", "/credentials/cred1/skills", request), http.StatusConflict)
+	require.Equal(t, web.CodeAlreadyApplied, resp.Code)
+	require.Empty(t, lastCall(t, contracts).Transient)
+}
@@ -0,0 +1,256 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// Claims are the claims of a verified token
+type Claims map[string]interface{}
+
+// Strings returns the values of a claim holding a string or a list of strings. A dotted name
+// such as "realm_access.roles" reaches into nested claims.
+func (claims Claims) Strings(name string) []string {
+	var value interface{} = map[string]interface{}(claims)
+	for _, key := range strings.Split(name, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// String returns the value of a string claim, or "" when it has none
+func (claims Claims) String(name string) string {
+	if values := claims.Strings(name); len(values) == 1 {
+		return values[0]
+	}
+	return ""
+}
+
+// clockSkew is the leeway allowed when checking the validity period of tokens
+const clockSkew = time.Minute
+
+// jwksRefreshInterval bounds how often the keys are fetched again for an unknown key ID
+const jwksRefreshInterval = time.Minute
+
+// signatureAlgorithms are the algorithms tokens may be signed with
+var signatureAlgorithms = []jose.SignatureAlgorithm{jose.RS256, jose.ES256}
+
+// TokenVerifier verifies JWT bearer tokens signed with RS256 or ES256 by the keys an issuer
+// publishes as a JWKS. The keys are fetched on first use, and again when a token is signed by
+// an unknown key, one fetch at a time. It is safe for concurrent use.
+type TokenVerifier struct {
+	issuer   string
+	audience string
+	jwksURL  string
+	client   *http.Client
+	now      func() time.Time
+
+	mu        sync.Mutex
+	keys      *jose.JSONWebKeySet
+	fetchedAt time.Time
+	fetching  chan struct{} // Closed when the fetch in progress, if any, completes
+}
+
+// NewTokenVerifier creates a verifier of the tokens of an issuer. The JWKS is found through the
+// OpenID discovery document of the issuer unless jwksURL is set; the audience is not checked
+// when empty.
+func NewTokenVerifier(issuer string, audience string, jwksURL string, client *http.Client) *TokenVerifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &TokenVerifier{
+		issuer:   issuer,
+		audience: audience,
+		jwksURL:  jwksURL,
+		client:   client,
+		now:      time.Now,
+	}
+}
+
+// Verify checks the signature, issuer, audience and validity period of a token and returns its claims
+func (verifier *TokenVerifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parsed, err := jwt.ParseSigned(token, signatureAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %w", err)
+	}
+
+	key, err := verifier.key(ctx, parsed.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	var registered jwt.Claims
+	if err := parsed.Claims(key.Key, &claims, &registered); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if registered.Expiry == nil {
+		return nil, errors.New("token has no expiry")
+	}
+	expected := jwt.Expected{Issuer: verifier.issuer, Time: verifier.now()}
+	if verifier.audience != "" {
+		expected.AnyAudience = jwt.Audience{verifier.audience}
+	}
+	if err := registered.ValidateWithLeeway(expected, clockSkew); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	return claims, nil
+}
+
+// key returns the public key of a key ID, fetching the keys of the issuer when it is unknown.
+// The keys are fetched without holding the lock, concurrent callers waiting for the same fetch.
+func (verifier *TokenVerifier) key(ctx context.Context, keyID string) (jose.JSONWebKey, error) {
+	verifier.mu.Lock()
+	for verifier.fetching != nil {
+		fetching := verifier.fetching
+		verifier.mu.Unlock()
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return jose.JSONWebKey{}, ctx.Err()
+		}
+		verifier.mu.Lock()
+	}
+
+	if key, ok := findKey(verifier.keys, keyID); ok {
+		verifier.mu.Unlock()
+		return key, nil
+	}
+	if verifier.keys != nil && verifier.now().Sub(verifier.fetchedAt) < jwksRefreshInterval {
+		verifier.mu.Unlock()
+		return jose.JSONWebKey{}, fmt.Errorf("unknown signing key %q", keyID)
+	}
+
+	fetching := make(chan struct{})
+	verifier.fetching = fetching
+	verifier.mu.Unlock()
+
+	keys, err := verifier.fetchKeys(ctx)
+
+	verifier.mu.Lock()
+	if err == nil {
+		verifier.keys = keys
+		verifier.fetchedAt = verifier.now()
+	}
+	verifier.fetching = nil
+	close(fetching)
+	verifier.mu.Unlock()
+
+	if err != nil {
+		return jose.JSONWebKey{}, err
+	}
+	if key, ok := findKey(keys, keyID); ok {
+		return key, nil
+	}
+	return jose.JSONWebKey{}, fmt.Errorf("unknown signing key %q", keyID)
+}
+
+// findKey returns the key of a key ID in a key set, which may be nil
+func findKey(keys *jose.JSONWebKeySet, keyID string) (jose.JSONWebKey, bool) {
+	if keys == nil {
+		return jose.JSONWebKey{}, false
+	}
+	if found := keys.Key(keyID); len(found) > 0 {
+		return found[0], true
+	}
+	return jose.JSONWebKey{}, false
+}
+
+// fetchKeys fetches the signing keys of the JWKS of the issuer, locating it through its
+// discovery document first. Only one fetch runs at a time.
+func (verifier *TokenVerifier) fetchKeys(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	if verifier.jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := verifier.getJSON(ctx, strings.TrimSuffix(verifier.issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, err
+		}
+		if discovery.JWKSURI == "" {
+			return nil, fmt.Errorf("issuer %s publishes no jwks_uri", verifier.issuer)
+		}
+		verifier.jwksURL = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := verifier.getJSON(ctx, verifier.jwksURL, &jwks); err != nil {
+		return nil, err
+	}
+
+	// Keys of types that cannot verify tokens are skipped rather than failing the whole set
+	keys := &jose.JSONWebKeySet{}
+	for _, raw := range jwks.Keys {
+		var key jose.JSONWebKey
+		if err := key.UnmarshalJSON(raw); err != nil {
+			log.Printf("Skipping key of %s: %v", verifier.jwksURL, err)
+			continue
+		}
+		if (key.Use == "" || key.Use == "sig") && key.IsPublic() && key.Valid() {
+			keys.Keys = append(keys.Keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// getJSON fetches and decodes a JSON document
+func (verifier *TokenVerifier) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := verifier.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid document %s: %w", url, err)
+	}
+
+	return nil
+}
+
+// contains tells whether a list holds a value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
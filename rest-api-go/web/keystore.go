@@ -0,0 +1,75 @@
+package web
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fieldEncryptionTransientKey is the transient data entry in which the chaincode expects the
+// AES key of its encrypted credential fields
+const fieldEncryptionTransientKey = "fieldEncryptionKey"
+
+// keyFileExtension is the extension of the key files of a keystore directory
+const keyFileExtension = ".key"
+
+// KeyStore holds the field encryption keys of organizations, by MSP ID
+type KeyStore struct {
+	keys map[string][]byte
+}
+
+// LoadKeyStore reads a keystore directory holding one <MSP ID>.key file per organization,
+// each containing a base64-encoded AES key of 16, 24 or 32 bytes
+func LoadKeyStore(dir string) (*KeyStore, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore directory: %w", err)
+	}
+
+	keyStore := &KeyStore{keys: make(map[string][]byte)}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != keyFileExtension {
+			continue
+		}
+
+		encoded, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+		if err != nil {
+			return nil, fmt.Errorf("key file %s is not base64: %w", file.Name(), err)
+		}
+		if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+			return nil, fmt.Errorf("key file %s must hold 16, 24 or 32 bytes, got %d", file.Name(), len(key))
+		}
+
+		keyStore.keys[strings.TrimSuffix(file.Name(), keyFileExtension)] = key
+	}
+
+	return keyStore, nil
+}
+
+// Key returns the field encryption key of an organization, or nil if it has none
+func (keyStore *KeyStore) Key(mspID string) []byte {
+	if keyStore == nil {
+		return nil
+	}
+	return keyStore.keys[mspID]
+}
+
+// transientData returns the transient data passed with the proposals of an organization:
+// its field encryption key, if any, and the idempotency key of the request, if any
+func (setup *OrgSetup) transientData(mspID string, idempotencyKey string) map[string][]byte {
+	transient := make(map[string][]byte)
+	if key := setup.KeyStore.Key(mspID); key != nil {
+		transient[fieldEncryptionTransientKey] = key
+	}
+	if idempotencyKey != "" {
+		transient[idempotencyTransientKey] = []byte(idempotencyKey)
+	}
+
+	return transient
+}
@@ -0,0 +1,218 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsNamespace prefixes the names of the metrics of the REST server
+const metricsNamespace = "rest_api"
+
+// unmatchedRoute labels the requests no route matched
+const unmatchedRoute = "unmatched"
+
+// latencyBuckets span 5ms to 40s, the commit of a transaction taking seconds
+var latencyBuckets = prometheus.ExponentialBuckets(0.005, 2, 14)
+
+// Metrics are the Prometheus metrics of the REST server, served on /metrics. The methods of a
+// nil Metrics do nothing.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requestDuration      *prometheus.HistogramVec
+	endorseDuration      *prometheus.HistogramVec
+	submitDuration       *prometheus.HistogramVec
+	commitStatusDuration *prometheus.HistogramVec
+	transactionsInFlight *prometheus.GaugeVec
+	credentialsCreated   prometheus.Counter
+	credentialsApproved  prometheus.Counter
+	credentialsRevoked   prometheus.Counter
+}
+
+// NewMetrics creates the metrics of a server, in a registry of their own along with the Go
+// runtime and process metrics
+func NewMetrics() *Metrics {
+	metrics := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of the HTTP requests by method, route template and status code.",
+			Buckets:   latencyBuckets,
+		}, []string{"method", "route", "status"}),
+		endorseDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "endorse_duration_seconds",
+			Help:      "Duration of the endorsement of the transactions by chaincode function.",
+			Buckets:   latencyBuckets,
+		}, []string{"function"}),
+		submitDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "submit_duration_seconds",
+			Help:      "Duration of the submission of the endorsed transactions to the orderer by chaincode function.",
+			Buckets:   latencyBuckets,
+		}, []string{"function"}),
+		commitStatusDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "commit_status_duration_seconds",
+			Help:      "Duration of the wait for the commit of the submitted transactions by chaincode function.",
+			Buckets:   latencyBuckets,
+		}, []string{"function"}),
+		transactionsInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "transactions_in_flight",
+			Help:      "Number of transactions being endorsed, submitted or committed by chaincode function.",
+		}, []string{"function"}),
+		credentialsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "credentials_created_total",
+			Help:      "Number of credentials created.",
+		}),
+		credentialsApproved: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "credentials_approved_total",
+			Help:      "Number of credentials approved.",
+		}),
+		credentialsRevoked: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "credentials_revoked_total",
+			Help:      "Number of credentials revoked.",
+		}),
+	}
+
+	metrics.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metrics.requestDuration,
+		metrics.endorseDuration,
+		metrics.submitDuration,
+		metrics.commitStatusDuration,
+		metrics.transactionsInFlight,
+		metrics.credentialsCreated,
+		metrics.credentialsApproved,
+		metrics.credentialsRevoked,
+	)
+
+	return metrics
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (metrics *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware measures the duration of the requests, labelled with the template of the first
+// route of routers matching them, e.g. /credentials/{id}/approve, which bounds the label values
+func (metrics *Metrics) Middleware(next http.Handler, routers ...*mux.Router) http.Handler {
+	if metrics == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := unmatchedRoute
+		for _, router := range routers {
+			var match mux.RouteMatch
+			if router.Match(r, &match) && match.Route != nil {
+				if template, err := match.Route.GetPathTemplate(); err == nil {
+					route = template
+					break
+				}
+			}
+		}
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		metrics.requestDuration.WithLabelValues(r.Method, route, strconv.Itoa(recorder.status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// observePhase records the duration of a phase of a transaction of a chaincode function
+func (metrics *Metrics) observePhase(function string, phase string, duration time.Duration) {
+	if metrics == nil {
+		return
+	}
+
+	switch phase {
+	case PhaseEndorse:
+		metrics.endorseDuration.WithLabelValues(function).Observe(duration.Seconds())
+	case PhaseSubmit:
+		metrics.submitDuration.WithLabelValues(function).Observe(duration.Seconds())
+	case PhaseCommitStatus:
+		metrics.commitStatusDuration.WithLabelValues(function).Observe(duration.Seconds())
+	}
+}
+
+// transactionStarted counts a transaction of a chaincode function in flight, until the
+// returned function is called
+func (metrics *Metrics) transactionStarted(function string) func() {
+	if metrics == nil {
+		return func() {}
+	}
+
+	inFlight := metrics.transactionsInFlight.WithLabelValues(function)
+	inFlight.Inc()
+	return inFlight.Dec
+}
+
+// transactionCommitted counts the credentials created, approved or revoked by a committed
+// transaction of a chaincode function
+func (metrics *Metrics) transactionCommitted(function string, args []string) {
+	if metrics == nil {
+		return
+	}
+
+	switch function {
+	case "credentials:CreateAcademicCredential", "credentials:CreateProfessionalCredential":
+		metrics.credentialsCreated.Inc()
+	case "issuers:UpdateVerificationStatus":
+		if len(args) < 2 {
+			return
+		}
+		switch args[1] {
+		case "Verified":
+			metrics.credentialsApproved.Inc()
+		case "Revoked":
+			metrics.credentialsRevoked.Inc()
+		}
+	}
+}
+
+// statusRecorder records the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	if !recorder.wroteHeader {
+		recorder.status = status
+		recorder.wroteHeader = true
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(data []byte) (int, error) {
+	recorder.wroteHeader = true
+	return recorder.ResponseWriter.Write(data)
+}
+
+// Flush sends the buffered data, for the event streams
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		recorder.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the recorded writer, for http.ResponseController
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
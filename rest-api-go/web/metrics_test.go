@@ -0,0 +1,122 @@
+package web_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"rest-api-go/web"
+	"rest-api-go/web/webtest"
+)
+
+// scrape returns the metrics served by a router, in the Prometheus text format
+func scrape(t *testing.T, router http.Handler) string {
+	t.Helper()
+
+	rec := serve(t, router, "GET", "/metrics", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+// newMetricsRouter creates the router of an Org1 setup measured by metrics
+func newMetricsRouter(contracts *webtest.Contracts) http.Handler {
+	return web.NewRouter(&web.OrgSetup{
+		OrgName:     "Org1",
+		MSPID:       "Org1MSP",
+		Contracts:   contracts,
+		ChannelID:   channelID,
+		ChaincodeID: chaincodeID,
+		Metrics:     web.NewMetrics(),
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("credentials:CreateAcademicCredential", "cred1")
+	contracts.Return("issuers:UpdateVerificationStatus", "")
+	router := newMetricsRouter(contracts)
+
+	decode(t, serve(t, router, "POST", "/credentials/academic", web.CredentialRequest{
+		ChainCodeID: chaincodeID,
+		ChannelID:   channelID,
+		Credential: web.Credential{
+			TalentID:    "talent1",
+			FirstName:   "Jane",
+			LastName:    "Doe",
+			Education:   "BSc Computer Science",
+			Institution: "Concordia University",
+		},
+	}), http.StatusCreated)
+	decode(t, serve(t, router, "PUT", "/credentials/cred1/approve"+chaincodeQuery, nil), http.StatusOK)
+	decode(t, serve(t, router, "PUT", "/credentials/cred1/approve"+chaincodeQuery, nil), http.StatusOK)
+	decode(t, serve(t, router, "PUT", "/credentials/cred1/revoke"+chaincodeQuery, nil), http.StatusOK)
+	decode(t, serve(t, router, "GET", "/credentials/cred2"+chaincodeQuery, nil), http.StatusNotFound)
+	serve(t, router, "GET", "/unknown", nil)
+
+	metrics := scrape(t, router)
+
+	// Requests by route template and status
+	require.Contains(t, metrics, `rest_api_http_request_duration_seconds_count{method="POST",route="/credentials/academic",status="201"} 1`)
+	require.Contains(t, metrics, `rest_api_http_request_duration_seconds_count{method="PUT",route="/credentials/{id}/approve",status="200"} 2`)
+	require.Contains(t, metrics, `rest_api_http_request_duration_seconds_count{method="GET",route="/credentials/{id}",status="404"} 1`)
+	require.Contains(t, metrics, `rest_api_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+
+	// Transaction phases by chaincode function
+	for _, phase := range []string{"endorse", "submit", "commit_status"} {
+		require.Contains(t, metrics, `rest_api_`+phase+`_duration_seconds_count{function="credentials:CreateAcademicCredential"} 1`)
+		require.Contains(t, metrics, `rest_api_`+phase+`_duration_seconds_count{function="issuers:UpdateVerificationStatus"} 3`)
+	}
+	require.Contains(t, metrics, `rest_api_transactions_in_flight{function="issuers:UpdateVerificationStatus"} 0`)
+
+	// Business events
+	require.Contains(t, metrics, "rest_api_credentials_created_total 1\n")
+	require.Contains(t, metrics, "rest_api_credentials_approved_total 2\n")
+	require.Contains(t, metrics, "rest_api_credentials_revoked_total 1\n")
+	require.Contains(t, metrics, "go_goroutines ")
+}
+
+func TestMetricsCountTransactionsInFlight(t *testing.T) {
+	contracts := webtest.NewContracts()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	contracts.Handle("credentials:UpdateSkills", func(webtest.Call) ([]byte, error) {
+		close(started)
+		<-release
+		return nil, webtest.ChaincodeError(web.CodeNotFound, "credential cred1 does not exist")
+	})
+	router := newMetricsRouter(contracts)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve(t, router, "PUT", "/credentials/cred1/skills", web.UpdateSkillsRequest{ChainCodeID: chaincodeID, ChannelID: channelID, NewSkills: "Go"})
+	}()
+	<-started
+	require.Contains(t, scrape(t, router), `rest_api_transactions_in_flight{function="credentials:UpdateSkills"} 1`)
+
+	// Failed transactions are measured up to the phase that failed, and create nothing
+	close(release)
+	<-done
+	metrics := scrape(t, router)
+	require.Contains(t, metrics, `rest_api_transactions_in_flight{function="credentials:UpdateSkills"} 0`)
+	require.Contains(t, metrics, `rest_api_endorse_duration_seconds_count{function="credentials:UpdateSkills"} 1`)
+	require.NotContains(t, metrics, `rest_api_submit_duration_seconds_count{function="credentials:UpdateSkills"}`)
+	require.Contains(t, metrics, "rest_api_credentials_created_total 0\n")
+}
+
+func TestMetricsNeedNoToken(t *testing.T) {
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+
+	router := web.NewRouter(&web.OrgSetup{
+		OrgName:   "Org1",
+		MSPID:     "Org1MSP",
+		Contracts: webtest.NewContracts(),
+		Auth:      &web.AuthConfig{Issuer: issuer.URL},
+		Metrics:   web.NewMetrics(),
+	})
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil), http.StatusUnauthorized)
+
+	require.Contains(t, scrape(t, router), `rest_api_http_request_duration_seconds_count{method="GET",route="/credentials/all",status="401"} 1`)
+}
@@ -0,0 +1,405 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"google.golang.org/grpc"
+)
+
+// Identity is a client identity the server makes requests as, through its own Gateway connection
+type Identity struct {
+	Name         string // Selected by the X-Fabric-Identity header
+	MSPID        string
+	CertPath     string
+	KeyPath      string // Directory holding the private key
+	Certificate  []byte // Used instead of CertPath when set, PEM-encoded
+	PrivateKey   []byte // Used instead of KeyPath when set, PEM-encoded
+	TLSCertPath  string
+	TLSCertPEM   []byte // Used instead of TLSCertPath when set
+	PeerEndpoint string
+	GatewayPeer  string
+}
+
+// peerKey identifies the gRPC connection to a peer, shared by the identities connecting to it
+func (id Identity) peerKey() string {
+	return id.PeerEndpoint + "|" + id.GatewayPeer
+}
+
+// GatewayPool holds the Gateway connections of several identities. Each connection is made on
+// first use and then shared by all requests; identities connecting to the same peer share its
+// gRPC connection and its circuit breaker. Beyond resilience.MaxGateways connections, the least
+// recently used idle one is closed. It is safe for concurrent use.
+type GatewayPool struct {
+	timeouts   Timeouts
+	resilience Resilience
+	identities map[string]Identity
+
+	mu         sync.Mutex
+	gateways   map[string]*pooledGateway
+	peers      map[string]*peerConnection
+	connecting map[string]chan struct{} // Closed when the connection in progress of an identity completes
+}
+
+// pooledGateway is a Gateway connection of the pool, counting the calls using it so that it is
+// only closed once they are done
+type pooledGateway struct {
+	gateway  *client.Gateway
+	breaker  *circuitBreaker
+	calls    int       // Calls in flight
+	lastUsed time.Time // Start of the latest call
+	retired  bool      // Removed from the pool, closed after its last call
+}
+
+// peerConnection is the gRPC connection to a peer and the circuit breaker of its calls
+type peerConnection struct {
+	connection *grpc.ClientConn
+	breaker    *circuitBreaker
+}
+
+// NewGatewayPool creates a pool for identities with distinct names, without connecting them
+func NewGatewayPool(timeouts Timeouts, resilience Resilience, identities ...Identity) (*GatewayPool, error) {
+	pool := &GatewayPool{
+		timeouts:   timeouts.withDefaults(),
+		resilience: resilience.withDefaults(),
+		identities: make(map[string]Identity),
+		gateways:   make(map[string]*pooledGateway),
+		peers:      make(map[string]*peerConnection),
+		connecting: make(map[string]chan struct{}),
+	}
+	for _, id := range identities {
+		if id.Name == "" {
+			return nil, fmt.Errorf("identity of %s has no name", id.MSPID)
+		}
+		if _, ok := pool.identities[id.Name]; ok {
+			return nil, fmt.Errorf("identity %s is configured twice", id.Name)
+		}
+		pool.identities[id.Name] = id
+	}
+
+	return pool, nil
+}
+
+// Put adds an identity to the pool, or replaces the identity of the same name. The Gateway
+// connection of a replaced identity is closed once the calls using it are done, unless the
+// identity is unchanged.
+func (pool *GatewayPool) Put(id Identity) error {
+	if id.Name == "" {
+		return fmt.Errorf("identity of %s has no name", id.MSPID)
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if previous, ok := pool.identities[id.Name]; ok && reflect.DeepEqual(previous, id) {
+		return nil
+	}
+	pool.identities[id.Name] = id
+	if gateway, ok := pool.gateways[id.Name]; ok {
+		delete(pool.gateways, id.Name)
+		return gateway.retire()
+	}
+
+	return nil
+}
+
+// retire removes a connection from use, closing it now if no call is using it. The caller holds
+// the mutex of the pool.
+func (gateway *pooledGateway) retire() error {
+	gateway.retired = true
+	if gateway.calls > 0 {
+		return nil
+	}
+
+	return gateway.gateway.Close()
+}
+
+// Has tells whether the pool holds an identity
+func (pool *GatewayPool) Has(name string) bool {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	_, ok := pool.identities[name]
+	return ok
+}
+
+// Gateway returns the Gateway connection of an identity, connecting it on first use. The
+// connection is closed when the identity is replaced or the connection is evicted.
+func (pool *GatewayPool) Gateway(name string) (*client.Gateway, error) {
+	gateway, err := pool.acquire(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	pool.release(gateway)
+
+	return gateway.gateway, nil
+}
+
+// acquire returns the Gateway connection of an identity for a call, connecting it on first use.
+// The connection is made without holding the lock, concurrent callers for the same identity
+// waiting for it until ctx is done. The connection stays open until the call releases it.
+func (pool *GatewayPool) acquire(ctx context.Context, name string) (*pooledGateway, error) {
+	pool.mu.Lock()
+	for {
+		if gateway, ok := pool.gateways[name]; ok {
+			gateway.calls++
+			gateway.lastUsed = time.Now()
+			pool.evictIdle()
+			pool.mu.Unlock()
+			return gateway, nil
+		}
+
+		if connecting, ok := pool.connecting[name]; ok {
+			pool.mu.Unlock()
+			select {
+			case <-connecting:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			pool.mu.Lock()
+			continue
+		}
+
+		id, ok := pool.identities[name]
+		if !ok {
+			pool.mu.Unlock()
+			return nil, fmt.Errorf("unknown identity %s", name)
+		}
+		connecting := make(chan struct{})
+		pool.connecting[name] = connecting
+		pool.mu.Unlock()
+
+		gateway, err := pool.connect(id)
+
+		pool.mu.Lock()
+		delete(pool.connecting, name)
+		close(connecting)
+		if err != nil {
+			pool.mu.Unlock()
+			return nil, err
+		}
+		// An identity replaced while it was connecting is connected again
+		if !reflect.DeepEqual(pool.identities[name], id) {
+			if err := gateway.gateway.Close(); err != nil {
+				log.Printf("Failed to close the Gateway connection of a replaced identity: %v\n", err)
+			}
+			continue
+		}
+		pool.gateways[name] = gateway
+	}
+}
+
+// release ends a call using a connection, closing the connection if it was retired meanwhile
+func (pool *GatewayPool) release(gateway *pooledGateway) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	gateway.calls--
+	if gateway.retired && gateway.calls == 0 {
+		if err := gateway.gateway.Close(); err != nil {
+			log.Printf("Failed to close a replaced Gateway connection: %v\n", err)
+		}
+	}
+}
+
+// evictIdle closes the least recently used idle connections beyond resilience.MaxGateways.
+// Connections in use are kept, their calls reconnect later. The caller holds the mutex of the pool.
+func (pool *GatewayPool) evictIdle() {
+	for len(pool.gateways) > pool.resilience.MaxGateways {
+		var (
+			oldestName string
+			oldest     *pooledGateway
+		)
+		for name, gateway := range pool.gateways {
+			if gateway.calls == 0 && (oldest == nil || gateway.lastUsed.Before(oldest.lastUsed)) {
+				oldestName, oldest = name, gateway
+			}
+		}
+		if oldest == nil {
+			return
+		}
+
+		log.Printf("Closing the idle Gateway connection of %s\n", oldestName)
+		delete(pool.gateways, oldestName)
+		if err := oldest.retire(); err != nil {
+			log.Printf("Failed to close the Gateway connection of %s: %v\n", oldestName, err)
+		}
+	}
+}
+
+// connect makes the Gateway connection of an identity, through the gRPC connection of its peer
+func (pool *GatewayPool) connect(id Identity) (*pooledGateway, error) {
+	log.Printf("Connecting %s (%s) to %s...\n", id.Name, id.MSPID, id.PeerEndpoint)
+	clientIdentity, err := id.newIdentity()
+	if err != nil {
+		return nil, err
+	}
+	sign, err := id.newSign()
+	if err != nil {
+		return nil, err
+	}
+	peer, err := pool.peer(id)
+	if err != nil {
+		return nil, err
+	}
+
+	gateway, err := client.Connect(
+		clientIdentity,
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(peer.connection),
+		client.WithEvaluateTimeout(pool.timeouts.Evaluate),
+		client.WithEndorseTimeout(pool.timeouts.Endorse),
+		client.WithSubmitTimeout(pool.timeouts.Submit),
+		client.WithCommitStatusTimeout(pool.timeouts.CommitStatus),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect %s: %w", id.Name, err)
+	}
+
+	return &pooledGateway{gateway: gateway, breaker: peer.breaker}, nil
+}
+
+// peer returns the connection to the peer of an identity, creating it on first use. The
+// connection is created without holding the lock; of concurrent creations, the first is kept.
+func (pool *GatewayPool) peer(id Identity) (*peerConnection, error) {
+	pool.mu.Lock()
+	peer, ok := pool.peers[id.peerKey()]
+	pool.mu.Unlock()
+	if ok {
+		return peer, nil
+	}
+
+	connection, err := id.newGrpcConnection(pool.resilience)
+	if err != nil {
+		return nil, err
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if peer, ok := pool.peers[id.peerKey()]; ok {
+		if err := connection.Close(); err != nil {
+			log.Printf("Failed to close a redundant connection to %s: %v\n", id.PeerEndpoint, err)
+		}
+		return peer, nil
+	}
+	peer = &peerConnection{connection: connection, breaker: newCircuitBreaker(id.PeerEndpoint, pool.resilience)}
+	pool.peers[id.peerKey()] = peer
+
+	return peer, nil
+}
+
+// Contracts returns the contracts reachable as an identity. The identity is connected when a
+// contract is first requested; if that fails, the calls to the contract return the error.
+func (pool *GatewayPool) Contracts(name string) ContractProvider {
+	return poolContracts{pool: pool, name: name}
+}
+
+// Close closes the Gateway and gRPC connections made so far
+func (pool *GatewayPool) Close() error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var errs []error
+	for name, gateway := range pool.gateways {
+		if err := gateway.gateway.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(pool.gateways, name)
+	}
+	for key, peer := range pool.peers {
+		if err := peer.connection.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(pool.peers, key)
+	}
+
+	return errors.Join(errs...)
+}
+
+// poolContracts provides the contracts of an identity of a pool
+type poolContracts struct {
+	pool *GatewayPool
+	name string
+}
+
+// Contract returns the contract of a chaincode deployed on a channel, called through the
+// circuit breaker of the peer. Each call uses the current connection of the identity.
+func (contracts poolContracts) Contract(channelID string, chaincodeID string) Contract {
+	return poolContract{pool: contracts.pool, name: contracts.name, channelID: channelID, chaincodeID: chaincodeID}
+}
+
+// poolContract is a contract of an identity of a pool, holding its connection for each call
+type poolContract struct {
+	pool        *GatewayPool
+	name        string
+	channelID   string
+	chaincodeID string
+}
+
+// acquire returns the contract of the current connection of the identity, and the function
+// releasing the connection at the end of the call. Waiting for the identity to connect stops
+// once ctx is done.
+func (c poolContract) acquire(ctx context.Context) (Contract, func(), error) {
+	gateway, err := c.pool.acquire(ctx, c.name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contract := resilientContract{
+		contract:   GatewayContracts{Gateway: gateway.gateway}.Contract(c.channelID, c.chaincodeID),
+		breaker:    gateway.breaker,
+		resilience: c.pool.resilience,
+	}
+	return contract, func() { c.pool.release(gateway) }, nil
+}
+
+// Evaluate runs a query on a peer without submitting a transaction, until ctx is done
+func (c poolContract) Evaluate(ctx context.Context, proposal Proposal) ([]byte, error) {
+	contract, release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return contract.Evaluate(ctx, proposal)
+}
+
+// Submit endorses a transaction, submits it to the orderer and waits for it to commit
+func (c poolContract) Submit(proposal Proposal) (*TransactionResult, error) {
+	contract, release, err := c.acquire(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return contract.Submit(proposal)
+}
+
+// ChaincodeEvents returns the events emitted by the chaincode from now on, until ctx is done.
+// The connection is held until then.
+func (c poolContract) ChaincodeEvents(ctx context.Context) (<-chan *client.ChaincodeEvent, error) {
+	contract, release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := contract.ChaincodeEvents(ctx)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		release()
+	}()
+
+	return events, nil
+}
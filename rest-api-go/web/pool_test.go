@@ -0,0 +1,335 @@
+package web_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"rest-api-go/web"
+)
+
+// newTestIdentity writes a self-signed certificate and its key to a temporary directory, as the
+// identity and TLS CA of a peer that is never dialled
+func newTestIdentity(t *testing.T, name string, mspID string) web.Identity {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	keyPath := filepath.Join(dir, "keystore")
+	require.NoError(t, os.Mkdir(keyPath, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(keyPath, "priv_sk"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return web.Identity{
+		Name:         name,
+		MSPID:        mspID,
+		CertPath:     certPath,
+		KeyPath:      keyPath,
+		TLSCertPath:  certPath,
+		PeerEndpoint: "dns:///localhost:7051",
+		GatewayPeer:  "peer0.org1.example.com",
+	}
+}
+
+func TestGatewayPool(t *testing.T) {
+	pool, err := web.NewGatewayPool(web.Timeouts{}, web.Resilience{}, newTestIdentity(t, "Org1", "Org1MSP"), newTestIdentity(t, "Org2", "Org2MSP"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// Each identity is connected once, however many requests use it concurrently
+	type connected struct {
+		org1, org2 *client.Gateway
+		err1, err2 error
+	}
+	results := make([]connected, 10)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(result *connected) {
+			defer wg.Done()
+			result.org1, result.err1 = pool.Gateway("Org1")
+			result.org2, result.err2 = pool.Gateway("Org2")
+		}(&results[i])
+	}
+	wg.Wait()
+	for _, result := range results {
+		require.NoError(t, result.err1)
+		require.NoError(t, result.err2)
+		require.Same(t, results[0].org1, result.org1)
+		require.Same(t, results[0].org2, result.org2)
+	}
+	require.NotSame(t, results[0].org1, results[0].org2)
+
+	_, err = pool.Gateway("Org3")
+	require.Error(t, err)
+
+	require.NoError(t, pool.Close())
+}
+
+func TestGatewayPoolConnectsOnFirstUse(t *testing.T) {
+	// An identity whose credentials are missing only fails once its contracts are used
+	broken := web.Identity{Name: "Org2", MSPID: "Org2MSP", TLSCertPEM: []byte("not PEM")}
+	pool, err := web.NewGatewayPool(web.Timeouts{}, web.Resilience{}, newTestIdentity(t, "Org1", "Org1MSP"), broken)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Gateway("Org1")
+	require.NoError(t, err)
+
+	_, err = pool.Gateway("Org2")
+	require.Error(t, err)
+	_, err = pool.Contracts("Org2").Contract("mychannel", "basic").Evaluate(context.Background(), web.Proposal{Function: "GetAllCredentials"})
+	require.Error(t, err)
+}
+
+func TestGatewayPoolRejectsDuplicateNames(t *testing.T) {
+	_, err := web.NewGatewayPool(web.Timeouts{}, web.Resilience{}, web.Identity{Name: "Org1", MSPID: "Org1MSP"}, web.Identity{Name: "Org1", MSPID: "Org2MSP"})
+	require.Error(t, err)
+
+	_, err = web.NewGatewayPool(web.Timeouts{}, web.Resilience{}, web.Identity{MSPID: "Org1MSP"})
+	require.Error(t, err)
+}
+
+// gatewayServer answers the evaluations of the Gateway service with "ok"
+type gatewayServer struct {
+	gateway.UnimplementedGatewayServer
+}
+
+func (gatewayServer) Evaluate(context.Context, *gateway.EvaluateRequest) (*gateway.EvaluateResponse, error) {
+	return &gateway.EvaluateResponse{Result: &peer.Response{Status: 200, Payload: []byte("ok")}}, nil
+}
+
+// newPeerCertificate creates the TLS certificate of a peer named peer0.org1.example.com
+func newPeerCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "peer0.org1.example.com"},
+		DNSNames:     []string{"peer0.org1.example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// blockingGatewayServer signals each evaluation on started, and answers it once release is closed
+type blockingGatewayServer struct {
+	gatewayServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s blockingGatewayServer) Evaluate(ctx context.Context, request *gateway.EvaluateRequest) (*gateway.EvaluateResponse, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return s.gatewayServer.Evaluate(ctx, request)
+}
+
+// startPeer serves the Gateway service on an address, until the returned server is stopped
+func startPeer(t *testing.T, address string, certificate tls.Certificate, service gateway.GatewayServer) (*grpc.Server, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", address)
+	require.NoError(t, err)
+	server := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&certificate)))
+	gateway.RegisterGatewayServer(server, service)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return server, listener.Addr().String()
+}
+
+func TestGatewayPoolReconnects(t *testing.T) {
+	certificate := newPeerCertificate(t)
+	server, address := startPeer(t, "127.0.0.1:0", certificate, gatewayServer{})
+
+	id := newTestIdentity(t, "Org1", "Org1MSP")
+	id.PeerEndpoint = address
+	id.TLSCertPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]})
+	pool, err := web.NewGatewayPool(web.Timeouts{Evaluate: time.Second}, web.Resilience{
+		EvaluateAttempts:  3,
+		RetryDelay:        time.Millisecond,
+		BreakerFailures:   3,
+		BreakerTimeout:    50 * time.Millisecond,
+		ReconnectMaxDelay: 20 * time.Millisecond,
+	}, id)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	contract := pool.Contracts("Org1").Contract("mychannel", "basic")
+	result, err := contract.Evaluate(context.Background(), web.Proposal{Function: "GetAllCredentials"})
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(result))
+
+	// Evaluations are retried while the peer is down. Each evaluation failing after its retries
+	// counts as one failure, until the circuit breaker opens and fails them without calling the peer
+	server.Stop()
+	for range 3 {
+		_, err = contract.Evaluate(context.Background(), web.Proposal{Function: "GetAllCredentials"})
+		require.True(t, web.IsUnavailable(err))
+		require.NotErrorIs(t, err, web.ErrPeerUnavailable)
+	}
+	_, err = contract.Evaluate(context.Background(), web.Proposal{Function: "GetAllCredentials"})
+	require.ErrorIs(t, err, web.ErrPeerUnavailable)
+	_, err = contract.Submit(web.Proposal{Function: "CreateTalentProfile"})
+	require.ErrorIs(t, err, web.ErrPeerUnavailable)
+
+	// Once the peer is back, a trial call after the breaker timeout reconnects
+	startPeer(t, address, certificate, gatewayServer{})
+	require.Eventually(t, func() bool {
+		result, err := contract.Evaluate(context.Background(), web.Proposal{Function: "GetAllCredentials"})
+		return err == nil && string(result) == "ok"
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestGatewayPoolConnectsOnceForConcurrentCalls(t *testing.T) {
+	certificate := newPeerCertificate(t)
+	_, address := startPeer(t, "127.0.0.1:0", certificate, gatewayServer{})
+
+	pool, err := web.NewGatewayPool(web.Timeouts{}, web.Resilience{}, newPeerIdentity(t, "Org1", address, certificate))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	gateways := make(chan *client.Gateway, 10)
+	var wg sync.WaitGroup
+	for range cap(gateways) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gateway, err := pool.Gateway("Org1")
+			assert.NoError(t, err)
+			gateways <- gateway
+		}()
+	}
+	wg.Wait()
+	close(gateways)
+
+	first := <-gateways
+	require.NotNil(t, first)
+	for gateway := range gateways {
+		require.Same(t, first, gateway)
+	}
+}
+
+func TestEvaluationRetriesStopWhenCancelled(t *testing.T) {
+	certificate := newPeerCertificate(t)
+	server, address := startPeer(t, "127.0.0.1:0", certificate, gatewayServer{})
+	server.Stop()
+
+	pool, err := web.NewGatewayPool(web.Timeouts{Evaluate: time.Second}, web.Resilience{
+		EvaluateAttempts: 3,
+		RetryDelay:       time.Hour,
+	}, newPeerIdentity(t, "Org1", address, certificate))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// The evaluation fails with Unavailable, and then gives up waiting to retry with its caller
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = pool.Contracts("Org1").Contract("mychannel", "basic").Evaluate(ctx, web.Proposal{Function: "GetAllCredentials"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+// newPeerIdentity returns an identity connecting to a peer started with a certificate
+func newPeerIdentity(t *testing.T, name string, address string, certificate tls.Certificate) web.Identity {
+	t.Helper()
+
+	id := newTestIdentity(t, name, "Org1MSP")
+	id.PeerEndpoint = address
+	id.TLSCertPath = ""
+	id.TLSCertPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]})
+	return id
+}
+
+func TestGatewayPoolKeepsReplacedConnectionsUntilTheirCallsAreDone(t *testing.T) {
+	certificate := newPeerCertificate(t)
+	service := blockingGatewayServer{started: make(chan struct{}), release: make(chan struct{})}
+	_, address := startPeer(t, "127.0.0.1:0", certificate, service)
+
+	pool, err := web.NewGatewayPool(web.Timeouts{Evaluate: 5 * time.Second}, web.Resilience{}, newPeerIdentity(t, "Org1", address, certificate))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	type evaluated struct {
+		result []byte
+		err    error
+	}
+	done := make(chan evaluated)
+	go func() {
+		result, err := pool.Contracts("Org1").Contract("mychannel", "basic").Evaluate(context.Background(), web.Proposal{Function: "GetAllCredentials"})
+		done <- evaluated{result, err}
+	}()
+	<-service.started
+
+	// The identity is replaced while its connection is in use: the call completes on it
+	require.NoError(t, pool.Put(newPeerIdentity(t, "Org1", address, certificate)))
+	close(service.release)
+	call := <-done
+	require.NoError(t, call.err)
+	require.Equal(t, "ok", string(call.result))
+}
+
+func TestGatewayPoolClosesLeastRecentlyUsedConnections(t *testing.T) {
+	pool, err := web.NewGatewayPool(web.Timeouts{}, web.Resilience{MaxGateways: 2},
+		newTestIdentity(t, "Org1", "Org1MSP"), newTestIdentity(t, "Org2", "Org2MSP"), newTestIdentity(t, "Org3", "Org3MSP"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	org1, err := pool.Gateway("Org1")
+	require.NoError(t, err)
+	org2, err := pool.Gateway("Org2")
+	require.NoError(t, err)
+	_, err = pool.Gateway("Org1")
+	require.NoError(t, err)
+
+	// Org2 was used least recently, and makes room for Org3
+	_, err = pool.Gateway("Org3")
+	require.NoError(t, err)
+	again, err := pool.Gateway("Org1")
+	require.NoError(t, err)
+	require.Same(t, org1, again)
+	again, err = pool.Gateway("Org2")
+	require.NoError(t, err)
+	require.NotSame(t, org2, again)
+}
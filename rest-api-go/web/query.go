@@ -1,140 +1,13 @@
-// package web
-
-// import (
-// 	"encoding/json"
-// 	"log"
-// 	"net/http"
-// )
-
-// // QueryParams represents the query parameters for credential lookups
-// type QueryParams struct {
-// 	Function   string   `json:"function"`
-// 	Args       []string `json:"args"`
-// 	ChaincodeID string   `json:"chaincodeid"`
-// 	ChannelID   string   `json:"channelid"`
-// }
-
-// // QueryCredentialsHandler handles general credential query requests
-// func (setup *OrgSetup) QueryCredentialsHandler(w http.ResponseWriter, r *http.Request) {
-// 	log.Println("Received Query Credentials request")
-	
-// 	// Parse the request body to get the parameters
-// 	var params QueryParams
-// 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-// 		HandleError(w, "Invalid request format: "+err.Error(), http.StatusBadRequest)
-// 		return
-// 	}
-	
-// 	// Validate chaincodeid and channelid
-// 	if params.ChaincodeID == "" || params.ChannelID == "" {
-// 		HandleError(w, "Missing required parameters: chaincodeid and channelid", http.StatusBadRequest)
-// 		return
-// 	}
-	
-// 	// Other optional filter parameters
-// 	talentID := r.URL.Query().Get("talentId")
-// 	institution := r.URL.Query().Get("institution")
-// 	company := r.URL.Query().Get("company")
-	
-// 	// Determine which query function to use based on parameters
-// 	var function string
-// 	var args []string
-	
-// 	if talentID != "" {
-// 		function = "GetCredentialsByTalent"
-// 		args = []string{talentID}
-// 	} else if institution != "" {
-// 		function = "GetCredentialsByInstitution"
-// 		args = []string{institution}
-// 	} else if company != "" {
-// 		function = "GetCredentialsByCompany"
-// 		args = []string{company}
-// 	} else {
-// 		function = "GetAllCredentials"
-// 		args = []string{}
-// 	}
-	
-// 	// Log the query details for debugging
-// 	log.Printf("channel: %s, chaincode: %s, function: %s, args: %v\n", params.ChannelID, params.ChaincodeID, function, args)
-	
-// 	// Execute the query
-// 	result, err := executeQuery(setup, params.ChannelID, params.ChaincodeID, function, args)
-// 	if err != nil {
-// 		HandleError(w, "Query failed: "+err.Error(), http.StatusInternalServerError)
-// 		return
-// 	}
-	
-// 	// Process the result (could be a JSON string that needs to be parsed)
-// 	var responseData interface{}
-// 	if err := json.Unmarshal([]byte(result), &responseData); err != nil {
-// 		// If it's not valid JSON, use the raw string
-// 		responseData = result
-// 	}
-	
-// 	// Send success response
-// 	HandleSuccess(w, "Query executed successfully", responseData)
-// }
-
-// // CustomQueryHandler handles custom query requests with specific function and args
-// func (setup *OrgSetup) CustomQueryHandler(w http.ResponseWriter, r *http.Request) {
-// 	log.Println("Received Custom Query request")
-	
-// 	// Parse the request body to get the parameters
-// 	var params QueryParams
-// 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-// 		HandleError(w, "Invalid request format: "+err.Error(), http.StatusBadRequest)
-// 		return
-// 	}
-	
-// 	// Validate chaincodeid, channelid, and function
-// 	if params.ChaincodeID == "" || params.ChannelID == "" || params.Function == "" {
-// 		HandleError(w, "Missing required parameters: chaincodeid, channelid, and function", http.StatusBadRequest)
-// 		return
-// 	}
-	
-// 	// Log the query details for debugging
-// 	log.Printf("channel: %s, chaincode: %s, function: %s, args: %v\n", params.ChannelID, params.ChaincodeID, params.Function, params.Args)
-	
-// 	// Execute the query
-// 	result, err := executeQuery(setup, params.ChannelID, params.ChaincodeID, params.Function, params.Args)
-// 	if err != nil {
-// 		HandleError(w, "Query failed: "+err.Error(), http.StatusInternalServerError)
-// 		return
-// 	}
-	
-// 	// Process the result (could be a JSON string that needs to be parsed)
-// 	var responseData interface{}
-// 	if err := json.Unmarshal([]byte(result), &responseData); err != nil {
-// 		// If it's not valid JSON, use the raw string
-// 		responseData = result
-// 	}
-	
-// 	// Send success response
-// 	HandleSuccess(w, "Query executed successfully", responseData)
-// }
-
-// // executeQuery handles the common query execution logic
-// func executeQuery(setup *OrgSetup, channelID, chainCodeID, function string, args []string) (string, error) {
-// 	// Get the network and contract
-// 	network := setup.Gateway.GetNetwork(channelID)
-// 	contract := network.GetContract(chainCodeID)
-	
-// 	// Evaluate transaction (query)
-// 	evaluateResponse, err := contract.EvaluateTransaction(function, args...)
-// 	if err != nil {
-// 		return "", err
-// 	}
-	
-// 	return string(evaluateResponse), nil
-// }
-
 package web
-import "github.com/gorilla/mux"
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
 )
 
 // QueryParams represents the query parameters for credential lookups
@@ -173,28 +46,28 @@ func (setup *OrgSetup) QueryCredentialsHandler(w http.ResponseWriter, r *http.Re
 	case credentialID != "":
 		switch credentialType {
 		case "academic":
-			function = "GetAcademicCredential"
+			function = "credentials:GetAcademicCredential"
 		case "professional":
-			function = "GetProfessionalCredential"
+			function = "credentials:GetProfessionalCredential"
 		default:
-			function = "GetBaseCredential"
+			function = "credentials:GetBaseCredential"
 		}
 		args = []string{credentialID}
 
 	case talentID != "":
-		function = "GetCredentialsByTalent"
+		function = "credentials:GetCredentialsByTalent"
 		args = []string{talentID}
 
 	case institution != "":
-		function = "GetCredentialsByInstitution"
+		function = "credentials:GetCredentialsByInstitution"
 		args = []string{institution}
 
 	case company != "":
-		function = "GetCredentialsByCompany"
+		function = "credentials:GetCredentialsByCompany"
 		args = []string{company}
 
 	default:
-		function = "GetAllCredentials"
+		function = "credentials:GetAllCredentials"
 		args = []string{}
 	}
 
@@ -202,10 +75,10 @@ func (setup *OrgSetup) QueryCredentialsHandler(w http.ResponseWriter, r *http.Re
 		channelID, chainCodeID, function, args)
 
 	// Execute query
-	result, err := executeQuery(setup, channelID, chainCodeID, function, args)
+	result, err := executeQuery(r.Context(), setup, setup.client(r), channelID, chainCodeID, function, args)
 	if err != nil {
 		log.Printf("Query failed: %v\n", err)
-		HandleError(w, "Query failed: "+err.Error(), http.StatusInternalServerError)
+		HandleTransactionError(w, "Query failed", err)
 		return
 	}
 
@@ -214,7 +87,6 @@ func (setup *OrgSetup) QueryCredentialsHandler(w http.ResponseWriter, r *http.Re
 	if err := json.Unmarshal([]byte(result), &responseData); err != nil {
 		responseData = result // fallback to raw string
 	}
-	log.Printf("Raw query result: %s\n", result)
 
 	HandleSuccess(w, "Query executed successfully", responseData)
 }
@@ -235,14 +107,28 @@ func (setup *OrgSetup) CustomQueryHandler(w http.ResponseWriter, r *http.Request
 		HandleError(w, "Missing required parameters: chaincodeid, channelid, and function", http.StatusBadRequest)
 		return
 	}
+
+	// Only functions on the allowlist may be evaluated
+	contractName, functionName := "", function
+	if i := strings.LastIndex(function, ":"); i >= 0 {
+		contractName, functionName = function[:i], function[i+1:]
+	}
+	if !setup.IsFunctionAllowed(contractName, functionName) {
+		HandleError(w, "Function "+function+" is not allowed", http.StatusForbidden)
+		return
+	}
+	if !setup.canCallContract(r, contractName) {
+		contractPermissionDenied(w, contractName)
+		return
+	}
 	
 	// Log the query details for debugging
 	log.Printf("channel: %s, chaincode: %s, function: %s, args: %v\n", channelID, chainCodeID, function, args)
 	
 	// Execute the query
-	result, err := executeQuery(setup, channelID, chainCodeID, function, args)
+	result, err := executeQuery(r.Context(), setup, setup.client(r), channelID, chainCodeID, function, args)
 	if err != nil {
-		HandleError(w, "Query failed: "+err.Error(), http.StatusInternalServerError)
+		HandleTransactionError(w, "Query failed", err)
 		return
 	}
 	
@@ -258,13 +144,16 @@ func (setup *OrgSetup) CustomQueryHandler(w http.ResponseWriter, r *http.Request
 }
 
 // executeQuery handles the common query execution logic
-func executeQuery(setup *OrgSetup, channelID, chainCodeID, function string, args []string) (string, error) {
-	// Get the network and contract
-	network := setup.Gateway.GetNetwork(channelID)
-	contract := network.GetContract(chainCodeID)
-	
+func executeQuery(ctx context.Context, setup *OrgSetup, client *Client, channelID, chainCodeID, function string, args []string) (string, error) {
+	// Get the contract, as the client identity of the request
+	contract := client.Contracts.Contract(channelID, chainCodeID)
+
 	// Evaluate transaction (query)
-	evaluateResponse, err := contract.EvaluateTransaction(function, args...)
+	evaluateResponse, err := contract.Evaluate(ctx, Proposal{
+		Function:  function,
+		Args:      args,
+		Transient: setup.transientData(client.MSPID, ""),
+	})
 	if err != nil {
 		return "", err
 	}
@@ -272,11 +161,22 @@ func executeQuery(setup *OrgSetup, channelID, chainCodeID, function string, args
 	return string(evaluateResponse), nil
 }
 
-// maybe useless
+// CredentialEnvelope mirrors the typed envelope in which the chaincode returns every credential
+type CredentialEnvelope struct {
+	Type          string                 `json:"type"`
+	SchemaVersion int                    `json:"schemaVersion"`
+	Data          map[string]interface{} `json:"data"`
+	ResolvedFrom  string                 `json:"resolvedFrom,omitempty"` // Superseded ID that was requested, if any
+	// Number of endorsements of each endorsed skill
+	SkillEndorsements map[string]int `json:"skillEndorsements,omitempty"`
+}
+
+// GetCredentialByTypeHandler returns a credential envelope by ID. The optional
+// ?type=academic|professional parameter only checks the credential is of that type.
 func (setup *OrgSetup) GetCredentialByTypeHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	credentialID := vars["id"]
-	credType := r.URL.Query().Get("type") // "academic", "professional", or "base"
+	credType := r.URL.Query().Get("type")
 	chaincodeID := r.URL.Query().Get("chaincodeid")
 	channelID := r.URL.Query().Get("channelid")
 
@@ -285,32 +185,27 @@ func (setup *OrgSetup) GetCredentialByTypeHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	var function string
-	switch credType {
-	case "academic":
-		function = "GetAcademicCredential"
-	case "professional":
-		function = "GetProfessionalCredential"
-	case "base":
-		function = "GetBaseCredential"
-	default:
-		function = "GetTalentCredential"
+	result, err := executeQuery(r.Context(), setup, setup.client(r), channelID, chaincodeID, "credentials:GetTalentCredential", []string{credentialID})
+	if err != nil {
+		HandleTransactionError(w, "Failed to evaluate transaction", err)
+		return
 	}
 
-	network := setup.Gateway.GetNetwork(channelID)
-	contract := network.GetContract(chaincodeID)
+	var envelope CredentialEnvelope
+	if err := json.Unmarshal([]byte(result), &envelope); err != nil {
+		HandleError(w, "Invalid credential returned by chaincode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	evaluateResult, err := contract.EvaluateTransaction(function, credentialID)
-	if err != nil {
-		HandleError(w, "Failed to evaluate transaction: "+err.Error(), http.StatusInternalServerError)
+	if credType != "" && credType != "base" && credType != envelope.Type {
+		HandleError(w, "Credential "+credentialID+" is of type "+envelope.Type+", not "+credType, http.StatusNotFound)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(evaluateResult)
+	HandleSuccess(w, "Credential retrieved successfully", envelope)
 }
 
-// maybe useless
+// GetAllCredentialsHandler returns the envelopes of all credentials
 func (setup *OrgSetup) GetAllCredentialsHandler(w http.ResponseWriter, r *http.Request) {
 	chaincodeID := r.URL.Query().Get("chaincodeid")
 	channelID := r.URL.Query().Get("channelid")
@@ -320,15 +215,93 @@ func (setup *OrgSetup) GetAllCredentialsHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	network := setup.Gateway.GetNetwork(channelID)
-	contract := network.GetContract(chaincodeID)
+	result, err := executeQuery(r.Context(), setup, setup.client(r), channelID, chaincodeID, "credentials:GetAllCredentials", []string{})
+	if err != nil {
+		HandleTransactionError(w, "Failed to evaluate transaction", err)
+		return
+	}
 
-	result, err := contract.EvaluateTransaction("GetAllCredentials")
+	envelopes := []CredentialEnvelope{}
+	if err := json.Unmarshal([]byte(result), &envelopes); err != nil {
+		HandleError(w, "Invalid credentials returned by chaincode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	HandleSuccess(w, "Credentials retrieved successfully", envelopes)
+}
+
+// GetTalentProfileHandler returns a talent profile with all of its credentials
+func (setup *OrgSetup) GetTalentProfileHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	talentID := vars["talentId"]
+	chaincodeID := r.URL.Query().Get("chaincodeid")
+	channelID := r.URL.Query().Get("channelid")
+
+	if talentID == "" || chaincodeID == "" || channelID == "" {
+		HandleError(w, "Missing required parameters", http.StatusBadRequest)
+		return
+	}
+
+	result, err := executeQuery(r.Context(), setup, setup.client(r), channelID, chaincodeID, "talents:GetTalentProfile", []string{talentID})
+	if err != nil {
+		HandleTransactionError(w, "Query failed", err)
+		return
+	}
+
+	var responseData interface{}
+	if err := json.Unmarshal([]byte(result), &responseData); err != nil {
+		responseData = result
+	}
+
+	HandleSuccess(w, "Talent profile retrieved successfully", responseData)
+}
+
+// GetEndorsementsHandler returns the skill endorsements of a credential
+func (setup *OrgSetup) GetEndorsementsHandler(w http.ResponseWriter, r *http.Request) {
+	credentialID := mux.Vars(r)["id"]
+	chaincodeID := r.URL.Query().Get("chaincodeid")
+	channelID := r.URL.Query().Get("channelid")
+
+	if credentialID == "" || chaincodeID == "" || channelID == "" {
+		HandleError(w, "Missing required parameters", http.StatusBadRequest)
+		return
+	}
+
+	result, err := executeQuery(r.Context(), setup, setup.client(r), channelID, chaincodeID, "endorsements:GetEndorsements", []string{credentialID})
 	if err != nil {
-		HandleError(w, "Failed to evaluate transaction: "+err.Error(), http.StatusInternalServerError)
+		HandleTransactionError(w, "Query failed", err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(result)
+	var responseData interface{}
+	if err := json.Unmarshal([]byte(result), &responseData); err != nil {
+		responseData = result
+	}
+
+	HandleSuccess(w, "Endorsements retrieved successfully", responseData)
+}
+
+// GetStatisticsHandler returns the credential statistics maintained by the chaincode
+func (setup *OrgSetup) GetStatisticsHandler(w http.ResponseWriter, r *http.Request) {
+	chaincodeID := r.URL.Query().Get("chaincodeid")
+	channelID := r.URL.Query().Get("channelid")
+	if chaincodeID == "" {
+		chaincodeID = setup.ChaincodeID
+	}
+	if channelID == "" {
+		channelID = setup.ChannelID
+	}
+
+	result, err := executeQuery(r.Context(), setup, setup.client(r), channelID, chaincodeID, "admin:GetStatistics", []string{})
+	if err != nil {
+		HandleTransactionError(w, "Query failed", err)
+		return
+	}
+
+	var responseData interface{}
+	if err := json.Unmarshal([]byte(result), &responseData); err != nil {
+		responseData = result
+	}
+
+	HandleSuccess(w, "Statistics retrieved successfully", responseData)
 }
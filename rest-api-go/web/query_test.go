@@ -0,0 +1,182 @@
+package web_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"rest-api-go/web"
+	"rest-api-go/web/webtest"
+)
+
+// academicEnvelope is a credential envelope as returned by the chaincode
+const academicEnvelope = `{"type":"academic","schemaVersion":1,"data":{"credentialId":"cred1","talentId":"talent1"}}`
+
+func TestGetCredential(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("credentials:GetTalentCredential", academicEnvelope)
+	router := newRouter(contracts)
+
+	var envelope web.CredentialEnvelope
+	decodeData(t, serve(t, router, "GET", "/credentials/cred1"+chaincodeQuery, nil), http.StatusOK, &envelope)
+	require.Equal(t, web.CredentialEnvelope{
+		Type:          "academic",
+		SchemaVersion: 1,
+		Data:          map[string]interface{}{"credentialId": "cred1", "talentId": "talent1"},
+	}, envelope)
+	requireCall(t, lastCall(t, contracts), false, "credentials:GetTalentCredential", "cred1")
+
+	decodeData(t, serve(t, router, "GET", "/credentials/cred1"+chaincodeQuery+"&type=academic", nil), http.StatusOK, &envelope)
+	decodeData(t, serve(t, router, "GET", "/credentials/cred1"+chaincodeQuery+"&type=base", nil), http.StatusOK, &envelope)
+
+	resp := decode(t, serve(t, router, "GET", "/credentials/cred1"+chaincodeQuery+"&type=professional", nil), http.StatusNotFound)
+	require.Equal(t, "Credential cred1 is of type academic, not professional", resp.Error)
+
+	decode(t, serve(t, router, "GET", "/credentials/cred1?chaincodeid="+chaincodeID, nil), http.StatusBadRequest)
+
+	contracts.Return("credentials:GetTalentCredential", "not JSON")
+	decode(t, serve(t, router, "GET", "/credentials/cred1"+chaincodeQuery, nil), http.StatusInternalServerError)
+}
+
+func TestGetAllCredentials(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("credentials:GetAllCredentials", "["+academicEnvelope+"]")
+	router := newRouter(contracts)
+
+	var envelopes []web.CredentialEnvelope
+	decodeData(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil), http.StatusOK, &envelopes)
+	require.Len(t, envelopes, 1)
+	require.Equal(t, "academic", envelopes[0].Type)
+	requireCall(t, lastCall(t, contracts), false, "credentials:GetAllCredentials")
+
+	// An empty ledger returns an empty list rather than null
+	contracts.Return("credentials:GetAllCredentials", "[]")
+	resp := decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil), http.StatusOK)
+	require.JSONEq(t, "[]", string(resp.Data))
+
+	contracts.Return("credentials:GetAllCredentials", "not JSON")
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil), http.StatusInternalServerError)
+
+	decode(t, serve(t, router, "GET", "/credentials/all", nil), http.StatusBadRequest)
+}
+
+func TestQueryCredentials(t *testing.T) {
+	for query, expected := range map[string]struct {
+		function string
+		args     []string
+	}{
+		"":                    {"credentials:GetAllCredentials", nil},
+		"&credentialid=cred1": {"credentials:GetBaseCredential", []string{"cred1"}},
+		"&credentialid=cred1&credentialtype=academic":     {"credentials:GetAcademicCredential", []string{"cred1"}},
+		"&credentialid=cred1&credentialtype=professional": {"credentials:GetProfessionalCredential", []string{"cred1"}},
+		"&talentid=talent1":                               {"credentials:GetCredentialsByTalent", []string{"talent1"}},
+		"&institution=Concordia":                          {"credentials:GetCredentialsByInstitution", []string{"Concordia"}},
+		"&company=Acme":                                   {"credentials:GetCredentialsByCompany", []string{"Acme"}},
+	} {
+		t.Run(expected.function, func(t *testing.T) {
+			contracts := webtest.NewContracts()
+			contracts.Return(expected.function, `{"found":true}`)
+
+			var result map[string]interface{}
+			decodeData(t, serve(t, newRouter(contracts), "GET", "/credentials"+chaincodeQuery+query, nil), http.StatusOK, &result)
+			require.Equal(t, map[string]interface{}{"found": true}, result)
+			requireCall(t, lastCall(t, contracts), false, expected.function, expected.args...)
+		})
+	}
+
+	router := newRouter(webtest.NewContracts())
+	decode(t, serve(t, router, "GET", "/credentials?channelid="+channelID, nil), http.StatusBadRequest)
+}
+
+func TestCustomQuery(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("credentials:CredentialExists", "true")
+	setup := &web.OrgSetup{
+		MSPID:            "Org1MSP",
+		Contracts:        contracts,
+		ChannelID:        channelID,
+		ChaincodeID:      chaincodeID,
+		AllowedFunctions: []string{"credentials:CredentialExists"},
+	}
+	router := web.NewRouter(setup)
+
+	var exists bool
+	decodeData(t, serve(t, router, "GET", "/credentials/query"+chaincodeQuery+"&function=credentials:CredentialExists&args=cred1", nil), http.StatusOK, &exists)
+	require.True(t, exists)
+	requireCall(t, lastCall(t, contracts), false, "credentials:CredentialExists", "cred1")
+
+	resp := decode(t, serve(t, router, "GET", "/credentials/query"+chaincodeQuery+"&function=admin:GetIdempotencyRecord&args=key1", nil), http.StatusForbidden)
+	require.Equal(t, "Function admin:GetIdempotencyRecord is not allowed", resp.Error)
+
+	decode(t, serve(t, router, "GET", "/credentials/query"+chaincodeQuery, nil), http.StatusBadRequest)
+}
+
+func TestCustomQueryRequiresContractRole(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("admin:GetQuotas", "{}")
+	contracts.Return("issuers:UpdateVerificationStatus", "")
+	setup := &web.OrgSetup{
+		MSPID:            "Org2MSP",
+		Contracts:        contracts,
+		ChannelID:        channelID,
+		ChaincodeID:      chaincodeID,
+		AllowedFunctions: []string{"*"},
+	}
+	router := web.NewRouter(setup)
+
+	calls := len(contracts.Calls())
+	resp := decode(t, serve(t, router, "GET", "/credentials/query"+chaincodeQuery+"&function=issuers:UpdateVerificationStatus&args=cred1&args=Verified&args=Org2", nil), http.StatusForbidden)
+	require.Equal(t, "Permission denied: only callers with the issuer role can call issuers functions", resp.Error)
+	resp = decode(t, serve(t, router, "GET", "/credentials/query"+chaincodeQuery+"&function=admin:GetQuotas", nil), http.StatusForbidden)
+	require.Equal(t, "Permission denied: only callers with the admin role can call admin functions", resp.Error)
+	require.Len(t, contracts.Calls(), calls)
+
+	// Org1 members issue credentials
+	setup.MSPID = "Org1MSP"
+	decode(t, serve(t, router, "GET", "/credentials/query"+chaincodeQuery+"&function=issuers:UpdateVerificationStatus&args=cred1&args=Verified&args=Org1", nil), http.StatusOK)
+	requireCall(t, lastCall(t, contracts), false, "issuers:UpdateVerificationStatus", "cred1", "Verified", "Org1")
+}
+
+func TestGetTalentProfile(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("talents:GetTalentProfile", `{"talentId":"talent1","credentials":[]}`)
+	router := newRouter(contracts)
+
+	var profile map[string]interface{}
+	decodeData(t, serve(t, router, "GET", "/talents/talent1"+chaincodeQuery, nil), http.StatusOK, &profile)
+	require.Equal(t, "talent1", profile["talentId"])
+	requireCall(t, lastCall(t, contracts), false, "talents:GetTalentProfile", "talent1")
+
+	decode(t, serve(t, router, "GET", "/talents/talent1", nil), http.StatusBadRequest)
+}
+
+func TestGetEndorsements(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("endorsements:GetEndorsements", `[{"skill":"Go"}]`)
+	router := newRouter(contracts)
+
+	var endorsements []map[string]interface{}
+	decodeData(t, serve(t, router, "GET", "/credentials/cred1/endorsements"+chaincodeQuery, nil), http.StatusOK, &endorsements)
+	require.Equal(t, []map[string]interface{}{{"skill": "Go"}}, endorsements)
+	requireCall(t, lastCall(t, contracts), false, "endorsements:GetEndorsements", "cred1")
+
+	decode(t, serve(t, router, "GET", "/credentials/cred1/endorsements", nil), http.StatusBadRequest)
+}
+
+func TestGetStatistics(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("admin:GetStatistics", `{"total":2}`)
+	router := newRouter(contracts)
+
+	// The configured channel and chaincode are used by default
+	var stats map[string]interface{}
+	decodeData(t, serve(t, router, "GET", "/stats", nil), http.StatusOK, &stats)
+	require.Equal(t, map[string]interface{}{"total": float64(2)}, stats)
+	requireCall(t, lastCall(t, contracts), false, "admin:GetStatistics")
+
+	serve(t, router, "GET", "/stats?channelid=other&chaincodeid=cc", nil)
+	call := lastCall(t, contracts)
+	require.Equal(t, "other", call.ChannelID)
+	require.Equal(t, "cc", call.ChaincodeID)
+}
@@ -0,0 +1,111 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// qsccChaincode is the system chaincode querying the blocks of a channel
+const qsccChaincode = "qscc"
+
+// requestHash identifies a call by its qualified function name and arguments, the way the
+// chaincode records it. Functions without a contract name belong to the credentials contract.
+func requestHash(function string, args []string) (string, error) {
+	if !strings.Contains(function, ":") {
+		function = "credentials:" + function
+	}
+	request, err := json.Marshal(append([]string{function}, args...))
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(request)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// replayTransaction returns the result of the transaction that committed a request with an
+// idempotency key. The idempotency record only holds the hash of the result, which is read
+// back from the block of the transaction. A record of another request fails with
+// IDEMPOTENCY_KEY_REUSED.
+func replayTransaction(ctx context.Context, client *Client, channelID, chaincodeID string, function string, args []string, idempotencyKey string) (*TransactionResult, error) {
+	recordJSON, err := client.Contracts.Contract(channelID, chaincodeID).Evaluate(ctx, Proposal{Function: "admin:GetIdempotencyRecord", Args: []string{idempotencyKey}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency record: %w", err)
+	}
+	var record IdempotencyRecord
+	if err := json.Unmarshal(recordJSON, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse idempotency record: %w", err)
+	}
+	hash, err := requestHash(function, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash request: %w", err)
+	}
+	if hash != record.RequestHash {
+		return nil, &ChaincodeError{
+			Code:    CodeIdempotencyKeyReused,
+			Message: fmt.Sprintf("idempotency key %s was already used for another request by transaction %s", idempotencyKey, record.TxID),
+		}
+	}
+
+	transaction, err := client.Contracts.Contract(channelID, qsccChaincode).Evaluate(ctx, Proposal{Function: "GetTransactionByID", Args: []string{channelID, record.TxID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transaction %s: %w", record.TxID, err)
+	}
+	result, err := transactionResponse(transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the result of transaction %s: %w", record.TxID, err)
+	}
+	if hash := sha256.Sum256(result); hex.EncodeToString(hash[:]) != record.ResultHash {
+		return nil, fmt.Errorf("the result of transaction %s does not match its idempotency record", record.TxID)
+	}
+
+	return &TransactionResult{
+		TxID:     record.TxID,
+		Response: string(result),
+		Replayed: true,
+	}, nil
+}
+
+// transactionResponse extracts the chaincode response payload from a transaction returned by
+// qscc GetTransactionByID
+func transactionResponse(processedTransactionBytes []byte) ([]byte, error) {
+	processedTransaction := &peer.ProcessedTransaction{}
+	if err := proto.Unmarshal(processedTransactionBytes, processedTransaction); err != nil {
+		return nil, err
+	}
+
+	payload := &common.Payload{}
+	if err := proto.Unmarshal(processedTransaction.GetTransactionEnvelope().GetPayload(), payload); err != nil {
+		return nil, err
+	}
+	transaction := &peer.Transaction{}
+	if err := proto.Unmarshal(payload.GetData(), transaction); err != nil {
+		return nil, err
+	}
+	if len(transaction.GetActions()) == 0 {
+		return nil, fmt.Errorf("the transaction has no actions")
+	}
+
+	actionPayload := &peer.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(transaction.GetActions()[0].GetPayload(), actionPayload); err != nil {
+		return nil, err
+	}
+	responsePayload := &peer.ProposalResponsePayload{}
+	if err := proto.Unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), responsePayload); err != nil {
+		return nil, err
+	}
+	action := &peer.ChaincodeAction{}
+	if err := proto.Unmarshal(responsePayload.GetExtension(), action); err != nil {
+		return nil, err
+	}
+
+	return action.GetResponse().GetPayload(), nil
+}
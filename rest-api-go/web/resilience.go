@@ -0,0 +1,195 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrPeerUnavailable is returned without calling a peer while its circuit breaker is open
+var ErrPeerUnavailable = errors.New("peer unavailable")
+
+// Resilience configures how the Gateway connections ride out the outages of their peers
+type Resilience struct {
+	// EvaluateAttempts is the number of attempts of an evaluation failing with Unavailable
+	EvaluateAttempts int
+	// RetryDelay is the delay before retrying an evaluation, doubled on each retry
+	RetryDelay time.Duration
+	// BreakerFailures is the number of consecutive Unavailable failures opening the circuit
+	// breaker of a peer, failing its calls fast
+	BreakerFailures int
+	// BreakerTimeout is the time the breaker stays open before letting a trial call through
+	BreakerTimeout time.Duration
+	// ReconnectMaxDelay bounds the exponential backoff between the connection attempts to a peer
+	ReconnectMaxDelay time.Duration
+	// MaxGateways bounds the Gateway connections kept open, the least recently used idle
+	// connection being closed to make room for a new one
+	MaxGateways int
+}
+
+// DefaultResilience is the resilience of the Gateway connections unless configured otherwise
+var DefaultResilience = Resilience{
+	EvaluateAttempts:  3,
+	RetryDelay:        100 * time.Millisecond,
+	BreakerFailures:   5,
+	BreakerTimeout:    10 * time.Second,
+	ReconnectMaxDelay: 30 * time.Second,
+	MaxGateways:       100,
+}
+
+// withDefaults returns the resilience with its zero fields set to the defaults
+func (resilience Resilience) withDefaults() Resilience {
+	if resilience.EvaluateAttempts == 0 {
+		resilience.EvaluateAttempts = DefaultResilience.EvaluateAttempts
+	}
+	if resilience.RetryDelay == 0 {
+		resilience.RetryDelay = DefaultResilience.RetryDelay
+	}
+	if resilience.BreakerFailures == 0 {
+		resilience.BreakerFailures = DefaultResilience.BreakerFailures
+	}
+	if resilience.BreakerTimeout == 0 {
+		resilience.BreakerTimeout = DefaultResilience.BreakerTimeout
+	}
+	if resilience.ReconnectMaxDelay == 0 {
+		resilience.ReconnectMaxDelay = DefaultResilience.ReconnectMaxDelay
+	}
+	if resilience.MaxGateways == 0 {
+		resilience.MaxGateways = DefaultResilience.MaxGateways
+	}
+
+	return resilience
+}
+
+// IsUnavailable tells whether a call failed because the peer could not be reached
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrPeerUnavailable) || status.Code(err) == codes.Unavailable
+}
+
+// circuitBreaker fails the calls to a peer fast once they kept failing with Unavailable. After
+// its timeout, one trial call is let through: its success closes the breaker, its failure
+// opens it again. It is safe for concurrent use.
+type circuitBreaker struct {
+	endpoint string
+	failures int
+	timeout  time.Duration
+
+	mu        sync.Mutex
+	failed    int       // Consecutive Unavailable failures
+	openUntil time.Time // Zero while closed
+	trial     bool      // Whether a trial call is in flight
+}
+
+// newCircuitBreaker creates a closed breaker for the peer of an endpoint
+func newCircuitBreaker(endpoint string, resilience Resilience) *circuitBreaker {
+	return &circuitBreaker{endpoint: endpoint, failures: resilience.BreakerFailures, timeout: resilience.BreakerTimeout}
+}
+
+// allow returns ErrPeerUnavailable when a call may not be made now
+func (breaker *circuitBreaker) allow() error {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	if breaker.openUntil.IsZero() {
+		return nil
+	}
+	if wait := time.Until(breaker.openUntil); wait > 0 || breaker.trial {
+		if wait < 0 {
+			wait = 0
+		}
+		return fmt.Errorf("%w: %s, retrying in %s", ErrPeerUnavailable, breaker.endpoint, wait.Round(time.Second))
+	}
+	breaker.trial = true
+
+	return nil
+}
+
+// record records the outcome of an allowed call
+func (breaker *circuitBreaker) record(err error) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	breaker.trial = false
+	if err == nil || !IsUnavailable(err) {
+		if !breaker.openUntil.IsZero() {
+			log.Printf("Peer %s is available again\n", breaker.endpoint)
+		}
+		breaker.failed = 0
+		breaker.openUntil = time.Time{}
+		return
+	}
+
+	breaker.failed++
+	if breaker.failed >= breaker.failures {
+		if breaker.openUntil.IsZero() {
+			log.Printf("Peer %s is unavailable, failing calls for %s: %v\n", breaker.endpoint, breaker.timeout, err)
+		}
+		breaker.openUntil = time.Now().Add(breaker.timeout)
+	}
+}
+
+// resilientContract calls a contract through the circuit breaker of its peer, retrying the
+// evaluations failing with Unavailable, which have no side effects
+type resilientContract struct {
+	contract   Contract
+	breaker    *circuitBreaker
+	resilience Resilience
+}
+
+// Evaluate runs a query on a peer without submitting a transaction. The breaker is checked
+// once and records the outcome of the call after its retries, so that one call failing counts
+// as one failure. The retries stop waiting once ctx is done, failing with its error.
+func (c resilientContract) Evaluate(ctx context.Context, proposal Proposal) ([]byte, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	delay := c.resilience.RetryDelay
+	for attempt := 1; ; attempt++ {
+		result, err := c.contract.Evaluate(ctx, proposal)
+		if err == nil || !IsUnavailable(err) || attempt >= c.resilience.EvaluateAttempts {
+			c.breaker.record(err)
+			return result, err
+		}
+
+		log.Printf("Evaluation of %s failed with Unavailable, retrying in %s: %v\n", proposal.Function, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			c.breaker.record(err)
+			return nil, ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// Submit endorses a transaction, submits it to the orderer and waits for it to commit
+func (c resilientContract) Submit(proposal Proposal) (*TransactionResult, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	result, err := c.contract.Submit(proposal)
+	c.breaker.record(err)
+
+	return result, err
+}
+
+// ChaincodeEvents returns the events emitted by the chaincode from now on, until ctx is done
+func (c resilientContract) ChaincodeEvents(ctx context.Context) (<-chan *client.ChaincodeEvent, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	events, err := c.contract.ChaincodeEvents(ctx)
+	c.breaker.record(err)
+
+	return events, err
+}
@@ -0,0 +1,347 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// metadataFunction is the contractapi system function returning the contract metadata
+const metadataFunction = "org.hyperledger.fabric:GetMetadata"
+
+// systemContract is the name of the contractapi system contract, which is never routed
+const systemContract = "org.hyperledger.fabric"
+
+// contractRoles are the roles required to call the functions of a contract through the
+// generated routes and custom queries, as the dedicated routes of these functions require them
+var contractRoles = map[string]string{
+	"issuers": RoleIssuer,
+	"admin":   RoleAdmin,
+}
+
+// contractMetadata is the part of the contractapi metadata used to generate routes
+type contractMetadata struct {
+	Contracts map[string]struct {
+		Name         string `json:"name"`
+		Default      bool   `json:"default"`
+		Transactions []struct {
+			Name       string   `json:"name"`
+			Tag        []string `json:"tag"`
+			Parameters []struct {
+				Name   string                 `json:"name"`
+				Schema map[string]interface{} `json:"schema"`
+			} `json:"parameters"`
+		} `json:"transactions"`
+	} `json:"contracts"`
+}
+
+// FunctionParameter describes a parameter of a chaincode function
+type FunctionParameter struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // JSON schema type: string, integer, number, boolean, object or array
+}
+
+// ContractFunction describes a chaincode function published in the contract metadata
+type ContractFunction struct {
+	Contract   string              `json:"contract"`
+	Name       string              `json:"name"`
+	ReadOnly   bool                `json:"readOnly"` // Evaluated rather than submitted
+	Parameters []FunctionParameter `json:"parameters"`
+}
+
+// QualifiedName returns the contract:function name used to call the function
+func (fn ContractFunction) QualifiedName() string {
+	return fn.Contract + ":" + fn.Name
+}
+
+// parseContractMetadata extracts the routable functions from contractapi metadata JSON
+func parseContractMetadata(metadataJSON []byte) ([]ContractFunction, error) {
+	var metadata contractMetadata
+	if err := json.Unmarshal(metadataJSON, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse contract metadata: %w", err)
+	}
+
+	functions := []ContractFunction{}
+	for contractName, contract := range metadata.Contracts {
+		if contractName == systemContract {
+			continue
+		}
+
+		for _, tx := range contract.Transactions {
+			fn := ContractFunction{
+				Contract:   contractName,
+				Name:       tx.Name,
+				Parameters: []FunctionParameter{},
+			}
+			for _, tag := range tx.Tag {
+				if strings.EqualFold(tag, "evaluate") {
+					fn.ReadOnly = true
+				}
+			}
+			for _, param := range tx.Parameters {
+				paramType, _ := param.Schema["type"].(string)
+				if paramType == "" {
+					// References to component schemas are structs
+					paramType = "object"
+				}
+				fn.Parameters = append(fn.Parameters, FunctionParameter{Name: param.Name, Type: paramType})
+			}
+			functions = append(functions, fn)
+		}
+	}
+
+	return functions, nil
+}
+
+// LoadContractFunctions fetches the contract metadata from the chaincode and returns the
+// functions reachable through the allowlist
+func (setup *OrgSetup) LoadContractFunctions(ctx context.Context) ([]ContractFunction, error) {
+	result, err := executeQuery(ctx, setup, setup.defaultClient(), setup.ChannelID, setup.ChaincodeID, metadataFunction, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch contract metadata: %w", err)
+	}
+
+	functions, err := parseContractMetadata([]byte(result))
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := []ContractFunction{}
+	for _, fn := range functions {
+		if setup.IsFunctionAllowed(fn.Contract, fn.Name) {
+			allowed = append(allowed, fn)
+		}
+	}
+
+	return allowed, nil
+}
+
+// IsFunctionAllowed reports whether a chaincode function is on the allowlist.
+// Entries are either "contract:function", a bare "function" name matching any
+// contract, or "*" to allow every function.
+func (setup *OrgSetup) IsFunctionAllowed(contract string, function string) bool {
+	for _, entry := range setup.AllowedFunctions {
+		if entry == "*" || entry == function || entry == contract+":"+function {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canCallContract tells whether the caller of a request has the role required to call the
+// functions of a contract
+func (setup *OrgSetup) canCallContract(r *http.Request, contract string) bool {
+	role, ok := contractRoles[contract]
+	return !ok || setup.hasRole(r, role)
+}
+
+// contractPermissionDenied answers a request whose caller lacks the role required by a contract
+func contractPermissionDenied(w http.ResponseWriter, contract string) {
+	HandleError(w, fmt.Sprintf("Permission denied: only callers with the %s role can call %s functions", contractRoles[contract], contract), http.StatusForbidden)
+}
+
+// functionRoutes serves the /functions routes, generated from the contract metadata by the
+// first request finding the chaincode reachable, so that a peer down at startup only fails
+// the requests made while it is. It is safe for concurrent use.
+type functionRoutes struct {
+	setup *OrgSetup
+
+	mu     sync.Mutex
+	router *mux.Router // Nil until the metadata is loaded
+}
+
+// ServeHTTP routes a request to the generated routes, loading them first if needed
+func (routes *functionRoutes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router, err := routes.load(r.Context())
+	if err != nil {
+		log.Printf("Contract metadata unavailable: %v\n", err)
+		HandleTransactionError(w, "Contract metadata unavailable", err)
+		return
+	}
+
+	router.ServeHTTP(w, r)
+}
+
+// load returns the generated routes, fetching the contract metadata until it loads once. The
+// metadata is fetched without holding the lock, which would queue the requests behind a slow peer.
+func (routes *functionRoutes) load(ctx context.Context) (*mux.Router, error) {
+	routes.mu.Lock()
+	router := routes.router
+	routes.mu.Unlock()
+	if router != nil {
+		return router, nil
+	}
+
+	functions, err := routes.setup.LoadContractFunctions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	router = mux.NewRouter()
+	routes.setup.RegisterFunctionRoutes(router, functions)
+
+	// Concurrent first requests may all load the metadata; the first result is kept
+	routes.mu.Lock()
+	defer routes.mu.Unlock()
+	if routes.router == nil {
+		routes.router = router
+	}
+
+	return routes.router, nil
+}
+
+// RegisterFunctionRoutes adds one route per chaincode function at /functions/contract:function:
+// read-only functions are evaluated on GET, the others are submitted on POST.
+// GET /functions lists the routed functions and their parameters.
+func (setup *OrgSetup) RegisterFunctionRoutes(router *mux.Router, functions []ContractFunction) {
+	routes := router.PathPrefix("/functions").Subrouter()
+
+	routes.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		HandleSuccess(w, "Functions retrieved successfully", functions)
+	}).Methods("GET")
+
+	for _, fn := range functions {
+		path := "/" + fn.QualifiedName()
+		if fn.ReadOnly {
+			routes.HandleFunc(path, setup.newFunctionHandler(fn)).Methods("GET")
+		} else {
+			routes.HandleFunc(path, setup.newFunctionHandler(fn)).Methods("POST")
+		}
+		log.Printf("Routed %s (readOnly=%t)\n", fn.QualifiedName(), fn.ReadOnly)
+	}
+}
+
+// newFunctionHandler creates the handler of a generated route. Arguments are given either
+// positionally ("args") or by parameter name, in the query string for read-only functions
+// and in a JSON object body for the others.
+func (setup *OrgSetup) newFunctionHandler(fn ContractFunction) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Received %s request\n", fn.QualifiedName())
+
+		if !setup.canCallContract(r, fn.Contract) {
+			contractPermissionDenied(w, fn.Contract)
+			return
+		}
+
+		var named map[string]interface{}
+		var positional []interface{}
+		if fn.ReadOnly {
+			query := r.URL.Query()
+			named = make(map[string]interface{})
+			for key := range query {
+				named[key] = query.Get(key)
+			}
+			for _, arg := range query["args"] {
+				positional = append(positional, arg)
+			}
+		} else {
+			if err := json.NewDecoder(r.Body).Decode(&named); err != nil {
+				HandleError(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if args, ok := named["args"].([]interface{}); ok {
+				positional = args
+			}
+		}
+
+		args, err := buildArguments(fn, named, positional)
+		if err != nil {
+			HandleError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if fn.ReadOnly {
+			result, err := executeQuery(r.Context(), setup, setup.client(r), setup.ChannelID, setup.ChaincodeID, fn.QualifiedName(), args)
+			if err != nil {
+				HandleTransactionError(w, "Query failed", err)
+				return
+			}
+
+			var responseData interface{}
+			if err := json.Unmarshal([]byte(result), &responseData); err != nil {
+				responseData = result
+			}
+			HandleSuccess(w, "Query executed successfully", responseData)
+			return
+		}
+
+		result, err := setup.executeTransaction(r.Context(), setup.client(r), setup.ChannelID, setup.ChaincodeID, fn.QualifiedName(), args, r.Header.Get(IdempotencyKeyHeader))
+		if err != nil {
+			HandleTransactionError(w, "Transaction failed", err)
+			return
+		}
+		HandleSuccess(w, "Transaction submitted successfully", result)
+	}
+}
+
+// buildArguments orders and validates the arguments of a call against the function parameters
+func buildArguments(fn ContractFunction, named map[string]interface{}, positional []interface{}) ([]string, error) {
+	if positional != nil && len(positional) != len(fn.Parameters) {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", fn.Name, len(fn.Parameters), len(positional))
+	}
+
+	args := make([]string, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		var value interface{}
+		if positional != nil {
+			value = positional[i]
+		} else {
+			v, ok := named[param.Name]
+			if !ok {
+				return nil, fmt.Errorf("missing parameter %s of %s", param.Name, fn.Name)
+			}
+			value = v
+		}
+
+		arg, err := formatArgument(param, value)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = arg
+	}
+
+	return args, nil
+}
+
+// formatArgument converts an argument to its string form and checks it matches the parameter type
+func formatArgument(param FunctionParameter, value interface{}) (string, error) {
+	var arg string
+	switch v := value.(type) {
+	case string:
+		arg = v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("parameter %s is not serializable: %w", param.Name, err)
+		}
+		arg = string(encoded)
+	}
+
+	switch param.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(arg, 10, 64); err != nil {
+			return "", fmt.Errorf("parameter %s must be an integer", param.Name)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(arg, 64); err != nil {
+			return "", fmt.Errorf("parameter %s must be a number", param.Name)
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(arg); err != nil {
+			return "", fmt.Errorf("parameter %s must be a boolean", param.Name)
+		}
+	case "object", "array":
+		if !json.Valid([]byte(arg)) {
+			return "", fmt.Errorf("parameter %s must be a JSON %s", param.Name, param.Type)
+		}
+	}
+
+	return arg, nil
+}
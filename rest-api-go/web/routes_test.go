@@ -0,0 +1,205 @@
+package web_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"rest-api-go/web"
+	"rest-api-go/web/webtest"
+)
+
+// metadata is the part of the contractapi metadata read to generate the /functions routes
+const metadata = `{
+	"contracts": {
+		"credentials": {
+			"name": "credentials",
+			"transactions": [
+				{
+					"name": "CredentialExists",
+					"tag": ["evaluate", "EVALUATE"],
+					"parameters": [{"name": "param0", "schema": {"type": "string"}}]
+				},
+				{
+					"name": "UpdateSkills",
+					"tag": ["submit", "SUBMIT"],
+					"parameters": [
+						{"name": "param0", "schema": {"type": "string"}},
+						{"name": "param1", "schema": {"type": "string"}}
+					]
+				}
+			]
+		},
+		"admin": {
+			"name": "admin",
+			"transactions": [
+				{
+					"name": "SetQuotas",
+					"tag": ["submit", "SUBMIT"],
+					"parameters": [
+						{"name": "param0", "schema": {"type": "integer"}},
+						{"name": "param1", "schema": {"$ref": "#/components/schemas/QuotaConfig"}}
+					]
+				}
+			]
+		},
+		"org.hyperledger.fabric": {
+			"name": "org.hyperledger.fabric",
+			"transactions": [{"name": "GetMetadata", "tag": ["evaluate", "EVALUATE"]}]
+		}
+	}
+}`
+
+// newFunctionsRouter creates a router whose /functions routes are generated from the metadata
+func newFunctionsRouter(contracts *webtest.Contracts, allowed ...string) http.Handler {
+	contracts.Return("org.hyperledger.fabric:GetMetadata", metadata)
+
+	return web.NewRouter(&web.OrgSetup{
+		MSPID:            "Org1MSP",
+		Contracts:        contracts,
+		ChannelID:        channelID,
+		ChaincodeID:      chaincodeID,
+		AllowedFunctions: allowed,
+	})
+}
+
+func TestListFunctions(t *testing.T) {
+	router := newFunctionsRouter(webtest.NewContracts(), "CredentialExists", "credentials:UpdateSkills")
+
+	var functions []web.ContractFunction
+	decodeData(t, serve(t, router, "GET", "/functions", nil), http.StatusOK, &functions)
+	require.ElementsMatch(t, []web.ContractFunction{
+		{
+			Contract:   "credentials",
+			Name:       "CredentialExists",
+			ReadOnly:   true,
+			Parameters: []web.FunctionParameter{{Name: "param0", Type: "string"}},
+		},
+		{
+			Contract:   "credentials",
+			Name:       "UpdateSkills",
+			Parameters: []web.FunctionParameter{{Name: "param0", Type: "string"}, {Name: "param1", Type: "string"}},
+		},
+	}, functions)
+
+	// Functions off the allowlist are not routed
+	require.Equal(t, http.StatusNotFound, serve(t, router, "POST", "/functions/admin:SetQuotas", map[string]interface{}{}).Code)
+}
+
+func TestFunctionRoutesLoadedOnceMetadataAvailable(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Fail("org.hyperledger.fabric:GetMetadata", status.Error(codes.Unavailable, "connection refused"))
+	contracts.Return("credentials:CredentialExists", "true")
+	router := web.NewRouter(&web.OrgSetup{
+		MSPID:            "Org1MSP",
+		Contracts:        contracts,
+		ChannelID:        channelID,
+		ChaincodeID:      chaincodeID,
+		AllowedFunctions: []string{"*"},
+	})
+
+	// The metadata is fetched again by each request until the peer answers
+	decode(t, serve(t, router, "GET", "/functions", nil), http.StatusServiceUnavailable)
+	decode(t, serve(t, router, "GET", "/functions/credentials:CredentialExists?param0=cred1", nil), http.StatusServiceUnavailable)
+
+	contracts.Return("org.hyperledger.fabric:GetMetadata", metadata)
+	var exists bool
+	decodeData(t, serve(t, router, "GET", "/functions/credentials:CredentialExists?param0=cred1", nil), http.StatusOK, &exists)
+	require.True(t, exists)
+
+	// Then the routes are kept
+	contracts.Fail("org.hyperledger.fabric:GetMetadata", status.Error(codes.Unavailable, "connection refused"))
+	var functions []web.ContractFunction
+	decodeData(t, serve(t, router, "GET", "/functions", nil), http.StatusOK, &functions)
+	require.Len(t, functions, 3)
+	require.Equal(t, http.StatusNotFound, serve(t, router, "GET", "/functions/credentials:Unknown", nil).Code)
+}
+
+func TestEvaluateFunction(t *testing.T) {
+	contracts := webtest.NewContracts()
+	contracts.Return("credentials:CredentialExists", "true")
+	router := newFunctionsRouter(contracts, "*")
+
+	var exists bool
+	decodeData(t, serve(t, router, "GET", "/functions/credentials:CredentialExists?param0=cred1", nil), http.StatusOK, &exists)
+	require.True(t, exists)
+	requireCall(t, lastCall(t, contracts), false, "credentials:CredentialExists", "cred1")
+
+	decodeData(t, serve(t, router, "GET", "/functions/credentials:CredentialExists?args=cred2", nil), http.StatusOK, &exists)
+	requireCall(t, lastCall(t, contracts), false, "credentials:CredentialExists", "cred2")
+
+	resp := decode(t, serve(t, router, "GET", "/functions/credentials:CredentialExists", nil), http.StatusBadRequest)
+	require.Equal(t, "missing parameter param0 of CredentialExists", resp.Error)
+
+	// Read-only functions are not submitted
+	calls := len(contracts.Calls())
+	rec := serve(t, router, "POST", "/functions/credentials:CredentialExists", map[string]interface{}{"param0": "cred1"})
+	require.NotEqual(t, http.StatusOK, rec.Code)
+	require.Len(t, contracts.Calls(), calls)
+}
+
+func TestSubmitFunction(t *testing.T) {
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	contracts := webtest.NewContracts()
+	contracts.Return("org.hyperledger.fabric:GetMetadata", metadata)
+	contracts.Return("admin:SetQuotas", "")
+	router := newAuthRouter(issuer, web.AuthConfig{}, contracts, webtest.NewContracts())
+	admin := bearer(issuer.Token(map[string]interface{}{"roles": "admin"}))
+
+	var result web.TransactionResult
+	body := map[string]interface{}{"param0": 10, "param1": map[string]int{"daily": 5}}
+	decodeData(t, serve(t, router, "POST", "/functions/admin:SetQuotas", body, admin...), http.StatusOK, &result)
+	require.Equal(t, "tx1", result.TxID)
+	requireCall(t, lastCall(t, contracts), true, "admin:SetQuotas", "10", `{"daily":5}`)
+
+	body = map[string]interface{}{"args": []interface{}{"20", map[string]int{"daily": 1}}}
+	decodeData(t, serve(t, router, "POST", "/functions/admin:SetQuotas", body, admin...), http.StatusOK, &result)
+	requireCall(t, lastCall(t, contracts), true, "admin:SetQuotas", "20", `{"daily":1}`)
+
+	for name, body := range map[string]interface{}{
+		"not an integer":  map[string]interface{}{"param0": "ten", "param1": map[string]int{}},
+		"not an object":   map[string]interface{}{"param0": 10, "param1": "daily"},
+		"missing":         map[string]interface{}{"param0": 10},
+		"too many args":   map[string]interface{}{"args": []interface{}{"10", "{}", "extra"}},
+		"not JSON object": "param0=10",
+	} {
+		t.Run(name, func(t *testing.T) {
+			decode(t, serve(t, router, "POST", "/functions/admin:SetQuotas", body, admin...), http.StatusBadRequest)
+		})
+	}
+
+	// The system contract is never routed
+	require.Equal(t, http.StatusNotFound, serve(t, router, "GET", "/functions/org.hyperledger.fabric:GetMetadata", nil, admin...).Code)
+}
+
+func TestFunctionRoutesRequireContractRole(t *testing.T) {
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	contracts := webtest.NewContracts()
+	contracts.Return("org.hyperledger.fabric:GetMetadata", metadata)
+	contracts.Return("credentials:UpdateSkills", "")
+	router := newAuthRouter(issuer, web.AuthConfig{}, contracts, webtest.NewContracts())
+
+	// The functions of the admin contract require the admin role, whatever the allowlist
+	decode(t, serve(t, router, "GET", "/functions", nil, bearer(issuer.Token(map[string]interface{}{}))...), http.StatusOK)
+	calls := len(contracts.Calls())
+	for _, roles := range []interface{}{[]string{}, "issuer"} {
+		token := bearer(issuer.Token(map[string]interface{}{"roles": roles}))
+		resp := decode(t, serve(t, router, "POST", "/functions/admin:SetQuotas", map[string]interface{}{"args": []interface{}{"10", "{}"}}, token...), http.StatusForbidden)
+		require.Equal(t, "Permission denied: only callers with the admin role can call admin functions", resp.Error)
+	}
+	require.Len(t, contracts.Calls(), calls)
+
+	// The other contracts keep their own checks
+	token := bearer(issuer.Token(map[string]interface{}{"roles": []string{}}))
+	decode(t, serve(t, router, "POST", "/functions/credentials:UpdateSkills", map[string]interface{}{"args": []interface{}{"cred1", "Go"}}, token...), http.StatusOK)
+	requireCall(t, lastCall(t, contracts), true, "credentials:UpdateSkills", "cred1", "Go")
+
+	// Without authentication no caller has the admin role
+	rec := serve(t, newFunctionsRouter(contracts, "*"), "POST", "/functions/admin:SetQuotas", map[string]interface{}{"args": []interface{}{"10", "{}"}})
+	decode(t, rec, http.StatusForbidden)
+}
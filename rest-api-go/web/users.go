@@ -0,0 +1,232 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// RoleAdmin registers users with the CA of the organization
+const RoleAdmin = "admin"
+
+// Registrar is the CA identity that registers users, enrolled on first use and then kept in
+// the wallet under its enrollment ID
+type Registrar struct {
+	EnrollmentID string
+	Secret       string
+}
+
+// RegisterUserRequest registers a user with the CA of the organization. The role and talent
+// ID are added as the attributes role and talentID to the certificates of the user.
+type RegisterUserRequest struct {
+	UserID         string `json:"userId"`
+	Secret         string `json:"secret,omitempty"` // Generated by the CA if empty
+	Role           string `json:"role"`
+	TalentID       string `json:"talentId,omitempty"`
+	Affiliation    string `json:"affiliation,omitempty"` // The registrar's if empty
+	MaxEnrollments int    `json:"maxEnrollments,omitempty"`
+}
+
+// RegisteredUser is the enrollment secret of a registered user
+type RegisteredUser struct {
+	UserID string `json:"userId"`
+	Secret string `json:"secret"`
+}
+
+// EnrollUserRequest enrolls a registered user, whose identity is stored in the wallet
+type EnrollUserRequest struct {
+	UserID string `json:"userId"`
+	Secret string `json:"secret"`
+}
+
+// EnrolledUser is the identity of an enrolled user, without its private key
+type EnrolledUser struct {
+	UserID      string            `json:"userId"`
+	MSPID       string            `json:"mspId"`
+	Certificate string            `json:"certificate"`
+	Attributes  map[string]string `json:"attributes"` // Attributes of the certificate, e.g. role
+}
+
+// registrarMutex serializes the enrollment of the registrar
+var registrarMutex sync.Mutex
+
+// RegisterUserHandler registers a user with the CA, on behalf of the registrar
+func (setup *OrgSetup) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received Register User request")
+
+	if setup.CA == nil || setup.Wallet == nil {
+		HandleError(w, "User registration is not configured", http.StatusNotImplemented)
+		return
+	}
+	if !setup.hasRole(r, RoleAdmin) {
+		HandleError(w, "Permission denied: only admins can register users", http.StatusForbidden)
+		return
+	}
+
+	var request RegisterUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		HandleError(w, "Invalid request format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !ValidWalletLabel(request.UserID) {
+		HandleError(w, "userId must be 1 to 128 letters, digits or ._@- characters", http.StatusBadRequest)
+		return
+	}
+	if request.Role == "" {
+		HandleError(w, "role is required", http.StatusBadRequest)
+		return
+	}
+
+	registrar, err := setup.registrar(r.Context())
+	if err != nil {
+		handleCAError(w, "Failed to enroll the registrar", err)
+		return
+	}
+
+	attributes := []CAAttribute{{Name: AttributeRole, Value: request.Role, ECert: true}}
+	if request.TalentID != "" {
+		attributes = append(attributes, CAAttribute{Name: AttributeTalentID, Value: request.TalentID, ECert: true})
+	}
+	secret, err := setup.CA.Register(r.Context(), registrar, CARegistration{
+		Name:           request.UserID,
+		Type:           "client",
+		Secret:         request.Secret,
+		Affiliation:    request.Affiliation,
+		MaxEnrollments: request.MaxEnrollments,
+		Attributes:     attributes,
+	})
+	if err != nil {
+		handleCAError(w, "Failed to register user", err)
+		return
+	}
+
+	handleSuccessWithStatus(w, "User registered successfully", RegisteredUser{UserID: request.UserID, Secret: secret}, http.StatusCreated)
+}
+
+// EnrollUserHandler enrolls a registered user with the CA and stores the identity in the
+// wallet, from which the requests naming the user as their identity are then signed.
+// Authenticated callers enroll the identity their token names, unless they are admins.
+func (setup *OrgSetup) EnrollUserHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received Enroll User request")
+
+	if setup.CA == nil || setup.Wallet == nil {
+		HandleError(w, "User enrollment is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	var request EnrollUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		HandleError(w, "Invalid request format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !ValidWalletLabel(request.UserID) || request.Secret == "" {
+		HandleError(w, "userId and secret are required", http.StatusBadRequest)
+		return
+	}
+
+	// The configured identities and the registrar cannot be replaced
+	if _, ok := setup.Clients[request.UserID]; ok || request.UserID == setup.OrgName || request.UserID == setup.Registrar.EnrollmentID {
+		HandleError(w, "Permission denied: "+request.UserID+" is reserved", http.StatusForbidden)
+		return
+	}
+	if principal, ok := PrincipalFromContext(r.Context()); ok && principal.Identity != request.UserID && !contains(principal.Roles, RoleAdmin) {
+		HandleError(w, "Permission denied: users can only enroll their own identity", http.StatusForbidden)
+		return
+	}
+
+	enrolled, err := setup.CA.Enroll(r.Context(), request.UserID, request.Secret, AttributeRole, AttributeTalentID)
+	if err != nil {
+		handleCAError(w, "Failed to enroll user", err)
+		return
+	}
+	enrolled.MSPID = setup.MSPID
+	attributes, err := CertificateAttributes(enrolled.Certificate)
+	if err != nil {
+		handleCAError(w, "Invalid certificate returned by the CA", err)
+		return
+	}
+
+	if err := setup.Wallet.Put(request.UserID, *enrolled); err != nil {
+		log.Printf("Error storing identity of %s: %v", request.UserID, err)
+		HandleError(w, "Failed to store the identity", http.StatusInternalServerError)
+		return
+	}
+
+	handleSuccessWithStatus(w, "User enrolled successfully", EnrolledUser{
+		UserID:      request.UserID,
+		MSPID:       enrolled.MSPID,
+		Certificate: string(enrolled.Certificate),
+		Attributes:  attributes,
+	}, http.StatusCreated)
+}
+
+// registrar returns the identity of the registrar, enrolling it when the wallet has none
+func (setup *OrgSetup) registrar(ctx context.Context) (*WalletIdentity, error) {
+	registrarMutex.Lock()
+	defer registrarMutex.Unlock()
+
+	label := setup.Registrar.EnrollmentID
+	registrar, err := setup.Wallet.Get(label)
+	if err == nil || !errors.Is(err, ErrIdentityNotFound) {
+		return registrar, err
+	}
+
+	log.Printf("Enrolling registrar %s...\n", label)
+	registrar, err = setup.CA.Enroll(ctx, label, setup.Registrar.Secret)
+	if err != nil {
+		return nil, err
+	}
+	registrar.MSPID = setup.MSPID
+	if err := setup.Wallet.Put(label, *registrar); err != nil {
+		return nil, err
+	}
+
+	return registrar, nil
+}
+
+// walletClient returns the client of a user of the wallet, adding its identity to the pool.
+// It returns ErrIdentityNotFound when the wallet does not hold the user.
+func (setup *OrgSetup) walletClient(name string) (*Client, error) {
+	if setup.Wallet == nil || setup.Gateways == nil || !ValidWalletLabel(name) || name == setup.Registrar.EnrollmentID {
+		return nil, ErrIdentityNotFound
+	}
+
+	// Checked on every request, so that removed users lose access; the wallet caches the
+	// decrypted identity and the connection is kept while the identity is unchanged
+	stored, err := setup.Wallet.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := setup.Gateways.Put(setup.walletIdentity(name, stored)); err != nil {
+		return nil, err
+	}
+
+	return &Client{Name: name, MSPID: stored.MSPID, Contracts: setup.Gateways.Contracts(name)}, nil
+}
+
+// walletIdentity returns the identity of a user of the wallet, connecting to the peer of the organization
+func (setup *OrgSetup) walletIdentity(name string, stored *WalletIdentity) Identity {
+	id := setup.identity()
+	id.Name = name
+	id.MSPID = stored.MSPID
+	id.CertPath, id.KeyPath = "", ""
+	id.Certificate = stored.Certificate
+	id.PrivateKey = stored.PrivateKey
+	return id
+}
+
+// handleCAError sends the error of a call to the CA: its client errors are passed on, and
+// other failures are reported as a bad gateway
+func handleCAError(w http.ResponseWriter, prefix string, err error) {
+	log.Printf("%s: %v", prefix, err)
+
+	var caErr *CAError
+	if errors.As(err, &caErr) && caErr.StatusCode >= 400 && caErr.StatusCode < 500 {
+		HandleError(w, prefix+": "+caErr.Message, caErr.StatusCode)
+		return
+	}
+	HandleError(w, prefix, http.StatusBadGateway)
+}
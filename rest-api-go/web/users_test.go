@@ -0,0 +1,180 @@
+package web_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"rest-api-go/web"
+	"rest-api-go/web/webtest"
+)
+
+// newUsersSetup creates an Org1 setup registering and enrolling users with a CA, into a new
+// wallet, authenticating requests with the tokens of issuer, whose preferred_username claim
+// names the identity of the caller
+func newUsersSetup(t *testing.T, ca *webtest.FabricCA, issuer *webtest.Issuer) *web.OrgSetup {
+	t.Helper()
+
+	wallet, err := web.NewFileWallet(filepath.Join(t.TempDir(), "wallet"), walletKey)
+	require.NoError(t, err)
+	org1 := webtest.NewContracts()
+	return &web.OrgSetup{
+		OrgName:   "Org1",
+		MSPID:     "Org1MSP",
+		Contracts: org1,
+		Clients: map[string]*web.Client{
+			"Org1": {Name: "Org1", MSPID: "Org1MSP", Contracts: org1},
+			"Org2": {Name: "Org2", MSPID: "Org2MSP", Contracts: webtest.NewContracts()},
+		},
+		ChannelID:        channelID,
+		ChaincodeID:      chaincodeID,
+		AllowedFunctions: []string{"*"},
+		CA:               &web.CAClient{URL: ca.URL, CAName: ca.Name},
+		Registrar:        web.Registrar{EnrollmentID: "admin", Secret: "adminpw"},
+		Wallet:           wallet,
+		Auth:             &web.AuthConfig{Issuer: issuer.URL, IdentityClaim: "preferred_username"},
+	}
+}
+
+func TestRegisterUser(t *testing.T) {
+	ca := webtest.NewFabricCA("ca-org1", "admin", "adminpw")
+	defer ca.Close()
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	setup := newUsersSetup(t, ca, issuer)
+	router := web.NewRouter(setup)
+	admin := bearer(issuer.Token(map[string]interface{}{"roles": "admin"}))
+
+	var registered web.RegisteredUser
+	decodeData(t, serve(t, router, "POST", "/users/register", web.RegisterUserRequest{UserID: "jane", Role: "talent", TalentID: "talent1"}, admin...), http.StatusCreated, &registered)
+	require.Equal(t, "jane", registered.UserID)
+	require.NotEmpty(t, registered.Secret)
+
+	registration, ok := ca.Registration("jane")
+	require.True(t, ok)
+	require.Equal(t, "client", registration.Type)
+	require.Equal(t, []web.CAAttribute{
+		{Name: web.AttributeRole, Value: "talent", ECert: true},
+		{Name: web.AttributeTalentID, Value: "talent1", ECert: true},
+	}, registration.Attributes)
+
+	// The registrar was enrolled on first use and kept in the wallet
+	require.Equal(t, 1, ca.Enrollments("admin"))
+	decodeData(t, serve(t, router, "POST", "/users/register", web.RegisterUserRequest{UserID: "john", Secret: "johnpw", Role: "issuer"}, admin...), http.StatusCreated, &registered)
+	require.Equal(t, web.RegisteredUser{UserID: "john", Secret: "johnpw"}, registered)
+	require.Equal(t, 1, ca.Enrollments("admin"))
+	_, err := setup.Wallet.Get("admin")
+	require.NoError(t, err)
+
+	decode(t, serve(t, router, "POST", "/users/register", web.RegisterUserRequest{UserID: "jane", Role: "talent"}, admin...), http.StatusConflict)
+	decode(t, serve(t, router, "POST", "/users/register", web.RegisterUserRequest{UserID: "joe"}, admin...), http.StatusBadRequest)
+	decode(t, serve(t, router, "POST", "/users/register", web.RegisterUserRequest{UserID: "../joe", Role: "talent"}, admin...), http.StatusBadRequest)
+
+	// Only authenticated admins register users
+	resp := decode(t, serve(t, router, "POST", "/users/register", web.RegisterUserRequest{UserID: "joe", Role: "talent"}, bearer(issuer.Token(nil))...), http.StatusForbidden)
+	require.Contains(t, resp.Error, "Permission denied")
+	setup.Auth = nil
+	resp = decode(t, serve(t, web.NewRouter(setup), "POST", "/users/register", web.RegisterUserRequest{UserID: "joe", Role: "talent"}), http.StatusForbidden)
+	require.Contains(t, resp.Error, "Permission denied")
+	_, ok = ca.Registration("joe")
+	require.False(t, ok)
+}
+
+func TestEnrollUser(t *testing.T) {
+	ca := webtest.NewFabricCA("ca-org1", "admin", "adminpw")
+	defer ca.Close()
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	setup := newUsersSetup(t, ca, issuer)
+	router := web.NewRouter(setup)
+	admin := bearer(issuer.Token(map[string]interface{}{"roles": "admin"}))
+
+	var registered web.RegisteredUser
+	decodeData(t, serve(t, router, "POST", "/users/register", web.RegisterUserRequest{UserID: "jane", Role: "talent", TalentID: "talent1"}, admin...), http.StatusCreated, &registered)
+
+	var enrolled web.EnrolledUser
+	decodeData(t, serve(t, router, "POST", "/users/enroll", web.EnrollUserRequest{UserID: "jane", Secret: registered.Secret}, admin...), http.StatusCreated, &enrolled)
+	require.Equal(t, "jane", enrolled.UserID)
+	require.Equal(t, "Org1MSP", enrolled.MSPID)
+	require.Equal(t, map[string]string{"role": "talent", "talentID": "talent1"}, enrolled.Attributes)
+
+	stored, err := setup.Wallet.Get("jane")
+	require.NoError(t, err)
+	require.Equal(t, enrolled.Certificate, string(stored.Certificate))
+	require.Contains(t, string(stored.PrivateKey), "PRIVATE KEY")
+
+	resp := decode(t, serve(t, router, "POST", "/users/enroll", web.EnrollUserRequest{UserID: "jane", Secret: "wrong"}, admin...), http.StatusUnauthorized)
+	require.Contains(t, resp.Error, "Authentication failure")
+	decode(t, serve(t, router, "POST", "/users/enroll", web.EnrollUserRequest{UserID: "jane"}, admin...), http.StatusBadRequest)
+
+	// The configured identities and the registrar cannot be overwritten
+	for _, userID := range []string{"admin", "Org1", "Org2"} {
+		decode(t, serve(t, router, "POST", "/users/enroll", web.EnrollUserRequest{UserID: userID, Secret: "adminpw"}, admin...), http.StatusForbidden)
+	}
+}
+
+func TestEnrollOwnIdentity(t *testing.T) {
+	ca := webtest.NewFabricCA("ca-org1", "admin", "adminpw")
+	defer ca.Close()
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	router := web.NewRouter(newUsersSetup(t, ca, issuer))
+
+	admin := bearer(issuer.Token(map[string]interface{}{"roles": "admin"}))
+	var registered web.RegisteredUser
+	decodeData(t, serve(t, router, "POST", "/users/register", web.RegisterUserRequest{UserID: "jane", Secret: "janepw", Role: "talent"}, admin...), http.StatusCreated, &registered)
+
+	// Only admins register users
+	jane := bearer(issuer.Token(map[string]interface{}{"preferred_username": "jane"}))
+	decode(t, serve(t, router, "POST", "/users/register", web.RegisterUserRequest{UserID: "joe", Role: "talent"}, jane...), http.StatusForbidden)
+
+	// Users enroll their own identity only
+	john := bearer(issuer.Token(map[string]interface{}{"preferred_username": "john"}))
+	decode(t, serve(t, router, "POST", "/users/enroll", web.EnrollUserRequest{UserID: "jane", Secret: "janepw"}, john...), http.StatusForbidden)
+	decode(t, serve(t, router, "POST", "/users/enroll", web.EnrollUserRequest{UserID: "jane", Secret: "janepw"}, jane...), http.StatusCreated)
+}
+
+func TestUsersNotConfigured(t *testing.T) {
+	router := newRouter(webtest.NewContracts())
+
+	decode(t, serve(t, router, "POST", "/users/register", web.RegisterUserRequest{UserID: "jane", Role: "talent"}), http.StatusNotImplemented)
+	decode(t, serve(t, router, "POST", "/users/enroll", web.EnrollUserRequest{UserID: "jane", Secret: "janepw"}), http.StatusNotImplemented)
+}
+
+func TestWalletIdentitySelectedByHeader(t *testing.T) {
+	ca := webtest.NewFabricCA("ca-org1", "admin", "adminpw")
+	defer ca.Close()
+	issuer := webtest.NewIssuer()
+	defer issuer.Close()
+	setup := newUsersSetup(t, ca, issuer)
+	org1 := newTestIdentity(t, "Org1", "Org1MSP")
+	pool, err := web.NewGatewayPool(web.Timeouts{Evaluate: time.Second}, web.Resilience{}, org1)
+	require.NoError(t, err)
+	defer pool.Close()
+	setup.Gateways = pool
+	setup.CertPath, setup.KeyPath, setup.TLSCertPath = org1.CertPath, org1.KeyPath, org1.TLSCertPath
+	setup.PeerEndpoint, setup.GatewayPeer = org1.PeerEndpoint, org1.GatewayPeer
+	router := web.NewRouter(setup)
+	admin := bearer(issuer.Token(map[string]interface{}{"roles": "admin"}))
+	jane := append(bearer(issuer.Token(map[string]interface{}{"preferred_username": "jane"})), web.IdentityHeader, "jane")
+
+	var registered web.RegisteredUser
+	decodeData(t, serve(t, router, "POST", "/users/register", web.RegisterUserRequest{UserID: "jane", Role: "talent"}, admin...), http.StatusCreated, &registered)
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, jane...), http.StatusForbidden)
+	decode(t, serve(t, router, "POST", "/users/enroll", web.EnrollUserRequest{UserID: "jane", Secret: registered.Secret}, admin...), http.StatusCreated)
+
+	// The requests of the user are signed with the identity of the wallet, connected on first use;
+	// the registrar is not selectable
+	rec := serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, jane...)
+	require.NotEqual(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	require.True(t, pool.Has("jane"))
+	registrar := append(bearer(issuer.Token(map[string]interface{}{"preferred_username": "admin"})), web.IdentityHeader, "admin")
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, registrar...), http.StatusForbidden)
+
+	// A subject naming the user does not map the caller to their identity
+	impostor := append(bearer(issuer.Token(map[string]interface{}{"sub": "jane"})), web.IdentityHeader, "jane")
+	decode(t, serve(t, router, "GET", "/credentials/all"+chaincodeQuery, nil, impostor...), http.StatusForbidden)
+}
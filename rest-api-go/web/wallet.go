@@ -0,0 +1,261 @@
+package web
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrIdentityNotFound is returned for the labels a wallet holds no identity for
+var ErrIdentityNotFound = errors.New("identity not found in wallet")
+
+// walletFileExtension is the extension of the identity files of a wallet directory
+const walletFileExtension = ".id"
+
+// walletLabelPattern restricts labels to names safe as file names
+var walletLabelPattern = regexp.MustCompile(`^[A-Za-z0-9_@-][A-Za-z0-9._@-]{0,127}$`)
+
+// WalletIdentity is an X.509 identity of a user: its enrollment certificate and private key, PEM-encoded
+type WalletIdentity struct {
+	MSPID       string `json:"mspId"`
+	Certificate []byte `json:"certificate"`
+	PrivateKey  []byte `json:"privateKey"`
+}
+
+// walletEntry is the content of an identity file, in the layout of the Fabric SDK wallets
+type walletEntry struct {
+	Type        string `json:"type"`
+	Version     int    `json:"version"`
+	MSPID       string `json:"mspId"`
+	Credentials struct {
+		Certificate string `json:"certificate"`
+		PrivateKey  string `json:"privateKey"`
+	} `json:"credentials"`
+}
+
+// Wallet stores the identities of users in a directory, one <label>.id file per identity,
+// encrypted with AES-GCM. The label is authenticated with each file, which cannot be
+// renamed to impersonate another user. Decrypted identities are cached for as long as their
+// file is unchanged. It is safe for concurrent use.
+type Wallet struct {
+	dir  string
+	aead cipher.AEAD
+
+	mu    sync.Mutex
+	cache map[string]cachedIdentity
+}
+
+// cachedIdentity is a decrypted identity and the file it was read from
+type cachedIdentity struct {
+	file     os.FileInfo
+	identity WalletIdentity
+}
+
+// NewFileWallet opens the wallet of a directory, created if missing, with an AES key of 16,
+// 24 or 32 bytes
+func NewFileWallet(dir string, key []byte) (*Wallet, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create wallet directory: %w", err)
+	}
+
+	return &Wallet{dir: dir, aead: aead, cache: make(map[string]cachedIdentity)}, nil
+}
+
+// LoadWalletKey reads a wallet key file holding a base64-encoded AES key
+func LoadWalletKey(filename string) ([]byte, error) {
+	encoded, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wallet key file: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("wallet key file %s is not base64: %w", filename, err)
+	}
+
+	return key, nil
+}
+
+// ValidWalletLabel tells whether a label can name an identity of a wallet
+func ValidWalletLabel(label string) bool {
+	return walletLabelPattern.MatchString(label)
+}
+
+// Put stores the identity of a label, replacing the previous one
+func (wallet *Wallet) Put(label string, identity WalletIdentity) error {
+	filename, err := wallet.filename(label)
+	if err != nil {
+		return err
+	}
+
+	entry := walletEntry{Type: "X.509", Version: 1, MSPID: identity.MSPID}
+	entry.Credentials.Certificate = string(identity.Certificate)
+	entry.Credentials.PrivateKey = string(identity.PrivateKey)
+	plaintext, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, wallet.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := wallet.aead.Seal(nonce, nonce, plaintext, []byte(label))
+
+	// Written to a temporary file first, so that a failure leaves the previous identity intact
+	file, err := os.CreateTemp(wallet.dir, "."+label+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write identity %s: %w", label, err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(sealed); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write identity %s: %w", label, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write identity %s: %w", label, err)
+	}
+	if err := os.Rename(file.Name(), filename); err != nil {
+		return fmt.Errorf("failed to write identity %s: %w", label, err)
+	}
+	wallet.forget(label)
+
+	return nil
+}
+
+// Get returns the identity of a label, or ErrIdentityNotFound
+func (wallet *Wallet) Get(label string) (*WalletIdentity, error) {
+	filename, err := wallet.filename(label)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file is checked on every call, so that removed or replaced identities are not used
+	file, err := os.Stat(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		wallet.forget(label)
+		return nil, fmt.Errorf("%w: %s", ErrIdentityNotFound, label)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity %s: %w", label, err)
+	}
+	if identity, ok := wallet.cached(label, file); ok {
+		return identity, nil
+	}
+
+	sealed, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrIdentityNotFound, label)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity %s: %w", label, err)
+	}
+
+	nonceSize := wallet.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("identity file of %s is truncated", label)
+	}
+	plaintext, err := wallet.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(label))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt identity %s: %w", label, err)
+	}
+
+	var entry walletEntry
+	if err := json.Unmarshal(plaintext, &entry); err != nil {
+		return nil, fmt.Errorf("invalid identity %s: %w", label, err)
+	}
+	if entry.Type != "X.509" {
+		return nil, fmt.Errorf("identity %s is of unsupported type %s", label, entry.Type)
+	}
+
+	identity := WalletIdentity{
+		MSPID:       entry.MSPID,
+		Certificate: []byte(entry.Credentials.Certificate),
+		PrivateKey:  []byte(entry.Credentials.PrivateKey),
+	}
+	wallet.mu.Lock()
+	wallet.cache[label] = cachedIdentity{file: file, identity: identity}
+	wallet.mu.Unlock()
+
+	return &identity, nil
+}
+
+// cached returns the cached identity of a label, if it was read from the current file
+func (wallet *Wallet) cached(label string, file os.FileInfo) (*WalletIdentity, bool) {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	cached, ok := wallet.cache[label]
+	if !ok || !os.SameFile(cached.file, file) || !cached.file.ModTime().Equal(file.ModTime()) || cached.file.Size() != file.Size() {
+		return nil, false
+	}
+	identity := cached.identity
+	return &identity, true
+}
+
+// forget drops the cached identity of a label
+func (wallet *Wallet) forget(label string) {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	delete(wallet.cache, label)
+}
+
+// Remove deletes the identity of a label, if any
+func (wallet *Wallet) Remove(label string) error {
+	filename, err := wallet.filename(label)
+	if err != nil {
+		return err
+	}
+	wallet.forget(label)
+	if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove identity %s: %w", label, err)
+	}
+
+	return nil
+}
+
+// List returns the labels of the identities of the wallet, sorted
+func (wallet *Wallet) List() ([]string, error) {
+	files, err := os.ReadDir(wallet.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wallet directory: %w", err)
+	}
+
+	labels := []string{}
+	for _, file := range files {
+		label := strings.TrimSuffix(file.Name(), walletFileExtension)
+		if file.IsDir() || filepath.Ext(file.Name()) != walletFileExtension || !ValidWalletLabel(label) {
+			continue
+		}
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	return labels, nil
+}
+
+// filename returns the identity file of a label
+func (wallet *Wallet) filename(label string) (string, error) {
+	if !ValidWalletLabel(label) {
+		return "", fmt.Errorf("invalid wallet label %q", label)
+	}
+	return filepath.Join(wallet.dir, label+walletFileExtension), nil
+}
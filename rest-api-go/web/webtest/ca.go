@@ -0,0 +1,326 @@
+package webtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"rest-api-go/web"
+)
+
+// FabricCA stands in for the REST API of a Fabric CA: it registers identities on behalf of its
+// bootstrap admin, authenticated by the token of its enrollment certificate, and enrolls them,
+// adding the requested attributes to their certificates as Fabric CA does.
+type FabricCA struct {
+	*httptest.Server
+	Name string
+
+	mu         sync.Mutex
+	key        *ecdsa.PrivateKey
+	cert       *x509.Certificate
+	admin      string
+	identities map[string]*caIdentity
+	serial     int64
+}
+
+// caIdentity is an identity registered with the CA
+type caIdentity struct {
+	registration web.CARegistration
+	secret       string
+	enrollments  int
+}
+
+// attributesOID is the certificate extension in which Fabric CA stores the attributes
+var attributesOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// NewFabricCA starts a CA named name whose bootstrap admin is registered with the given secret;
+// it is stopped with Close
+func NewFabricCA(name string, adminID string, adminSecret string) *FabricCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+
+	ca := &FabricCA{
+		Name:       name,
+		key:        key,
+		cert:       cert,
+		admin:      adminID,
+		identities: map[string]*caIdentity{adminID: {registration: web.CARegistration{Name: adminID, Type: "admin"}, secret: adminSecret}},
+		serial:     1,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/register", ca.register)
+	mux.HandleFunc("/api/v1/enroll", ca.enroll)
+	ca.Server = httptest.NewServer(mux)
+
+	return ca
+}
+
+// Registration returns the registration of an identity
+func (ca *FabricCA) Registration(name string) (web.CARegistration, bool) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	identity, ok := ca.identities[name]
+	if !ok {
+		return web.CARegistration{}, false
+	}
+	return identity.registration, true
+}
+
+// Enrollments returns the number of enrollments of an identity
+func (ca *FabricCA) Enrollments(name string) int {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	if identity, ok := ca.identities[name]; ok {
+		return identity.enrollments
+	}
+	return 0
+}
+
+// register handles POST /api/v1/register
+func (ca *FabricCA) register(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	registrar, ok := ca.authenticateToken(r, body)
+	if !ok || registrar != ca.admin {
+		writeCAError(w, http.StatusUnauthorized, 20, "Authentication failure")
+		return
+	}
+
+	var registration web.CARegistration
+	if err := json.Unmarshal(body, &registration); err != nil || registration.Name == "" {
+		writeCAError(w, http.StatusBadRequest, 0, "Invalid registration request")
+		return
+	}
+	if registration.CAName != ca.Name {
+		writeCAError(w, http.StatusBadRequest, 19, "CA '"+registration.CAName+"' does not exist")
+		return
+	}
+
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	if _, ok := ca.identities[registration.Name]; ok {
+		writeCAError(w, http.StatusConflict, 74, "Identity '"+registration.Name+"' is already registered")
+		return
+	}
+	secret := registration.Secret
+	if secret == "" {
+		random := make([]byte, 8)
+		rand.Read(random)
+		secret = hex.EncodeToString(random)
+	}
+	registration.Secret = ""
+	ca.identities[registration.Name] = &caIdentity{registration: registration, secret: secret}
+
+	writeCAResult(w, map[string]string{"secret": secret})
+}
+
+// enroll handles POST /api/v1/enroll
+func (ca *FabricCA) enroll(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		CertificateRequest string `json:"certificate_request"`
+		AttributeRequests  []struct {
+			Name     string `json:"name"`
+			Optional bool   `json:"optional"`
+		} `json:"attr_reqs"`
+		CAName string `json:"caname"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeCAError(w, http.StatusBadRequest, 0, "Invalid enrollment request")
+		return
+	}
+	if request.CAName != ca.Name {
+		writeCAError(w, http.StatusBadRequest, 19, "CA '"+request.CAName+"' does not exist")
+		return
+	}
+	block, _ := pem.Decode([]byte(request.CertificateRequest))
+	if block == nil {
+		writeCAError(w, http.StatusBadRequest, 0, "Invalid certificate request")
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil || csr.CheckSignature() != nil {
+		writeCAError(w, http.StatusBadRequest, 0, "Invalid certificate request")
+		return
+	}
+
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	name, secret, ok := r.BasicAuth()
+	identity, registered := ca.identities[name]
+	if !ok || !registered || identity.secret != secret {
+		writeCAError(w, http.StatusUnauthorized, 20, "Authentication failure")
+		return
+	}
+	max := identity.registration.MaxEnrollments
+	if max > 0 && identity.enrollments >= max {
+		writeCAError(w, http.StatusUnauthorized, 20, "The identity "+name+" has already enrolled "+strconv.Itoa(max)+" times")
+		return
+	}
+
+	attributes := make(map[string]string)
+	for _, attributeRequest := range request.AttributeRequests {
+		found := false
+		for _, attribute := range identity.registration.Attributes {
+			if attribute.Name == attributeRequest.Name {
+				attributes[attribute.Name] = attribute.Value
+				found = true
+			}
+		}
+		if !found && !attributeRequest.Optional {
+			writeCAError(w, http.StatusBadRequest, 0, "Identity '"+name+"' does not have attribute '"+attributeRequest.Name+"'")
+			return
+		}
+	}
+
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: name, OrganizationalUnit: []string{"client"}},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if len(attributes) > 0 {
+		value, _ := json.Marshal(map[string]interface{}{"attrs": attributes})
+		template.ExtraExtensions = []pkix.Extension{{Id: attributesOID, Value: value}}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, csr.PublicKey, ca.key)
+	if err != nil {
+		writeCAError(w, http.StatusInternalServerError, 0, err.Error())
+		return
+	}
+	identity.enrollments++
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+	writeCAResult(w, map[string]interface{}{
+		"Cert": base64.StdEncoding.EncodeToString(certPEM),
+		"ServerInfo": map[string]string{
+			"CAName":  ca.Name,
+			"CAChain": base64.StdEncoding.EncodeToString(caPEM),
+		},
+	})
+}
+
+// authenticateToken checks the token of a request, issued to an identity enrolled by the CA,
+// and returns the name of that identity
+func (ca *FabricCA) authenticateToken(r *http.Request, body []byte) (string, bool) {
+	b64Cert, b64Signature, ok := strings.Cut(r.Header.Get("Authorization"), ".")
+	if !ok {
+		return "", false
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(b64Cert)
+	if err != nil {
+		return "", false
+	}
+	signature, err := base64.StdEncoding.DecodeString(b64Signature)
+	if err != nil {
+		return "", false
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return "", false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil || cert.CheckSignatureFrom(ca.cert) != nil {
+		return "", false
+	}
+	publicKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return "", false
+	}
+
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(signature, &sig); err != nil {
+		return "", false
+	}
+	// Fabric only accepts signatures in their low-S form
+	if sig.S.Cmp(new(big.Int).Rsh(publicKey.Curve.Params().N, 1)) > 0 {
+		return "", false
+	}
+	payload := r.Method + "." + base64.StdEncoding.EncodeToString([]byte(r.URL.RequestURI())) + "." + base64.StdEncoding.EncodeToString(body) + "." + b64Cert
+	digest := sha256.Sum256([]byte(payload))
+	if !ecdsa.Verify(publicKey, digest[:], sig.R, sig.S) {
+		return "", false
+	}
+
+	return cert.Subject.CommonName, true
+}
+
+// readBody reads the body of a POST request
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		writeCAError(w, http.StatusMethodNotAllowed, 0, "Method not allowed")
+		return nil, false
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeCAError(w, http.StatusBadRequest, 0, err.Error())
+		return nil, false
+	}
+	return body, true
+}
+
+// writeCAResult writes a successful response
+func writeCAResult(w http.ResponseWriter, result interface{}) {
+	writeJSON(w, map[string]interface{}{"success": true, "result": result, "errors": []interface{}{}, "messages": []interface{}{}})
+}
+
+// writeCAError writes a failed response
+func writeCAError(w http.ResponseWriter, status int, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  false,
+		"result":   nil,
+		"errors":   []map[string]interface{}{{"code": code, "message": message}},
+		"messages": []interface{}{},
+	})
+}
@@ -0,0 +1,244 @@
+// Package webtest provides an in-memory web.ContractProvider, so that the REST handlers can be
+// tested without a Fabric network.
+//
+// Chaincode functions are answered by the handlers registered with Handle, Return and Fail;
+// calling any other function fails the way the chaincode does for unknown functions. Every
+// call is recorded and can be inspected with Calls. Submitted transactions are given the IDs
+// tx1, tx2, ... in order; the handler is timed as their endorsement, their submission and
+// commit taking no time. Emit delivers chaincode events to the subscribed listeners.
+//
+// Issuer signs bearer tokens with a local key and serves its JWKS, for testing authentication,
+// and FabricCA stands in for the REST API of a Fabric CA.
+package webtest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"rest-api-go/web"
+)
+
+// eventBufferSize is the number of events buffered for each listener, beyond which Emit drops them
+const eventBufferSize = 16
+
+// Call is a chaincode call received by a fake contract
+type Call struct {
+	ChannelID   string
+	ChaincodeID string
+	Submitted   bool // Submitted as a transaction rather than evaluated
+	web.Proposal
+}
+
+// Handler answers a chaincode call with the result of the function or its error
+type Handler func(call Call) ([]byte, error)
+
+// subscriber is a listener of the events of a chaincode
+type subscriber struct {
+	channelID   string
+	chaincodeID string
+	events      chan *client.ChaincodeEvent
+}
+
+// Contracts is a fake web.ContractProvider whose chaincode functions are answered by handlers.
+// It is safe for concurrent use.
+type Contracts struct {
+	mu          sync.Mutex
+	handlers    map[string]Handler
+	calls       []Call
+	txCount     int
+	subscribers map[*subscriber]struct{}
+}
+
+// NewContracts creates a provider whose contracts have no functions
+func NewContracts() *Contracts {
+	return &Contracts{
+		handlers:    make(map[string]Handler),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Handle answers the calls to a function, named as the REST API calls it, e.g.
+// "credentials:CreateAcademicCredential", with a handler
+func (contracts *Contracts) Handle(function string, handler Handler) {
+	contracts.mu.Lock()
+	defer contracts.mu.Unlock()
+
+	contracts.handlers[function] = handler
+}
+
+// Return answers every call to a function with the same result
+func (contracts *Contracts) Return(function string, result string) {
+	contracts.Handle(function, func(Call) ([]byte, error) {
+		return []byte(result), nil
+	})
+}
+
+// Fail answers every call to a function with the same error
+func (contracts *Contracts) Fail(function string, err error) {
+	contracts.Handle(function, func(Call) ([]byte, error) {
+		return nil, err
+	})
+}
+
+// Calls returns the calls received so far, in order
+func (contracts *Contracts) Calls() []Call {
+	contracts.mu.Lock()
+	defer contracts.mu.Unlock()
+
+	return append([]Call(nil), contracts.calls...)
+}
+
+// Emit delivers an event to the listeners of a chaincode. It does not block: listeners whose
+// buffer is full miss the event.
+func (contracts *Contracts) Emit(channelID string, chaincodeID string, event *client.ChaincodeEvent) {
+	contracts.mu.Lock()
+	defer contracts.mu.Unlock()
+
+	for sub := range contracts.subscribers {
+		if sub.channelID != channelID || sub.chaincodeID != chaincodeID {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// Contract returns the fake contract of a chaincode deployed on a channel
+func (contracts *Contracts) Contract(channelID string, chaincodeID string) web.Contract {
+	return &contract{contracts: contracts, channelID: channelID, chaincodeID: chaincodeID}
+}
+
+// call records a call and answers it with the handler of its function
+func (contracts *Contracts) call(call Call) ([]byte, error) {
+	contracts.mu.Lock()
+	contracts.calls = append(contracts.calls, call)
+	handler, ok := contracts.handlers[call.Function]
+	contracts.mu.Unlock()
+
+	if !ok {
+		contractName, functionName := "", call.Function
+		if i := strings.LastIndex(call.Function, ":"); i >= 0 {
+			contractName, functionName = call.Function[:i], call.Function[i+1:]
+		}
+		return nil, ChaincodeError(web.CodeNotFound, fmt.Sprintf("function %s is not part of contract %s", functionName, contractName))
+	}
+
+	return handler(call)
+}
+
+// contract is a fake web.Contract calling the handlers of its provider
+type contract struct {
+	contracts   *Contracts
+	channelID   string
+	chaincodeID string
+}
+
+// Evaluate answers a query with the handler of its function
+func (c *contract) Evaluate(ctx context.Context, proposal web.Proposal) ([]byte, error) {
+	return c.contracts.call(Call{ChannelID: c.channelID, ChaincodeID: c.chaincodeID, Proposal: proposal})
+}
+
+// Submit answers a transaction with the handler of its function and gives it the next ID
+func (c *contract) Submit(proposal web.Proposal) (*web.TransactionResult, error) {
+	start := time.Now()
+	result, err := c.contracts.call(Call{ChannelID: c.channelID, ChaincodeID: c.chaincodeID, Submitted: true, Proposal: proposal})
+	if proposal.ObservePhase != nil {
+		proposal.ObservePhase(web.PhaseEndorse, time.Since(start))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if proposal.ObservePhase != nil {
+		proposal.ObservePhase(web.PhaseSubmit, 0)
+		proposal.ObservePhase(web.PhaseCommitStatus, 0)
+	}
+
+	c.contracts.mu.Lock()
+	defer c.contracts.mu.Unlock()
+	c.contracts.txCount++
+
+	return &web.TransactionResult{
+		TxID:     fmt.Sprintf("tx%d", c.contracts.txCount),
+		Response: string(result),
+	}, nil
+}
+
+// ChaincodeEvents returns the events emitted from now on, until ctx is done
+func (c *contract) ChaincodeEvents(ctx context.Context) (<-chan *client.ChaincodeEvent, error) {
+	sub := &subscriber{
+		channelID:   c.channelID,
+		chaincodeID: c.chaincodeID,
+		events:      make(chan *client.ChaincodeEvent, eventBufferSize),
+	}
+
+	c.contracts.mu.Lock()
+	c.contracts.subscribers[sub] = struct{}{}
+	c.contracts.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		c.contracts.mu.Lock()
+		defer c.contracts.mu.Unlock()
+		delete(c.contracts.subscribers, sub)
+		close(sub.events)
+	}()
+
+	return sub.events, nil
+}
+
+// ChaincodeError returns the error the Gateway reports when the chaincode fails with
+// "CODE: message", the peer's message being in the details of the gRPC status
+func ChaincodeError(code string, message string) error {
+	detail := &gateway.ErrorDetail{
+		Address: "peer0.org1.example.com:7051",
+		MspId:   "Org1MSP",
+		Message: fmt.Sprintf("chaincode response 500, %s: %s", code, message),
+	}
+	st, err := status.New(codes.Aborted, "failed to endorse transaction, see attached details for more info").WithDetails(detail)
+	if err != nil {
+		panic(err)
+	}
+
+	return st.Err()
+}
+
+// ConflictError returns the error of a transaction invalidated by a concurrent write to the
+// keys it read
+func ConflictError(txID string) error {
+	commitErr := &client.CommitError{TransactionID: txID, Code: peer.TxValidationCode_MVCC_READ_CONFLICT}
+	return fmt.Errorf("transaction %s failed to commit with status code %d (%s): %w", txID, int32(commitErr.Code), commitErr.Code, commitErr)
+}
+
+// ProcessedTransaction returns a transaction as returned by qscc GetTransactionByID, whose
+// chaincode returned payload
+func ProcessedTransaction(payload string) string {
+	action := mustMarshal(&peer.ChaincodeAction{Response: &peer.Response{Status: 200, Payload: []byte(payload)}})
+	responsePayload := mustMarshal(&peer.ProposalResponsePayload{Extension: action})
+	actionPayload := mustMarshal(&peer.ChaincodeActionPayload{Action: &peer.ChaincodeEndorsedAction{ProposalResponsePayload: responsePayload}})
+	transaction := mustMarshal(&peer.Transaction{Actions: []*peer.TransactionAction{{Payload: actionPayload}}})
+	envelopePayload := mustMarshal(&common.Payload{Data: transaction})
+
+	return string(mustMarshal(&peer.ProcessedTransaction{TransactionEnvelope: &common.Envelope{Payload: envelopePayload}}))
+}
+
+func mustMarshal(message proto.Message) []byte {
+	data, err := proto.Marshal(message)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
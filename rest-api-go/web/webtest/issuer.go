@@ -0,0 +1,103 @@
+package webtest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+)
+
+// Issuer is an OpenID provider serving the JWKS of a local RSA key, which signs the tokens it
+// issues with RS256. Its URL is the issuer of the tokens.
+type Issuer struct {
+	*httptest.Server
+	KeyID string
+	key   *rsa.PrivateKey
+
+	// KeysDelay delays the responses of the JWKS endpoint, whose requests are counted
+	KeysDelay  time.Duration
+	keyFetches atomic.Int64
+}
+
+// NewIssuer starts an issuer with a new key; it is stopped with Close
+func NewIssuer() *Issuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	issuer := &Issuer{KeyID: "test-key", key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"issuer": issuer.URL, "jwks_uri": issuer.URL + "/jwks.json"})
+	})
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		issuer.keyFetches.Add(1)
+		time.Sleep(issuer.KeysDelay)
+		writeJSON(w, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": issuer.KeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	issuer.Server = httptest.NewServer(mux)
+
+	return issuer
+}
+
+// KeyFetches returns the number of requests for the JWKS
+func (issuer *Issuer) KeyFetches() int {
+	return int(issuer.keyFetches.Load())
+}
+
+// Token issues a token with the given claims. The issuer, subject and an expiry in one hour are
+// added unless given.
+func (issuer *Issuer) Token(claims map[string]interface{}) string {
+	all := map[string]interface{}{
+		"iss": issuer.URL,
+		"sub": "user1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		all[name] = value
+	}
+
+	return issuer.sign(map[string]string{"alg": "RS256", "typ": "JWT", "kid": issuer.KeyID}, all)
+}
+
+// sign encodes and signs a token
+func (issuer *Issuer) sign(header map[string]string, claims map[string]interface{}) string {
+	signed := encodeSegment(header) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, issuer.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// encodeSegment encodes a segment of a token as base64url JSON
+func encodeSegment(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// writeJSON writes a JSON document
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
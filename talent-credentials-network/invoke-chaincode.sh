@@ -17,7 +17,7 @@ setEnvOrg2() {
 echo "Invoking the chaincode to initialize the ledger..."
 
 setEnvOrg1
-peer chaincode invoke -o localhost:7050 --ordererTLSHostnameOverride orderer.example.com --tls --cafile "${PWD}/organizations/ordererOrganizations/example.com/orderers/orderer.example.com/msp/tlscacerts/tlsca.example.com-cert.pem" -C mychannel -n basic --peerAddresses localhost:7051 --tlsRootCertFiles "${PWD}/organizations/peerOrganizations/org1.example.com/peers/peer0.org1.example.com/tls/ca.crt" --peerAddresses localhost:9051 --tlsRootCertFiles "${PWD}/organizations/peerOrganizations/org2.example.com/peers/peer0.org2.example.com/tls/ca.crt" -c '{"function":"InitLedger","Args":[]}'
+peer chaincode invoke -o localhost:7050 --ordererTLSHostnameOverride orderer.example.com --tls --cafile "${PWD}/organizations/ordererOrganizations/example.com/orderers/orderer.example.com/msp/tlscacerts/tlsca.example.com-cert.pem" -C mychannel -n basic --peerAddresses localhost:7051 --tlsRootCertFiles "${PWD}/organizations/peerOrganizations/org1.example.com/peers/peer0.org1.example.com/tls/ca.crt" --peerAddresses localhost:9051 --tlsRootCertFiles "${PWD}/organizations/peerOrganizations/org2.example.com/peers/peer0.org2.example.com/tls/ca.crt" -c '{"function":"admin:InitLedger","Args":[]}'
 
 if [ $? -eq 0 ]; then
   echo "Chaincode invoke successful: Ledger initialized."
@@ -30,7 +30,7 @@ fi
 echo "Querying the chaincode to get all credentials..."
 
 setEnvOrg1
-peer chaincode query -C mychannel -n basic -c '{"Args":["GetAllCredentials"]}'
+peer chaincode query -C mychannel -n basic -c '{"Args":["credentials:GetAllCredentials"]}'
 
 if [ $? -eq 0 ]; then
   echo "Chaincode query successful: Credentials retrieved."